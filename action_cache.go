@@ -0,0 +1,315 @@
+package cc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"time"
+)
+
+const actionCacheManifestName = "manifest.json"
+
+// actionCacheManifest lists the outputs stored for an action cache key.  The manifest is
+// written after the outputs so that incomplete cache entries are never restored.
+type actionCacheManifest struct {
+	Key     string              `json:"key"`
+	Action  string              `json:"action"`
+	Created time.Time           `json:"created"`
+	Outputs []actionCacheOutput `json:"outputs"`
+}
+
+type actionCacheOutput struct {
+	DataSource string `json:"data_source"`
+	PathKey    string `json:"path_key"`
+	Bytes      int64  `json:"bytes"`
+	Sha256     string `json:"sha256"`
+}
+
+// ActionCacheEnabled returns true if action outputs are cached in an action cache store.
+//
+// When the cache is enabled, RunActions computes a key for each action from the action name,
+// the plugin name and version, the resolved payload and action attributes, and the sha256 of every
+// path of the action inputs.  If the cache store has outputs for the key, they are copied to
+// the action output data sources and the action is not run.  After a successful run the
+// outputs are copied to the cache store under the key.
+//
+// Payload data sources are not part of the key and are not restored, so an action that finds a
+// data source in the payload through its IOManager is not cached.  Cached actions must declare
+// the data sources they read and write as action inputs and outputs, and must not read or write
+// data through the PluginManager.
+//
+// The plugin version is part of the key, so actions are only cached for plugins with a plugin
+// definition that has a version.  Restored outputs are checked against the sha256 and size
+// recorded when they were cached, and a cache entry with changed outputs is not used.
+//
+// Only actions with output data sources are cached.  Actions with input or output paths that have
+// unresolved {VAR::} templates, and actions with output path patterns, are always run.
+// Data paths are not cached separately; the whole object at each output path is cached.
+func (pm *PluginManager) ActionCacheEnabled() bool {
+	return pm.actionCacheStore != ""
+}
+
+// actionCacheKey returns the cache key of an action, or an empty key if the cache is
+// disabled or the action can not be cached
+func (pm *PluginManager) actionCacheKey(action *Action) string {
+	if !pm.ActionCacheEnabled() || len(action.Outputs) == 0 {
+		return ""
+	}
+	key, err := pm.computeActionCacheKey(action)
+	if err != nil {
+		pm.Logger.Warn("action is not cached", "action", action.Name, "reason", err.Error())
+		return ""
+	}
+	return key
+}
+
+func (pm *PluginManager) computeActionCacheKey(action *Action) (string, error) {
+	if pm.definition == nil || pm.definition.Version == "" {
+		return "", errors.New("the plugin does not have a plugin definition with a version")
+	}
+	for _, ds := range action.Outputs {
+		for _, key := range sortedKeys(ds.Paths) {
+			p := ds.Paths[key]
+			if containsTemplate(p) {
+				return "", fmt.Errorf("output %s path %s has unresolved templates", ds.Name, key)
+			}
+			pattern, err := NewPathPattern(unescapeTemplateBraces(p))
+			if err != nil || !pattern.IsLiteral() {
+				return "", fmt.Errorf("output %s path %s is not a literal path", ds.Name, key)
+			}
+		}
+	}
+
+	inputs, err := action.IOManager.inputChecksums()
+	if err != nil {
+		return "", err
+	}
+	attrs := maps.Clone(pm.Attributes)
+	if attrs == nil {
+		attrs = PayloadAttributes{}
+	}
+	maps.Copy(attrs, action.Attributes)
+	doc := map[string]any{
+		"action":     action.Name,
+		"attributes": attrs,
+		"inputs":     inputs,
+		"plugin":     pm.definition.Name + "@" + pm.definition.Version,
+	}
+
+	//canonical json with secrets redacted so that rotating a secret does not invalidate the cache
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	var canonical any
+	if err = json.Unmarshal(data, &canonical); err != nil {
+		return "", err
+	}
+	data, err = json.Marshal(redactValue(canonical))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// inputChecksums returns the sha256 of every path of the IOManager inputs,
+// keyed by data source, path key and store path
+func (im *IOManager) inputChecksums() (map[string]string, error) {
+	checksums := map[string]string{}
+	for _, ds := range im.Inputs {
+		for _, key := range sortedKeys(ds.Paths) {
+			if containsTemplate(ds.Paths[key]) {
+				return nil, fmt.Errorf("input %s path %s has unresolved templates", ds.Name, key)
+			}
+			_, paths, err := im.expandPaths(ds, unescapeTemplateBraces(ds.Paths[key]))
+			if err != nil {
+				return nil, fmt.Errorf("input %s path %s: %w", ds.Name, key, err)
+			}
+			for _, p := range paths {
+				sum, err := im.storeChecksum(ds.StoreName, p)
+				if err != nil {
+					return nil, fmt.Errorf("input %s path %s: %w", ds.Name, key, err)
+				}
+				checksums[ds.Name+"."+key+":"+p] = sum
+			}
+		}
+	}
+	return checksums, nil
+}
+
+// storeChecksum reads a store path and returns its sha256.  The read is not recorded in the provenance.
+func (im *IOManager) storeChecksum(storeName string, p string) (string, error) {
+	store, err := im.GetStore(storeName)
+	if err != nil {
+		return "", err
+	}
+	reader, ok := store.Session.(StoreReader)
+	if !ok {
+		return "", fmt.Errorf("data store %s session does not implement a StoreReader", store.Name)
+	}
+	rc, err := reader.Get(p, "")
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	cr := newChecksumReader(rc)
+	if _, err = io.Copy(io.Discard, cr); err != nil {
+		return "", err
+	}
+	return cr.Sum(), nil
+}
+
+// restoreCachedAction copies the cached outputs of an action to its output data sources.
+// Returns false if there are no cached outputs for the key or the outputs could not be restored.
+func (pm *PluginManager) restoreCachedAction(action *Action, key string) bool {
+	if key == "" {
+		return false
+	}
+	cache, err := action.IOManager.GetStore(pm.actionCacheStore)
+	if err != nil {
+		pm.Logger.Warn("action cache is not available", "action", action.Name, "reason", err.Error())
+		return false
+	}
+	cacheReader, ok := cache.Session.(StoreReader)
+	if !ok {
+		pm.Logger.Warn("action cache store session does not implement a StoreReader", "store", cache.Name)
+		return false
+	}
+	manifest := actionCacheManifest{}
+	rc, err := cacheReader.Get(path.Join(key, actionCacheManifestName), "")
+	if err != nil {
+		return false //cache miss
+	}
+	err = json.NewDecoder(rc).Decode(&manifest)
+	rc.Close()
+	if err != nil {
+		pm.Logger.Warn("invalid action cache manifest", "action", action.Name, "key", key, "reason", err.Error())
+		return false
+	}
+
+	err = action.IOManager.restoreCachedOutputs(cacheReader, key, manifest)
+	if err != nil {
+		pm.Logger.Warn("failed to restore cached action outputs", "action", action.Name, "key", key, "reason", err.Error())
+		return false
+	}
+	return true
+}
+
+func (im *IOManager) restoreCachedOutputs(cacheReader StoreReader, key string, manifest actionCacheManifest) error {
+	cached := map[string]bool{}
+	for _, o := range manifest.Outputs {
+		cached[o.DataSource+"."+o.PathKey] = true
+	}
+	for _, ds := range im.Outputs {
+		for _, pathKey := range sortedKeys(ds.Paths) {
+			if !cached[ds.Name+"."+pathKey] {
+				return fmt.Errorf("output %s path %s is not in the cache", ds.Name, pathKey)
+			}
+		}
+	}
+	//check every cached output before restoring any of them
+	for _, o := range manifest.Outputs {
+		if err := verifyCachedOutput(cacheReader, key, o); err != nil {
+			return err
+		}
+	}
+	for _, o := range manifest.Outputs {
+		ds, err := im.GetOutputDataSource(o.DataSource)
+		if err != nil {
+			return err
+		}
+		rc, err := cacheReader.Get(path.Join(key, o.DataSource, o.PathKey), "")
+		if err != nil {
+			return err
+		}
+		_, err = im.Put(PutOpInput{
+			SrcReader:         rc,
+			DataSourceOpInput: DataSourceOpInput{DataSource: &ds, PathKey: o.PathKey},
+		})
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyCachedOutput returns an error if a cached output does not match its manifest size and sha256
+func verifyCachedOutput(cacheReader StoreReader, key string, o actionCacheOutput) error {
+	rc, err := cacheReader.Get(path.Join(key, o.DataSource, o.PathKey), "")
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	cr := newChecksumReader(rc)
+	if _, err = io.Copy(io.Discard, cr); err != nil {
+		return err
+	}
+	if cr.n != o.Bytes || cr.Sum() != o.Sha256 {
+		return fmt.Errorf("cached output %s path %s does not match the cache manifest", o.DataSource, o.PathKey)
+	}
+	return nil
+}
+
+// storeCachedAction copies the outputs of a successful action run to the action cache
+func (pm *PluginManager) storeCachedAction(action *Action, key string) {
+	if key == "" {
+		return
+	}
+	if action.IOManager.parentSources != nil && action.IOManager.parentSources.Load() {
+		pm.Logger.Warn("action is not cached", "action", action.Name, "reason", "the action used payload data sources")
+		return
+	}
+	err := action.IOManager.storeCachedOutputs(pm.actionCacheStore, action.Name, key)
+	if err != nil {
+		pm.Logger.Warn("failed to cache action outputs", "action", action.Name, "key", key, "reason", err.Error())
+	}
+}
+
+func (im *IOManager) storeCachedOutputs(cacheStore string, actionName string, key string) error {
+	cache, err := im.GetStore(cacheStore)
+	if err != nil {
+		return err
+	}
+	writer, ok := im.storeWriter(cache)
+	if !ok {
+		return fmt.Errorf("data store %s session does not implement a storewriter", cache.Name)
+	}
+	manifest := actionCacheManifest{Key: key, Action: actionName, Created: time.Now().UTC(), Outputs: []actionCacheOutput{}}
+	for _, ds := range im.Outputs {
+		store, err := im.GetStore(ds.StoreName)
+		if err != nil {
+			return err
+		}
+		reader, ok := store.Session.(StoreReader)
+		if !ok {
+			return fmt.Errorf("data store %s session does not implement a StoreReader", store.Name)
+		}
+		for _, pathKey := range sortedKeys(ds.Paths) {
+			rc, err := reader.Get(unescapeTemplateBraces(ds.Paths[pathKey]), "")
+			if err != nil {
+				return fmt.Errorf("output %s path %s: %w", ds.Name, pathKey, err)
+			}
+			cr := newChecksumReader(rc)
+			_, err = writer.Put(cr, path.Join(key, ds.Name, pathKey), "")
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			manifest.Outputs = append(manifest.Outputs, actionCacheOutput{ds.Name, pathKey, cr.n, cr.Sum()})
+		}
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = writer.Put(bytes.NewReader(data), path.Join(key, actionCacheManifestName), "")
+	return err
+}
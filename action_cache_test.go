@@ -0,0 +1,128 @@
+package cc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	filestore "github.com/usace/filesapi"
+)
+
+var cacheTestRuns int
+
+type cacheTestRunner struct {
+	ActionRunnerBase
+}
+
+func (r *cacheTestRunner) Run() error {
+	cacheTestRuns++
+	data, err := r.Action.Get(DataSourceOpInput{DataSourceName: "terrain", PathKey: "default"})
+	if err != nil {
+		return err
+	}
+	scale := r.Action.Attributes.GetStringOrDefault("scale", "1")
+	_, err = r.Action.Put(PutOpInput{
+		SrcReader:         strings.NewReader(string(data) + "x" + scale),
+		DataSourceOpInput: DataSourceOpInput{DataSourceName: "depth", PathKey: "default"},
+	})
+	return err
+}
+
+func TestActionCache(t *testing.T) {
+	im, root := testFileIOManager(t)
+	cacheRoot := t.TempDir()
+	cache := DataStore{Name: "cache", StoreType: FSB, Parameters: PayloadAttributes{"root": cacheRoot}}
+	session, err := (&FileDataStore[filestore.BlockFS]{}).Connect(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Session = session
+	im.Stores = append(im.Stores, cache)
+	if err = os.MkdirAll(filepath.Join(root, "inputs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeInput := func(data string) {
+		if err := os.WriteFile(filepath.Join(root, "inputs/terrain.txt"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeInput("terrain-v1")
+
+	ActionRegistry.RegisterAction("compute", &cacheTestRunner{})
+	t.Cleanup(func() { delete(ActionRegistry, "compute") })
+	newManager := func(scale string) *PluginManager {
+		pm := &PluginManager{Logger: NewCcLogger(CcLoggerInput{}), actionCacheStore: "cache", definition: &PluginDefinition{Name: "router", Version: "1.2.0"}}
+		pm.IOManager = *im
+		pm.Actions = []Action{{Name: "compute", IOManager: IOManager{
+			Attributes: PayloadAttributes{"scale": scale},
+			Inputs:     []DataSource{{Name: "terrain", StoreName: "local", Paths: map[string]string{"default": "inputs/terrain.txt"}}},
+			Outputs:    []DataSource{{Name: "depth", StoreName: "local", Paths: map[string]string{"default": "outputs/depth.txt"}}},
+		}}}
+		pm.Actions[0].IOManager.SetParent(&pm.IOManager)
+		return pm
+	}
+	output := filepath.Join(root, "outputs/depth.txt")
+	run := func(scale string, expectedRuns int, expectedOutput string) {
+		t.Helper()
+		os.Remove(output)
+		if err := newManager(scale).RunActions(); err != nil {
+			t.Fatal(err)
+		}
+		if cacheTestRuns != expectedRuns {
+			t.Errorf("expected %d runs, got %d", expectedRuns, cacheTestRuns)
+		}
+		if data, err := os.ReadFile(output); err != nil || string(data) != expectedOutput {
+			t.Errorf("unexpected output: %q %v", data, err)
+		}
+	}
+
+	cacheTestRuns = 0
+	run("2", 1, "terrain-v1x2")
+	//identical inputs and attributes are restored from the cache
+	run("2", 1, "terrain-v1x2")
+	//changed attributes and inputs are run
+	run("3", 2, "terrain-v1x3")
+	writeInput("terrain-v2")
+	run("3", 3, "terrain-v2x3")
+	run("3", 3, "terrain-v2x3")
+
+	//cached outputs that do not match the manifest are a cache miss
+	pm := newManager("3")
+	key := pm.actionCacheKey(&pm.Actions[0])
+	if err = os.WriteFile(filepath.Join(cacheRoot, key, "depth", "default"), []byte("terrain-v2x4"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("3", 4, "terrain-v2x3")
+	run("3", 4, "terrain-v2x3")
+
+	//actions that read payload data sources are not cached
+	usesPayload := func() *PluginManager {
+		pm := newManager("4")
+		pm.Inputs = append(pm.Inputs, pm.Actions[0].Inputs...)
+		pm.Actions[0].Inputs = nil
+		return pm
+	}
+	for i := 0; i < 2; i++ {
+		if err = usesPayload().RunActions(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cacheTestRuns != 6 {
+		t.Errorf("expected actions using payload inputs to run every time, got %d runs", cacheTestRuns)
+	}
+
+	//actions of plugins without a versioned plugin definition are not cached
+	pm = newManager("3")
+	pm.definition = nil
+	if key := pm.actionCacheKey(&pm.Actions[0]); key != "" {
+		t.Errorf("expected an action without a plugin version not to be cached, got %s", key)
+	}
+
+	//actions with unresolved output paths are always run
+	pm = newManager("3")
+	pm.Actions[0].Outputs[0].Paths["default"] = "outputs/{VAR::realization}/depth.txt"
+	if key := pm.actionCacheKey(&pm.Actions[0]); key != "" {
+		t.Errorf("expected an action with template output paths not to be cached, got %s", key)
+	}
+}
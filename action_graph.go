@@ -0,0 +1,252 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type actionStatus int
+
+const (
+	actionPending actionStatus = iota
+	actionRunning
+	actionSucceeded //completed, or failed with ContinueOnError
+	actionFailed
+	actionSkipped //a dependency failed
+)
+
+type actionResult struct {
+	index           int
+	continueOnError bool
+	err             error
+}
+
+// hasActionDependencies returns true if any action declares depends_on
+func hasActionDependencies(actions []Action) bool {
+	for _, action := range actions {
+		if len(action.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// actionDependencies returns the indices of the actions each action depends on.
+// Undefined dependencies and dependency cycles are errors.
+func actionDependencies(actions []Action) ([][]int, error) {
+	errs := []error{}
+	for _, action := range actions {
+		for _, name := range action.DependsOn {
+			if !slices.ContainsFunc(actions, func(a Action) bool { return a.Name == name }) {
+				errs = append(errs, fmt.Errorf("action %s depends on undefined action %s", action.Name, name))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	deps := actionDependencyIndices(actions)
+	if cycle := actionCycle(actions, deps); cycle != nil {
+		return nil, fmt.Errorf("action dependency cycle %s", strings.Join(cycle, " -> "))
+	}
+	return deps, nil
+}
+
+// actionDependencyIndices returns the indices of the actions each action depends on.  A dependency
+// on a name used by several actions depends on all of them.  Undefined dependencies are ignored.
+func actionDependencyIndices(actions []Action) [][]int {
+	byName := map[string][]int{}
+	for i, action := range actions {
+		byName[action.Name] = append(byName[action.Name], i)
+	}
+	deps := make([][]int, len(actions))
+	for i, action := range actions {
+		for _, name := range action.DependsOn {
+			deps[i] = append(deps[i], byName[name]...)
+		}
+	}
+	return deps
+}
+
+// addOrderDependencies makes each action without depends_on depend on the actions before it in
+// the payload, except the actions that depend on it, so that those actions keep their payload order.
+func addOrderDependencies(actions []Action, deps [][]int) [][]int {
+	for i, action := range actions {
+		if len(action.DependsOn) > 0 {
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if !dependsOnAction(deps, j, i) {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+	return deps
+}
+
+// dependsOnAction returns true if action from depends on action to, directly or indirectly
+func dependsOnAction(deps [][]int, from int, to int) bool {
+	visited := make([]bool, len(deps))
+	stack := []int{from}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, d := range deps[i] {
+			if d == to {
+				return true
+			}
+			if !visited[d] {
+				visited[d] = true
+				stack = append(stack, d)
+			}
+		}
+	}
+	return false
+}
+
+// actionCycle returns the names of the actions in a dependency cycle, or nil if the dependencies are acyclic
+func actionCycle(actions []Action, deps [][]int) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(actions))
+	stack := []int{}
+	var visit func(i int) []string
+	visit = func(i int) []string {
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, d := range deps[i] {
+			switch state[d] {
+			case visiting:
+				cycle := []string{}
+				for j := len(stack) - 1; j >= 0; j-- {
+					if stack[j] == d {
+						for _, k := range stack[j:] {
+							cycle = append(cycle, actions[k].Name)
+						}
+						break
+					}
+				}
+				return append(cycle, actions[d].Name)
+			case unvisited:
+				if cycle := visit(d); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range actions {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func maxConcurrentActionsFromEnv() (int, error) {
+	val := os.Getenv(CcMaxConcurrentActions)
+	if val == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s value %q", CcMaxConcurrentActions, val)
+	}
+	return n, nil
+}
+
+// actionWorkers returns the maximum number of actions run at the same time
+func (pm *PluginManager) actionWorkers() int {
+	if pm.maxConcurrentActions > 0 {
+		return pm.maxConcurrentActions
+	}
+	return runtime.NumCPU()
+}
+
+// runActionGraph runs the actions in dependency order.  Actions whose dependencies have completed
+// are run concurrently, up to the worker limit.  Actions without depends_on run after the actions
+// before them in the payload.  When an action fails and its runner does not continue on errors,
+// the actions that depend on it are skipped while independent actions continue to run.  The errors
+// of all failed and skipped actions are returned together.
+func (pm *PluginManager) runActionGraph(progress *actionProgress) error {
+	deps, err := actionDependencies(pm.Actions)
+	if err != nil {
+		return err
+	}
+	deps = addOrderDependencies(pm.Actions, deps)
+	status := make([]actionStatus, len(pm.Actions))
+	for i, action := range pm.Actions {
+		if progress != nil && progress.isCompleted(i, action.Name) {
+			pm.Logger.Info("Skipping " + action.Name + ", completed by a previous run")
+			status[i] = actionSucceeded
+		}
+	}
+
+	workers := pm.actionWorkers()
+	results := make(chan actionResult)
+	running := 0
+	errs := []error{}
+	for {
+		changed := false
+		for i := range pm.Actions {
+			if status[i] != actionPending {
+				continue
+			}
+			ready := true
+			for _, d := range deps[i] {
+				switch status[d] {
+				case actionFailed, actionSkipped:
+					status[i] = actionSkipped
+					changed = true
+					pm.Logger.Warn("Skipping "+pm.Actions[i].Name+", a dependency failed", "dependency", pm.Actions[d].Name)
+					errs = append(errs, fmt.Errorf("skipped %s: dependency %s did not complete", pm.Actions[i].Name, pm.Actions[d].Name))
+				case actionSucceeded:
+					continue
+				}
+				ready = false
+				break
+			}
+			if !ready || running >= workers {
+				continue
+			}
+			status[i] = actionRunning
+			running++
+			changed = true
+			go func(i int) {
+				continueOnError, err := pm.runAction(i, pm.Actions[i], progress)
+				results <- actionResult{i, continueOnError, err}
+			}(i)
+		}
+		if running == 0 {
+			if changed {
+				//check the dependents of newly skipped actions
+				continue
+			}
+			break
+		}
+		result := <-results
+		running--
+		switch {
+		case result.err == nil:
+			status[result.index] = actionSucceeded
+		case result.continueOnError:
+			status[result.index] = actionSucceeded
+		default:
+			errs = append(errs, result.err)
+			status[result.index] = actionFailed
+		}
+	}
+	return errors.Join(errs...)
+}
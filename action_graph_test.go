@@ -0,0 +1,126 @@
+package cc
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type graphTestRunner struct {
+	ActionRunnerBase
+}
+
+var (
+	graphTestMutex   sync.Mutex
+	graphTestRuns    []string
+	graphTestBarrier sync.WaitGroup
+)
+
+func (r *graphTestRunner) Run() error {
+	graphTestMutex.Lock()
+	graphTestRuns = append(graphTestRuns, r.ActionName)
+	graphTestMutex.Unlock()
+	switch r.Action.Attributes.GetStringOrDefault("mode", "") {
+	case "parallel":
+		//both parallel actions must be running at the same time to pass the barrier
+		graphTestBarrier.Done()
+		done := make(chan struct{})
+		go func() {
+			graphTestBarrier.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			return errors.New("actions did not run concurrently")
+		}
+	case "fail":
+		return errors.New("failed")
+	case "continue":
+		r.ContinueOnError = true
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func TestActionGraph(t *testing.T) {
+	names := []string{"load", "extract", "depth", "velocity", "summary", "independent", "report"}
+	for _, name := range names {
+		ActionRegistry.RegisterAction(name, &graphTestRunner{})
+	}
+	t.Cleanup(func() {
+		for _, name := range names {
+			delete(ActionRegistry, name)
+		}
+	})
+	newManager := func(modes map[string]string) *PluginManager {
+		pm := &PluginManager{Logger: NewCcLogger(CcLoggerInput{}), maxConcurrentActions: 2}
+		pm.Actions = []Action{
+			{Name: "load"},
+			{Name: "summary", DependsOn: []string{"depth", "velocity"}},
+			{Name: "depth", DependsOn: []string{"extract"}},
+			{Name: "velocity", DependsOn: []string{"extract"}},
+			{Name: "extract", DependsOn: []string{"load"}},
+			{Name: "independent", DependsOn: []string{"load"}},
+			{Name: "report"},
+		}
+		for i := range pm.Actions {
+			pm.Actions[i].Attributes = PayloadAttributes{"mode": modes[pm.Actions[i].Name]}
+		}
+		graphTestRuns = nil
+		return pm
+	}
+	position := func(name string) int {
+		for i, run := range graphTestRuns {
+			if run == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	graphTestBarrier.Add(2)
+	err := newManager(map[string]string{"depth": "parallel", "velocity": "parallel"}).RunActions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//actions without depends_on keep their payload order
+	if len(graphTestRuns) != 7 || position("load") != 0 || position("extract") > position("depth") ||
+		position("summary") > position("report") || position("report") != 6 {
+		t.Errorf("unexpected run order: %v", graphTestRuns)
+	}
+
+	//a failed action skips its dependents and independent actions still run
+	err = newManager(map[string]string{"extract": "fail", "independent": "fail"}).RunActions()
+	if err == nil || !strings.Contains(err.Error(), "error running extract") || !strings.Contains(err.Error(), "error running independent") {
+		t.Errorf("expected the errors of both failed actions, got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "skipped summary") || !strings.Contains(err.Error(), "skipped report") {
+		t.Errorf("expected the skipped actions in the error, got %v", err)
+	}
+	if len(graphTestRuns) != 3 {
+		t.Errorf("expected the dependents of extract to be skipped: %v", graphTestRuns)
+	}
+
+	//dependents of actions that continue on errors are run
+	if err = newManager(map[string]string{"extract": "continue"}).RunActions(); err != nil {
+		t.Fatal(err)
+	}
+	if len(graphTestRuns) != 7 {
+		t.Errorf("expected all actions to run: %v", graphTestRuns)
+	}
+
+	pm := newManager(nil)
+	pm.Actions[4].DependsOn = []string{"summary"}
+	if err = pm.RunActions(); err == nil || !strings.Contains(err.Error(), "cycle summary -> depth -> extract -> summary") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+	pm.Actions[4].DependsOn = []string{"unload"}
+	err = pm.Payload.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Path != "actions[4].depends_on[0]" {
+		t.Errorf("expected an undefined dependency error, got %v", err)
+	}
+}
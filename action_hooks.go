@@ -0,0 +1,125 @@
+package cc
+
+import (
+	"time"
+)
+
+// ActionMiddleware wraps the runner of every action, for example to time, retry or prefetch
+// inputs for actions.  Middleware can read the action being run from the next runner with RunnerContext.
+type ActionMiddleware func(next ActionRunner) ActionRunner
+
+// ActionRunnerFunc adapts a function to an ActionRunner
+type ActionRunnerFunc func() error
+
+func (f ActionRunnerFunc) Run() error {
+	return f()
+}
+
+// ActionEvent is passed to the before and after action hooks.  Elapsed and Err
+// are set for after action hooks.  Restored is true for actions restored from the action cache.
+type ActionEvent struct {
+	Action    Action
+	IOManager *IOManager
+	Started   time.Time
+	Elapsed   time.Duration
+	Restored  bool
+	Err       error
+}
+
+// PluginEvent is passed to the plugin start and end hooks.  Elapsed and Err are set for end hooks.
+type PluginEvent struct {
+	PluginManager *PluginManager
+	Started       time.Time
+	Elapsed       time.Duration
+	Err           error
+}
+
+type actionHooks struct {
+	pluginStart  []func(event PluginEvent) error
+	pluginEnd    []func(event PluginEvent)
+	beforeAction []func(event ActionEvent) error
+	afterAction  []func(event ActionEvent)
+}
+
+// Use adds action middleware.  Middleware added first is the outermost wrapper of the runner.
+// Middleware and hooks must be added before RunActions is called.
+func (pm *PluginManager) Use(middleware ...ActionMiddleware) {
+	pm.middleware = append(pm.middleware, middleware...)
+}
+
+// OnPluginStart adds a hook called by RunActions before the actions run.
+// An error stops the run and is returned by RunActions.
+func (pm *PluginManager) OnPluginStart(hook func(event PluginEvent) error) {
+	pm.hooks.pluginStart = append(pm.hooks.pluginStart, hook)
+}
+
+// OnPluginEnd adds a hook called by RunActions after the actions run, with the error returned by RunActions
+func (pm *PluginManager) OnPluginEnd(hook func(event PluginEvent)) {
+	pm.hooks.pluginEnd = append(pm.hooks.pluginEnd, hook)
+}
+
+// BeforeAction adds a hook called before each action runs.  An error fails the action
+// without running it.
+func (pm *PluginManager) BeforeAction(hook func(event ActionEvent) error) {
+	pm.hooks.beforeAction = append(pm.hooks.beforeAction, hook)
+}
+
+// AfterAction adds a hook called after each action runs, with the elapsed time and error of the action
+func (pm *PluginManager) AfterAction(hook func(event ActionEvent)) {
+	pm.hooks.afterAction = append(pm.hooks.afterAction, hook)
+}
+
+// RunnerContext returns the action of a runner passed to ActionMiddleware
+func RunnerContext(runner ActionRunner) (ActionContext, bool) {
+	if cr, ok := runner.(contextRunner); ok {
+		return cr.ctx, true
+	}
+	return ActionContext{}, false
+}
+
+// contextRunner carries the action context through the middleware chain
+type contextRunner struct {
+	ActionRunner
+	ctx ActionContext
+}
+
+// wrapActionRunner applies the middleware to an action runner
+func (pm *PluginManager) wrapActionRunner(runner ActionRunner, ctx ActionContext) ActionRunner {
+	wrapped := ActionRunner(contextRunner{runner, ctx})
+	for i := len(pm.middleware) - 1; i >= 0; i-- {
+		wrapped = contextRunner{pm.middleware[i](wrapped), ctx}
+	}
+	return wrapped
+}
+
+func (pm *PluginManager) pluginStart(event PluginEvent) error {
+	for _, hook := range pm.hooks.pluginStart {
+		if err := hook(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pm *PluginManager) pluginEnd(event PluginEvent) {
+	event.Elapsed = time.Since(event.Started)
+	for _, hook := range pm.hooks.pluginEnd {
+		hook(event)
+	}
+}
+
+func (pm *PluginManager) beforeAction(event ActionEvent) error {
+	for _, hook := range pm.hooks.beforeAction {
+		if err := hook(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pm *PluginManager) afterAction(event ActionEvent) {
+	event.Elapsed = time.Since(event.Started)
+	for _, hook := range pm.hooks.afterAction {
+		hook(event)
+	}
+}
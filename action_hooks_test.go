@@ -0,0 +1,115 @@
+package cc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type continuingRunner struct {
+	ActionRunnerBase
+}
+
+func (r *continuingRunner) Run() error {
+	r.ContinueOnError = true
+	return errors.New("partial results")
+}
+
+func TestActionHooks(t *testing.T) {
+	attempts := 0
+	RegisterActionFunc("flaky", func(ctx ActionContext) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	RegisterActionFunc("blocked", func(ctx ActionContext) error {
+		t.Error("expected the blocked action not to run")
+		return nil
+	})
+	t.Cleanup(func() {
+		delete(ActionFactories, "flaky")
+		delete(ActionFactories, "blocked")
+	})
+
+	events := []string{}
+	pm := &PluginManager{Logger: NewCcLogger(CcLoggerInput{})}
+	pm.Actions = []Action{{Name: "flaky", IOManager: IOManager{Attributes: PayloadAttributes{"retries": 1}}}}
+
+	//retry middleware reads the retry count from the action attributes
+	pm.Use(func(next ActionRunner) ActionRunner {
+		return ActionRunnerFunc(func() error {
+			ctx, ok := RunnerContext(next)
+			if !ok {
+				return errors.New("missing runner context")
+			}
+			retries := ctx.Action.Attributes.GetIntOrDefault("retries", 0)
+			err := next.Run()
+			for i := 0; err != nil && i < retries; i++ {
+				events = append(events, "retry "+ctx.ActionName)
+				err = next.Run()
+			}
+			return err
+		})
+	})
+	pm.OnPluginStart(func(event PluginEvent) error {
+		events = append(events, "start")
+		return nil
+	})
+	pm.OnPluginEnd(func(event PluginEvent) {
+		events = append(events, fmt.Sprintf("end %v", event.Err))
+	})
+	pm.BeforeAction(func(event ActionEvent) error {
+		events = append(events, "before "+event.Action.Name)
+		if event.Action.Name == "blocked" {
+			return errors.New("prefetch failed")
+		}
+		return nil
+	})
+	pm.AfterAction(func(event ActionEvent) {
+		if event.Elapsed <= 0 || event.IOManager == nil {
+			t.Errorf("unexpected after action event: %+v", event)
+		}
+		events = append(events, fmt.Sprintf("after %s %v", event.Action.Name, event.Err))
+	})
+
+	if err := pm.RunActions(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "start,before flaky,retry flaky,after flaky <nil>,end <nil>"
+	if strings.Join(events, ",") != expected {
+		t.Errorf("expected events %s, got %s", expected, strings.Join(events, ","))
+	}
+
+	//a failed before action hook fails the action without running it
+	events = nil
+	pm.Actions = []Action{{Name: "blocked"}}
+	err := pm.RunActions()
+	if err == nil || !strings.Contains(err.Error(), "error running blocked: prefetch failed") {
+		t.Errorf("expected the before action hook error, got %v", err)
+	}
+	if len(events) != 4 || events[2] != "after blocked prefetch failed" || !strings.HasPrefix(events[3], "end error running blocked") {
+		t.Errorf("unexpected events: %v", events)
+	}
+
+	//actions that continue on errors are logged as failed and the after hook gets the error
+	Register[continuingRunner]("partial")
+	t.Cleanup(func() { delete(ActionFactories, "partial") })
+	buf := bytes.Buffer{}
+	pm.Logger = &CcLogger{Logger: slog.New(slog.NewJSONHandler(&buf, ccLoggerOpts(slog.LevelDebug)))}
+	events = nil
+	pm.Actions = []Action{{Name: "partial"}}
+	if err = pm.RunActions(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 || events[2] != "after partial partial results" {
+		t.Errorf("unexpected events: %v", events)
+	}
+	if log := buf.String(); !strings.Contains(log, `"level":"ERROR","msg":"Failed partial, continuing"`) || strings.Contains(log, "Completed partial") {
+		t.Errorf("expected the continued action to be logged as failed: %s", log)
+	}
+}
@@ -0,0 +1,158 @@
+package cc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ActionContext is the action a runner is created for
+type ActionContext struct {
+	PluginManager *PluginManager
+	Action        Action
+	ActionName    string
+}
+
+// Log logs an action message
+func (ctx ActionContext) Log(msg string, args ...any) {
+	args = append(args, "action", ctx.ActionName)
+	ctx.PluginManager.Logger.Action(msg, args...)
+}
+
+// ActionFunc runs an action
+type ActionFunc func(ctx ActionContext) error
+
+// ActionFactory creates the runner for an action.  A new runner is created for each action run.
+type ActionFactory func(ctx ActionContext) (ActionRunner, error)
+
+// ActionInitializer is implemented by runners that are given their action context when they are
+// created.  Runners embedding ActionRunnerBase implement it.
+type ActionInitializer interface {
+	InitAction(ctx ActionContext)
+}
+
+// ActionErrorPolicy is implemented by runners that can continue with the remaining
+// actions when they fail.  Runners embedding ActionRunnerBase implement it.
+type ActionErrorPolicy interface {
+	ContinuesOnError() bool
+}
+
+type ActionFactoryRegistry map[string]ActionFactory
+
+// ActionFactories create the runners of actions.  Factories take precedence over runners
+// registered in the ActionRegistry.
+var ActionFactories ActionFactoryRegistry = make(map[string]ActionFactory)
+
+func (afr *ActionFactoryRegistry) RegisterActionFactory(actionName string, factory ActionFactory) {
+	(*afr)[actionName] = factory
+}
+
+// RegisterActionFunc registers a function that runs an action
+func RegisterActionFunc(actionName string, fn ActionFunc) {
+	ActionFactories.RegisterActionFactory(actionName, func(ctx ActionContext) (ActionRunner, error) {
+		return actionFuncRunner{fn, ctx}, nil
+	})
+}
+
+// Register registers a runner type for an action.  A new runner is created for each run and
+// initialized with InitAction, so the runner type must embed ActionRunnerBase or implement
+// ActionInitializer.  For example:
+//
+//	cc.Register[MyRunner]("compute")
+func Register[T any, PT interface {
+	*T
+	ActionRunner
+	ActionInitializer
+}](actionName string) {
+	ActionFactories.RegisterActionFactory(actionName, func(ctx ActionContext) (ActionRunner, error) {
+		runner := PT(new(T))
+		runner.InitAction(ctx)
+		return runner, nil
+	})
+}
+
+type actionFuncRunner struct {
+	fn  ActionFunc
+	ctx ActionContext
+}
+
+func (r actionFuncRunner) Run() error {
+	return r.fn(r.ctx)
+}
+
+// InitAction sets the plugin manager, action and action name of the runner
+func (arb *ActionRunnerBase) InitAction(ctx ActionContext) {
+	arb.PluginManager = ctx.PluginManager
+	arb.Action = ctx.Action
+	arb.ActionName = ctx.ActionName
+}
+
+func (arb ActionRunnerBase) ContinuesOnError() bool {
+	return arb.ContinueOnError
+}
+
+// isActionRegistered returns true if an action has a registered factory or runner
+func isActionRegistered(actionName string) bool {
+	if _, ok := ActionFactories[actionName]; ok {
+		return true
+	}
+	_, ok := ActionRegistry[actionName]
+	return ok
+}
+
+// hasRegisteredActions returns true if any action factories or runners are registered
+func hasRegisteredActions() bool {
+	return len(ActionFactories) > 0 || len(ActionRegistry) > 0
+}
+
+// newActionRunner creates the runner for an action from the ActionFactories or the ActionRegistry.
+// Actions without a registered runner are errors.
+func newActionRunner(ctx ActionContext) (ActionRunner, error) {
+	if factory, ok := ActionFactories[ctx.ActionName]; ok {
+		return factory(ctx)
+	}
+	runner, ok := ActionRegistry[ctx.ActionName]
+	if !ok {
+		return nil, fmt.Errorf("no action runner is registered for %s", ctx.ActionName)
+	}
+	return newRegisteredRunner(runner, ctx)
+}
+
+// newRegisteredRunner creates a new instance of an ActionRegistry runner.  Runners implementing
+// ActionInitializer are initialized with InitAction.  Other runners must have PluginManager,
+// Action and ActionName fields.
+func newRegisteredRunner(runner ActionRunner, ctx ActionContext) (ActionRunner, error) {
+	t := reflect.TypeOf(runner)
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("action runner %T for %s must be a pointer to a struct", runner, ctx.ActionName)
+	}
+	instance := reflect.New(t.Elem()) //runner is a pointer, so create a new instance of the struct it points to
+	newRunner := instance.Interface().(ActionRunner)
+	if initializer, ok := newRunner.(ActionInitializer); ok {
+		initializer.InitAction(ctx)
+		return newRunner, nil
+	}
+	fields := map[string]any{"PluginManager": ctx.PluginManager, "Action": ctx.Action, "ActionName": ctx.ActionName}
+	for _, name := range sortedKeys(fields) {
+		field := instance.Elem().FieldByName(name)
+		val := reflect.ValueOf(fields[name])
+		if !field.IsValid() || !field.CanSet() || !val.Type().AssignableTo(field.Type()) {
+			return nil, fmt.Errorf("action runner %T for %s is missing the %s field of type %s", runner, ctx.ActionName, name, val.Type())
+		}
+		field.Set(val)
+	}
+	return newRunner, nil
+}
+
+// continuesOnError returns true if a runner continues with the remaining actions when it fails.
+// Runners can set ContinueOnError while running.
+func continuesOnError(runner ActionRunner) bool {
+	if policy, ok := runner.(ActionErrorPolicy); ok {
+		return policy.ContinuesOnError()
+	}
+	v := reflect.ValueOf(runner)
+	if v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct {
+		field := v.Elem().FieldByName("ContinueOnError")
+		return field.IsValid() && field.Kind() == reflect.Bool && field.Bool()
+	}
+	return false
+}
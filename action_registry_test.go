@@ -0,0 +1,77 @@
+package cc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type typedRunner struct {
+	ActionRunnerBase
+}
+
+var typedRunnerScale string
+
+func (r *typedRunner) Run() error {
+	typedRunnerScale = r.Action.Attributes.GetStringOrDefault("scale", "")
+	if r.PluginManager == nil || r.ActionName != "typed" {
+		return errors.New("runner was not initialized")
+	}
+	return nil
+}
+
+// misnamedRunner does not embed ActionRunnerBase and misspells the Action field
+type misnamedRunner struct {
+	PluginManager *PluginManager
+	Actoin        Action
+	ActionName    string
+}
+
+func (r *misnamedRunner) Run() error {
+	return nil
+}
+
+func TestActionRegistration(t *testing.T) {
+	Register[typedRunner]("typed")
+	var funcAction string
+	RegisterActionFunc("func", func(ctx ActionContext) error {
+		funcAction = ctx.ActionName + ":" + ctx.Action.Attributes.GetStringOrDefault("scale", "")
+		return nil
+	})
+	ActionRegistry.RegisterAction("misnamed", &misnamedRunner{})
+	t.Cleanup(func() {
+		delete(ActionFactories, "typed")
+		delete(ActionFactories, "func")
+		delete(ActionRegistry, "misnamed")
+	})
+
+	pm := &PluginManager{Logger: NewCcLogger(CcLoggerInput{})}
+	pm.Actions = []Action{
+		{Name: "typed", IOManager: IOManager{Attributes: PayloadAttributes{"scale": "2"}}},
+		{Name: "func", IOManager: IOManager{Attributes: PayloadAttributes{"scale": "3"}}},
+	}
+	if err := pm.RunActions(); err != nil {
+		t.Fatal(err)
+	}
+	if typedRunnerScale != "2" || funcAction != "func:3" {
+		t.Errorf("unexpected action runs: %q %q", typedRunnerScale, funcAction)
+	}
+
+	//misnamed runner fields and unknown actions are errors
+	pm.Actions = []Action{{Name: "misnamed"}}
+	if err := pm.RunActions(); err == nil || !strings.Contains(err.Error(), "is missing the Action field") {
+		t.Errorf("expected a runner field error, got %v", err)
+	}
+	//unknown actions fail the run before any action runs
+	typedRunnerScale = "unset"
+	pm.Actions = []Action{{Name: "typed"}, {Name: "unknown"}}
+	if err := pm.RunActions(); err == nil || !strings.Contains(err.Error(), "no action runner is registered for unknown") {
+		t.Errorf("expected an unknown action error, got %v", err)
+	}
+	if typedRunnerScale != "unset" {
+		t.Error("expected no actions to run")
+	}
+	if err := pm.Payload.Validate(); err == nil || !strings.Contains(err.Error(), "actions[1].name") {
+		t.Errorf("expected a validation error for the unknown action, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package cc
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/spf13/cast"
+)
+
+// AttributeConstraint restricts the values of an attribute.  Constraints are declared on plugin
+// definition attributes or registered by the plugin in AttributeConstraints.  List values are
+// checked element by element.  Strings containing substitution templates are not checked.
+type AttributeConstraint struct {
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+	Enum    []any    `json:"enum,omitempty"`
+
+	//regular expression the value must match.  Like JSON Schema patterns, the expression is not anchored
+	Pattern string `json:"pattern,omitempty"`
+
+	//the attribute is required when the condition is true
+	RequiredIf *AttributeCondition `json:"required_if,omitempty"`
+
+	//unit of numeric values.  Unit annotated values (see ParseQuantity) must be convertible
+	//to the unit and are converted before they are compared to the Minimum and Maximum
+	Unit string `json:"unit,omitempty"`
+}
+
+// AttributeCondition is true when an attribute in the same attribute set is present and,
+// if Values is not empty, equal to one of the values
+type AttributeCondition struct {
+	Attribute string `json:"attribute"`
+	Values    []any  `json:"values,omitempty"`
+}
+
+type AttributeConstraintRegistry map[string]AttributeConstraint
+
+// AttributeConstraints are checked for the payload attributes and for each action's attributes
+// when the payload is validated, and when the attribute is read with GetAttribute.
+// Attribute names can be dotted paths.
+var AttributeConstraints AttributeConstraintRegistry = make(map[string]AttributeConstraint)
+
+func (acr *AttributeConstraintRegistry) RegisterConstraint(attributeName string, constraint AttributeConstraint) {
+	(*acr)[attributeName] = constraint
+}
+
+// Bound returns a pointer to a Minimum or Maximum constraint value
+func Bound(val float64) *float64 {
+	return &val
+}
+
+// Violations returns a message for each constraint the value does not satisfy
+func (c AttributeConstraint) Violations(val any) []string {
+	if list, ok := val.([]any); ok {
+		msgs := []string{}
+		for i, v := range list {
+			for _, msg := range c.Violations(v) {
+				msgs = append(msgs, fmt.Sprintf("[%d] %s", i, msg))
+			}
+		}
+		return msgs
+	}
+	if s, ok := val.(string); ok && containsTemplate(s) {
+		return nil
+	}
+
+	msgs := []string{}
+	if c.Minimum != nil || c.Maximum != nil || c.Unit != "" {
+		q, err := ParseQuantity(val)
+		num := q.Value
+		if err == nil && c.Unit != "" {
+			num, err = q.As(c.Unit)
+		}
+		switch {
+		case err != nil:
+			msgs = append(msgs, err.Error())
+		case c.Minimum != nil && num < *c.Minimum:
+			msgs = append(msgs, fmt.Sprintf("value %v is less than the minimum of %v", num, *c.Minimum))
+		case c.Maximum != nil && num > *c.Maximum:
+			msgs = append(msgs, fmt.Sprintf("value %v is greater than the maximum of %v", num, *c.Maximum))
+		}
+	}
+	if len(c.Enum) > 0 && !attributeValueIn(val, c.Enum) {
+		msgs = append(msgs, fmt.Sprintf("value %v is not one of %v", val, c.Enum))
+	}
+	if c.Pattern != "" {
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("invalid pattern %s: %s", c.Pattern, err))
+		} else if s, err := cast.ToStringE(val); err != nil || !re.MatchString(s) {
+			msgs = append(msgs, fmt.Sprintf("value %v does not match the pattern %s", val, c.Pattern))
+		}
+	}
+	return msgs
+}
+
+// Holds returns true if the condition is true for the attributes
+func (ac AttributeCondition) Holds(attrs PayloadAttributes) bool {
+	val, ok := attrs.lookup(ac.Attribute)
+	if !ok {
+		return false
+	}
+	return len(ac.Values) == 0 || attributeValueIn(val, ac.Values)
+}
+
+func (ac AttributeCondition) String() string {
+	if len(ac.Values) == 0 {
+		return ac.Attribute + " is set"
+	}
+	return fmt.Sprintf("%s is one of %v", ac.Attribute, ac.Values)
+}
+
+// check adds the constraint violations of an attribute to errs
+func (c AttributeConstraint) check(path string, name string, attrs PayloadAttributes, errs *ValidationErrors) {
+	val, ok := attrs.lookup(name)
+	if !ok {
+		if c.RequiredIf != nil && c.RequiredIf.Holds(attrs) {
+			errs.add(path, "attribute is required when %s", c.RequiredIf)
+		}
+		return
+	}
+	for _, msg := range c.Violations(val) {
+		errs.add(path, "%s", msg)
+	}
+}
+
+// checkRegisteredConstraints checks the attributes against the registered AttributeConstraints
+func checkRegisteredConstraints(path string, attrs PayloadAttributes, errs *ValidationErrors) {
+	for _, name := range sortedKeys(AttributeConstraints) {
+		AttributeConstraints[name].check(path+"."+name, name, attrs, errs)
+	}
+}
+
+// attributeValueIn compares values by their string form so that json numbers match integer options
+func attributeValueIn(val any, options []any) bool {
+	s := templateValue(val)
+	for _, option := range options {
+		if templateValue(option) == s {
+			return true
+		}
+	}
+	return false
+}
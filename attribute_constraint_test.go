@@ -0,0 +1,94 @@
+package cc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type constraintTestRunner struct {
+	ActionRunnerBase
+}
+
+func (r *constraintTestRunner) Run() error {
+	r.Action.Attributes.GetIntOrFail("timestep")
+	return nil
+}
+
+func TestAttributeConstraints(t *testing.T) {
+	def := PluginDefinition{
+		Name: "router",
+		Attributes: []AttributeDefinition{
+			{Name: "timestep", Type: ATTRIBUTE_INT, AttributeConstraint: AttributeConstraint{Minimum: Bound(1), Maximum: Bound(3600), Unit: "s"}},
+			{Name: "method", Type: ATTRIBUTE_STRING, Default: "kinematic", AttributeConstraint: AttributeConstraint{Enum: []any{"kinematic", "diffusive"}}},
+			{Name: "gage", Type: ATTRIBUTE_STRING, AttributeConstraint: AttributeConstraint{Pattern: `^[0-9]{8}$`}},
+			{Name: "weights", Type: ATTRIBUTE_STRING, AttributeConstraint: AttributeConstraint{
+				RequiredIf: &AttributeCondition{Attribute: "method", Values: []any{"diffusive"}},
+			}},
+		},
+	}
+	payload := Payload{IOManager: IOManager{Attributes: PayloadAttributes{
+		"timestep": -5.0,
+		"method":   "diffusive",
+		"gage":     "0319800",
+	}}}
+	err := def.CheckPayload(&payload)
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 3 {
+		t.Fatalf("expected three validation errors, got %v", err)
+	}
+	for i, path := range []string{"attributes.timestep", "attributes.gage", "attributes.weights"} {
+		if verrs[i].Path != path {
+			t.Errorf("expected error %d at %s, got %s", i, path, verrs[i])
+		}
+	}
+
+	payload.Attributes = PayloadAttributes{"timestep": "30 s", "gage": "03198000", "method": "{ENV::CC_TEST_METHOD}"}
+	if err = def.CheckPayload(&payload); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	payload.Attributes = PayloadAttributes{"timestep": "2 h"}
+	if err = def.CheckPayload(&payload); err == nil || !strings.Contains(err.Error(), "value 7200 is greater than the maximum") {
+		t.Errorf("expected the converted value to exceed the maximum, got %v", err)
+	}
+	payload.Attributes = PayloadAttributes{"timestep": "30 ft"}
+	if err = def.CheckPayload(&payload); err == nil || !strings.Contains(err.Error(), "can not convert ft") {
+		t.Errorf("expected a unit error, got %v", err)
+	}
+
+	//registered constraints are checked by Validate and by the accessors
+	AttributeConstraints.RegisterConstraint("timestep", AttributeConstraint{Minimum: Bound(1)})
+	t.Cleanup(func() { delete(AttributeConstraints, "timestep") })
+	payload = Payload{
+		IOManager: IOManager{Attributes: PayloadAttributes{"timestep": 0.0}},
+		Actions:   []Action{{Name: "route", IOManager: IOManager{Attributes: PayloadAttributes{"timestep": -1.0}}}},
+	}
+	err = payload.Validate()
+	if !errors.As(err, &verrs) || len(verrs) != 2 || verrs[1].Path != "actions[0].attributes.timestep" {
+		t.Errorf("unexpected validation errors: %v", err)
+	}
+	if _, err = payload.Attributes.GetInt("timestep"); err == nil {
+		t.Error("expected a constraint error from GetInt")
+	}
+	if v := payload.Attributes.GetIntOrDefault("missing", 60); v != 60 {
+		t.Errorf("expected the default for a missing value, got %d", v)
+	}
+	func() {
+		defer recoverAttributeError(&err)
+		err = nil
+		payload.Attributes.GetIntOrDefault("timestep", 60)
+	}()
+	if err == nil || !strings.Contains(err.Error(), "attribute timestep") {
+		t.Errorf("expected a constraint error from GetIntOrDefault, got %v", err)
+	}
+
+	//GetOrFail errors are returned from RunActions
+	ActionRegistry.RegisterAction("route", &constraintTestRunner{})
+	t.Cleanup(func() { delete(ActionRegistry, "route") })
+	pm := PluginManager{Logger: NewCcLogger(CcLoggerInput{})}
+	pm.Payload = payload
+	err = pm.RunActions()
+	if err == nil || !strings.Contains(err.Error(), "error running route: attribute timestep") {
+		t.Errorf("expected an attribute error from the action, got %v", err)
+	}
+}
@@ -16,8 +16,9 @@ type StoreType string
 
 const (
 	//S3    StoreType = "S3"
-	FSS3  StoreType = "S3" //aws S3
-	FSB   StoreType = "FS" //mounted file system
+	FSS3  StoreType = "S3"  //aws S3
+	FSB   StoreType = "FS"  //mounted file system
+	COG   StoreType = "COG" //cloud optimized geotiffs in an S3 or FS backend store
 	WS    StoreType = "WS"
 	RDBMS StoreType = "RDBMS"
 	EBS   StoreType = "EBS"
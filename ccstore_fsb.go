@@ -1,7 +1,6 @@
 package cc
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -93,23 +92,30 @@ func (fs *FSBCcStore) GetObject(input GetObjectInput) ([]byte, error) {
 	return data, nil
 }
 
-// GetPayload retrieves the payload from the local file system
+// GetPayload retrieves the payload from the local file system.
+// The payload can be json, yaml or toml (see payloadFileNames)
 func (fs *FSBCcStore) GetPayload() (Payload, error) {
-	var payload Payload
-
-	filePath := filepath.Join(fs.remoteRootPath, fs.payloadId, payloadFileName)
-
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return payload, fmt.Errorf("failed to read payload file: %w", err)
+	for _, name := range payloadFileNames {
+		filePath := filepath.Join(fs.remoteRootPath, fs.payloadId, name)
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return Payload{}, fmt.Errorf("failed to read payload file: %w", err)
+		}
+		return readPayload(name, data)
 	}
+	return Payload{}, fmt.Errorf("failed to read payload file: no payload found in %s", filepath.Join(fs.remoteRootPath, fs.payloadId))
+}
 
-	err = json.Unmarshal(data, &payload)
+// GetPayloadInclude reads a payload fragment relative to the root path
+func (fs *FSBCcStore) GetPayloadInclude(path string) (Payload, error) {
+	data, err := os.ReadFile(filepath.Join(fs.remoteRootPath, path))
 	if err != nil {
-		return payload, fmt.Errorf("failed to unmarshal payload: %w", err)
+		return Payload{}, fmt.Errorf("failed to read payload include: %w", err)
 	}
-
-	return payload, nil
+	return readPayload(path, data)
 }
 
 // SetPayload stores a payload in the local file system
@@ -121,21 +127,43 @@ func (fs *FSBCcStore) SetPayload(p Payload) error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	_, shouldFormat := os.LookupEnv(CcPayloadFormatted)
-	var data []byte
-	var err error
-
-	if shouldFormat {
-		data, err = json.MarshalIndent(p, "", "  ")
-	} else {
-		data, err = json.Marshal(p)
+	data, err := writePayload(p)
+	if err != nil {
+		return err
 	}
 
+	return os.WriteFile(filePath, data, 0644)
+}
+
+// PutCheckpoint stores checkpoint data under the manifest directory
+func (fs *FSBCcStore) PutCheckpoint(key string, reader io.Reader) error {
+	filePath := filepath.Join(fs.remoteRootPath, fs.manifestId, checkpointPrefix, key)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	//write to a temporary file so that an interrupted write does not replace the checkpoint
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".checkpoint-*")
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return err
 	}
+	_, err = io.Copy(tmp, reader)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
 
-	return os.WriteFile(filePath, data, 0644)
+// GetCheckpoint reads checkpoint data from the manifest directory
+func (fs *FSBCcStore) GetCheckpoint(key string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(fs.remoteRootPath, fs.manifestId, checkpointPrefix, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCheckpointNotFound
+	}
+	return file, err
 }
 
 // PullObject copies a file from the remote location to local directory
@@ -2,12 +2,12 @@ package cc
 
 import (
 	"bufio"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/retry"
@@ -110,34 +110,78 @@ func (ws *S3CcStore) GetObject(input GetObjectInput) ([]byte, error) {
 }
 
 // GetPayload produces a Payload for the current manifestId of the environment from S3 based on the remoteRootPath set in the configuration of the environment.
+// The payload can be json, yaml or toml (see payloadFileNames)
 func (ws *S3CcStore) GetPayload() (Payload, error) {
-	payload := Payload{}
-	path := filestore.PathConfig{Path: fmt.Sprintf("%s/%s/%s", ws.remoteRootPath, ws.payloadId, payloadFileName)}
+	for _, name := range payloadFileNames {
+		path := filestore.PathConfig{Path: fmt.Sprintf("%s/%s/%s", ws.remoteRootPath, ws.payloadId, name)}
+		fsgoi := filestore.GetObjectInput{
+			Path: path,
+		}
+		reader, err := ws.fs.GetObject(fsgoi)
+		if err != nil {
+			var notFound *filestore.FileNotFoundError
+			if errors.As(err, &notFound) {
+				continue
+			}
+			return Payload{}, err
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return Payload{}, err
+		}
+		return readPayload(name, data)
+	}
+	return Payload{}, fmt.Errorf("no payload found in %s/%s", ws.remoteRootPath, ws.payloadId)
+}
+
+// GetPayloadInclude reads a payload fragment relative to the remote root path
+func (ws *S3CcStore) GetPayloadInclude(path string) (Payload, error) {
 	fsgoi := filestore.GetObjectInput{
-		Path: path,
+		Path: filestore.PathConfig{Path: fmt.Sprintf("%s/%s", ws.remoteRootPath, strings.TrimPrefix(path, "/"))},
 	}
 	reader, err := ws.fs.GetObject(fsgoi)
 	if err != nil {
-		return payload, err
+		return Payload{}, err
 	}
 	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return Payload{}, err
+	}
+	return readPayload(path, data)
+}
 
-	err = json.NewDecoder(reader).Decode(&payload)
+// PutCheckpoint stores checkpoint data under the manifest prefix
+func (ws *S3CcStore) PutCheckpoint(key string, reader io.Reader) error {
+	fspoi := filestore.PutObjectInput{
+		Dest: filestore.PathConfig{Path: fmt.Sprintf("%s/%s/%s/%s", ws.remoteRootPath, ws.manifestId, checkpointPrefix, key)},
+		Source: filestore.ObjectSource{
+			Reader: reader,
+		},
+		Mutipart: true,
+	}
+	_, err := ws.fs.PutObject(fspoi)
+	return err
+}
 
-	return payload, err
+// GetCheckpoint reads checkpoint data from the manifest prefix
+func (ws *S3CcStore) GetCheckpoint(key string) (io.ReadCloser, error) {
+	fsgoi := filestore.GetObjectInput{
+		Path: filestore.PathConfig{Path: fmt.Sprintf("%s/%s/%s/%s", ws.remoteRootPath, ws.manifestId, checkpointPrefix, key)},
+	}
+	reader, err := ws.fs.GetObject(fsgoi)
+	var notFound *filestore.FileNotFoundError
+	if errors.As(err, &notFound) {
+		return nil, ErrCheckpointNotFound
+	}
+	return reader, err
 }
 
 // SetPayload sets a payload. This is designed for cloud compute to use, please do not use this method in a plugin.
 func (ws *S3CcStore) SetPayload(p Payload) error {
-	s3path := filestore.PathConfig{Path: fmt.Sprintf("%s/%s/%s", ws.remoteRootPath, ws.payloadId, "payload")}
-	_, shouldFormat := os.LookupEnv(CcPayloadFormatted)
-	var data []byte
-	var err error
-	if shouldFormat {
-		data, err = json.MarshalIndent(p, "", "  ")
-	} else {
-		data, err = json.Marshal(p)
-	}
+	s3path := filestore.PathConfig{Path: fmt.Sprintf("%s/%s/%s", ws.remoteRootPath, ws.payloadId, payloadFileName)}
+	data, err := writePayload(p)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,172 @@
+package cc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	checkpointPrefix = "checkpoints"
+
+	//checkpoint key of the action progress of a run
+	actionProgressKey = "_actions.json"
+
+	//checkpoint scope of runs without an event identifier
+	defaultCheckpointEvent = "default"
+)
+
+// ErrCheckpointNotFound is returned by LoadCheckpoint for checkpoints that have not been saved
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// CheckpointStore is implemented by CcStores that store checkpoints under the manifest prefix.
+// Keys are relative slash separated paths.  GetCheckpoint returns ErrCheckpointNotFound
+// for keys that have not been saved.
+type CheckpointStore interface {
+	PutCheckpoint(key string, reader io.Reader) error
+	GetCheckpoint(key string) (io.ReadCloser, error)
+}
+
+// actionProgress records the actions completed by a run so that a restarted run
+// resumes after them.  Progress is discarded when the payload changes.
+type actionProgress struct {
+	PayloadHash string            `json:"payload_hash"`
+	Completed   []completedAction `json:"completed"`
+	Finished    bool              `json:"finished"`
+	mutex       sync.Mutex        //concurrent actions complete at the same time
+}
+
+type completedAction struct {
+	Index int       `json:"index"`
+	Name  string    `json:"name"`
+	Time  time.Time `json:"time"`
+}
+
+func (ap *actionProgress) isCompleted(index int, name string) bool {
+	for _, c := range ap.Completed {
+		if c.Index == index && c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveCheckpoint stores checkpoint data under the manifest prefix of the CcStore.  Checkpoints
+// are scoped to the event, so each event of a manifest has its own checkpoints.
+// Action runners can use checkpoints to restore their state when a run is restarted (see LoadCheckpoint).
+func (pm *PluginManager) SaveCheckpoint(key string, reader io.Reader) error {
+	store, storeKey, err := pm.checkpointStore(key)
+	if err != nil {
+		return err
+	}
+	if err = store.PutCheckpoint(storeKey, reader); err != nil {
+		return fmt.Errorf("failed to save checkpoint %s: %w", key, err)
+	}
+	return nil
+}
+
+// LoadCheckpoint reads checkpoint data saved by SaveCheckpoint in this or a previous run of the event.
+// Returns an error wrapping ErrCheckpointNotFound if the checkpoint has not been saved.
+func (pm *PluginManager) LoadCheckpoint(key string) (io.ReadCloser, error) {
+	store, storeKey, err := pm.checkpointStore(key)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := store.GetCheckpoint(storeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint %s: %w", key, err)
+	}
+	return reader, nil
+}
+
+// SaveCheckpoint stores checkpoint data for the action (see PluginManager.SaveCheckpoint)
+func (arb *ActionRunnerBase) SaveCheckpoint(key string, reader io.Reader) error {
+	return arb.PluginManager.SaveCheckpoint(path.Join(arb.ActionName, key), reader)
+}
+
+// LoadCheckpoint reads checkpoint data saved for the action (see PluginManager.LoadCheckpoint)
+func (arb *ActionRunnerBase) LoadCheckpoint(key string) (io.ReadCloser, error) {
+	return arb.PluginManager.LoadCheckpoint(path.Join(arb.ActionName, key))
+}
+
+// checkpointStore returns the checkpoint store and the store key of a checkpoint
+func (pm *PluginManager) checkpointStore(key string) (CheckpointStore, string, error) {
+	store, ok := pm.ccStore.(CheckpointStore)
+	if !ok {
+		return nil, "", errors.New("the cc store does not support checkpoints")
+	}
+	clean := path.Clean(key)
+	if key == "" || path.IsAbs(key) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return nil, "", fmt.Errorf("invalid checkpoint key %q", key)
+	}
+	event := pm.EventIdentifier
+	if event == "" {
+		event = defaultCheckpointEvent
+	}
+	return store, path.Join(event, clean), nil
+}
+
+// loadActionProgress reads the action progress of a previous run of the event.  Returns nil if
+// the cc store does not support checkpoints.  Progress of a finished run or of a different
+// payload is discarded.
+func (pm *PluginManager) loadActionProgress() *actionProgress {
+	if _, ok := pm.ccStore.(CheckpointStore); !ok {
+		return nil
+	}
+	hash, err := pm.Payload.Hash()
+	if err != nil {
+		pm.Logger.Warn("action progress is not tracked", "reason", err.Error())
+		return nil
+	}
+	progress := &actionProgress{PayloadHash: hash, Completed: []completedAction{}}
+	reader, err := pm.LoadCheckpoint(actionProgressKey)
+	if err != nil {
+		if !errors.Is(err, ErrCheckpointNotFound) {
+			pm.Logger.Warn("failed to read the action progress", "reason", err.Error())
+		}
+		return progress
+	}
+	defer reader.Close()
+	previous := actionProgress{}
+	if err = json.NewDecoder(reader).Decode(&previous); err != nil {
+		pm.Logger.Warn("invalid action progress", "reason", err.Error())
+		return progress
+	}
+	if previous.Finished || previous.PayloadHash != hash {
+		return progress
+	}
+	progress.Completed = previous.Completed
+	return progress
+}
+
+// saveActionProgress writes the action progress.  Failures are logged so that a
+// checkpoint store failure does not fail the run.
+func (pm *PluginManager) saveActionProgress(progress *actionProgress) {
+	if progress == nil {
+		return
+	}
+	data, err := json.Marshal(progress)
+	if err == nil {
+		err = pm.SaveCheckpoint(actionProgressKey, bytes.NewReader(data))
+	}
+	if err != nil {
+		pm.Logger.Warn("failed to save the action progress", "reason", err.Error())
+	}
+}
+
+// completeAction records a completed action in the action progress
+func (pm *PluginManager) completeAction(progress *actionProgress, index int, name string) {
+	if progress == nil {
+		return
+	}
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	progress.Completed = append(progress.Completed, completedAction{index, name, time.Now().UTC()})
+	pm.saveActionProgress(progress)
+}
@@ -0,0 +1,101 @@
+package cc
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+var checkpointTestRuns = map[string]int{}
+
+type prepareRunner struct {
+	ActionRunnerBase
+}
+
+func (r *prepareRunner) Run() error {
+	checkpointTestRuns["prepare"]++
+	return nil
+}
+
+type simulateRunner struct {
+	ActionRunnerBase
+}
+
+// Run saves its progress and fails on the first run to simulate an interruption
+func (r *simulateRunner) Run() error {
+	checkpointTestRuns["simulate"]++
+	reader, err := r.LoadCheckpoint("state")
+	if errors.Is(err, ErrCheckpointNotFound) {
+		if err = r.SaveCheckpoint("state", strings.NewReader("timestep=120")); err != nil {
+			return err
+		}
+		return errors.New("interrupted")
+	}
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	state, err := io.ReadAll(reader)
+	if err != nil || string(state) != "timestep=120" {
+		return errors.New("unexpected state " + string(state))
+	}
+	return nil
+}
+
+func TestCheckpoints(t *testing.T) {
+	t.Setenv(FsbRootPath, t.TempDir())
+	store, err := NewFSBCcStore("manifest", "payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ActionRegistry.RegisterAction("prepare", &prepareRunner{})
+	ActionRegistry.RegisterAction("simulate", &simulateRunner{})
+	t.Cleanup(func() {
+		delete(ActionRegistry, "prepare")
+		delete(ActionRegistry, "simulate")
+	})
+	newManager := func(event string, scenario string) *PluginManager {
+		pm := &PluginManager{Logger: NewCcLogger(CcLoggerInput{}), ccStore: store, EventIdentifier: event}
+		pm.Attributes = PayloadAttributes{"scenario": scenario}
+		pm.Actions = []Action{{Name: "prepare"}, {Name: "simulate"}}
+		return pm
+	}
+
+	if err = newManager("7", "2yr").RunActions(); err == nil {
+		t.Fatal("expected the first run to be interrupted")
+	}
+	//the restarted run resumes after the completed action and the action restores its state
+	if err = newManager("7", "2yr").RunActions(); err != nil {
+		t.Fatal(err)
+	}
+	if checkpointTestRuns["prepare"] != 1 || checkpointTestRuns["simulate"] != 2 {
+		t.Errorf("unexpected runs after a restart: %v", checkpointTestRuns)
+	}
+	//a finished run, another event or a changed payload starts from the first action
+	if err = newManager("7", "2yr").RunActions(); err != nil {
+		t.Fatal(err)
+	}
+	if err = newManager("8", "2yr").RunActions(); err == nil {
+		t.Error("expected the first run of another event to be interrupted")
+	}
+	if err = newManager("8", "5yr").RunActions(); err != nil {
+		t.Fatal(err)
+	}
+	if checkpointTestRuns["prepare"] != 4 {
+		t.Errorf("unexpected runs: %v", checkpointTestRuns)
+	}
+
+	pm := newManager("7", "2yr")
+	if _, err = pm.LoadCheckpoint("missing"); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("expected a missing checkpoint error, got %v", err)
+	}
+	for _, key := range []string{"", "../other-event/state", "/state"} {
+		if err = pm.SaveCheckpoint(key, strings.NewReader("x")); err == nil {
+			t.Errorf("expected an error for the checkpoint key %q", key)
+		}
+	}
+	if err = (&PluginManager{}).SaveCheckpoint("state", strings.NewReader("x")); err == nil {
+		t.Error("expected an error without a checkpoint store")
+	}
+}
@@ -29,16 +29,20 @@ func registerStoreTypes() {
 	//DataStoreTypeRegistry.Register(S3, S3DataStore{})
 	DataStoreTypeRegistry.Register(FSS3, FileDataStore[filestore.S3FS]{})
 	DataStoreTypeRegistry.Register(FSB, FileDataStore[filestore.BlockFS]{})
+	DataStoreTypeRegistry.Register(COG, CogDataStore{})
 
 }
 
 type DataStore struct {
-	Name       string            `json:"name,omitempty" yaml:"name"`
-	ID         *uuid.UUID        `json:"id,omitempty" yaml:id omitempty`
-	StoreType  StoreType         `json:"store_type,omitempty" yaml:"store_type"`
-	DsProfile  string            `json:"profile,omitempty" yaml:"profile"`
-	Parameters PayloadAttributes `json:"params,omitempty" yaml:"params"`
-	Session    any               `json:"-" yaml:"-"` //reference to the actual connection native to the data store
+	Name       string            `json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+	ID         *uuid.UUID        `json:"id,omitempty" yaml:"id,omitempty" toml:"id,omitempty"`
+	StoreType  StoreType         `json:"store_type,omitempty" yaml:"store_type,omitempty" toml:"store_type,omitempty"`
+	DsProfile  string            `json:"profile,omitempty" yaml:"profile,omitempty" toml:"profile,omitempty"`
+	Parameters PayloadAttributes `json:"params,omitempty" yaml:"params,omitempty" toml:"params,omitempty"`
+	Session    any               `json:"-" yaml:"-" toml:"-"` //reference to the actual connection native to the data store
+
+	//name of a payload store profile that provides defaults for the store
+	Extends string `json:"extends,omitempty" yaml:"extends,omitempty" toml:"extends,omitempty"`
 }
 
 type ConnectionDataStore interface {
@@ -50,6 +54,24 @@ type StoreReader interface {
 	Get(path string, datapath string) (io.ReadCloser, error)
 }
 
+// StoreRangeReader reads a byte range of a resource.  Stores implementing
+// StoreRangeReader can serve windowed reads of large files (e.g. cloud optimized geotiffs)
+type StoreRangeReader interface {
+	GetRange(path string, offset int64, length int64) ([]byte, error)
+	GetSize(path string) (int64, error)
+}
+
+// StoreLister lists the resources in a store beginning with a prefix.  Returned paths
+// are relative to the store root and can be read with the store StoreReader.
+type StoreLister interface {
+	List(prefix string) ([]string, error)
+}
+
+// StoreExistenceChecker reports whether a resource exists without reading it
+type StoreExistenceChecker interface {
+	Exists(path string) (bool, error)
+}
+
 type StoreWriter interface {
 	Put(srcReader io.Reader, destPath string, destDataPath string) (int, error)
 }
@@ -60,9 +82,9 @@ type StoreWriter interface {
 // For example "MODEL_LIBRARY" would match "MODEL_LIBRARY_AWS_ACCESS_KEY_ID"
 // or an empty string to ignore a prefix match.
 type DataSource struct {
-	Name      string            `json:"name,omitempty" yaml:"name"`
-	ID        *uuid.UUID        `json:"id,omitempty" yaml:id omitempty`
-	Paths     map[string]string `json:"paths,omitempty" yaml:"paths"`
-	DataPaths map[string]string `json:"data_paths,omitempty" yaml:"data_paths"`
-	StoreName string            `json:"store_name,omitempty" yaml:"store_name"`
+	Name      string            `json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+	ID        *uuid.UUID        `json:"id,omitempty" yaml:"id,omitempty" toml:"id,omitempty"`
+	Paths     map[string]string `json:"paths,omitempty" yaml:"paths,omitempty" toml:"paths,omitempty"`
+	DataPaths map[string]string `json:"data_paths,omitempty" yaml:"data_paths,omitempty" toml:"data_paths,omitempty"`
+	StoreName string            `json:"store_name,omitempty" yaml:"store_name,omitempty" toml:"store_name,omitempty"`
 }
@@ -0,0 +1,168 @@
+package cc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	COGBACKEND = "backend"
+)
+
+// RasterStore reads and writes windows of georeferenced rasters
+type RasterStore interface {
+	GetRasterInfo(path string) (RasterInfo, error)
+	GetRaster(input GetRasterInput) (*ArrayResult, error)
+	PutRaster(input PutRasterInput) (int, error)
+}
+
+// PutRasterInput describes a raster to be written as a cloud optimized geotiff
+type PutRasterInput struct {
+	Path string
+	GeoTiffInput
+}
+
+// CogDataStore reads and writes cloud optimized geotiffs in an S3 or FS backend store.
+// Windows are read using range requests against the backend so only the
+// tiles intersecting a window are transferred.
+// Data store parameters:
+//   - root: root path in the backend store
+//   - backend: backend store type.  S3 (default) or FS
+type CogDataStore struct {
+	backend any
+}
+
+func (cds *CogDataStore) Connect(ds DataStore) (any, error) {
+	backendType := StoreType(ds.Parameters.GetStringOrDefault(COGBACKEND, string(FSS3)))
+	if backendType != FSS3 && backendType != FSB {
+		return nil, fmt.Errorf("unsupported cog backend store type: %s", backendType)
+	}
+	backendStore, err := DataStoreTypeRegistry.New(backendType)
+	if err != nil {
+		return nil, err
+	}
+	conn, ok := backendStore.(ConnectionDataStore)
+	if !ok {
+		return nil, fmt.Errorf("cog backend %s is not a connection data store", backendType)
+	}
+	backendDs := ds
+	backendDs.StoreType = backendType
+	session, err := conn.Connect(backendDs)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := session.(StoreRangeReader); !ok {
+		return nil, fmt.Errorf("cog backend %s does not support range reads", backendType)
+	}
+	return &CogDataStore{session}, nil
+}
+
+func (cds *CogDataStore) GetSession() any {
+	if cs, ok := cds.backend.(ConnectionDataStore); ok {
+		return cs.GetSession()
+	}
+	return nil
+}
+
+func (cds *CogDataStore) Get(path string, datapath string) (io.ReadCloser, error) {
+	if reader, ok := cds.backend.(StoreReader); ok {
+		return reader.Get(path, datapath)
+	}
+	return nil, errors.New("cog backend store does not implement a StoreReader")
+}
+
+func (cds *CogDataStore) Put(reader io.Reader, destPath string, destDataPath string) (int, error) {
+	if writer, ok := cds.backend.(StoreWriter); ok {
+		return writer.Put(reader, destPath, destDataPath)
+	}
+	return 0, errors.New("cog backend store does not implement a StoreWriter")
+}
+
+func (cds *CogDataStore) GetRange(path string, offset int64, length int64) ([]byte, error) {
+	return cds.backend.(StoreRangeReader).GetRange(path, offset, length)
+}
+
+func (cds *CogDataStore) GetSize(path string) (int64, error) {
+	return cds.backend.(StoreRangeReader).GetSize(path)
+}
+
+func (cds *CogDataStore) List(prefix string) ([]string, error) {
+	if lister, ok := cds.backend.(StoreLister); ok {
+		return lister.List(prefix)
+	}
+	return nil, errors.New("cog backend store does not implement a StoreLister")
+}
+
+func (cds *CogDataStore) Exists(path string) (bool, error) {
+	if checker, ok := cds.backend.(StoreExistenceChecker); ok {
+		return checker.Exists(path)
+	}
+	return false, errors.New("cog backend store does not implement a StoreExistenceChecker")
+}
+
+func (cds *CogDataStore) GetRasterInfo(path string) (RasterInfo, error) {
+	reader, err := NewRangeReaderAt(cds, path)
+	if err != nil {
+		return RasterInfo{}, err
+	}
+	gt, err := OpenGeoTiff(reader)
+	if err != nil {
+		return RasterInfo{}, err
+	}
+	return gt.Info(), nil
+}
+
+func (cds *CogDataStore) GetRaster(input GetRasterInput) (*ArrayResult, error) {
+	reader, err := NewRangeReaderAt(cds, input.Path)
+	if err != nil {
+		return nil, err
+	}
+	gt, err := OpenGeoTiff(reader)
+	if err != nil {
+		return nil, err
+	}
+	return gt.ReadWindow(input)
+}
+
+func (cds *CogDataStore) PutRaster(input PutRasterInput) (int, error) {
+	buf := bytes.Buffer{}
+	err := WriteCog(&buf, input.GeoTiffInput)
+	if err != nil {
+		return 0, err
+	}
+	size := buf.Len()
+	_, err = cds.Put(&buf, input.Path, "")
+	return size, err
+}
+
+// rangeReaderAt adapts a StoreRangeReader resource to an io.ReaderAt
+type rangeReaderAt struct {
+	store StoreRangeReader
+	path  string
+	size  int64
+}
+
+// NewRangeReaderAt returns an io.ReaderAt for a store resource.  The reader has a Size method
+// so that readers such as OpenGeoTiff can check offsets against the size of the resource.
+func NewRangeReaderAt(store StoreRangeReader, path string) (io.ReaderAt, error) {
+	size, err := store.GetSize(path)
+	if err != nil {
+		return nil, err
+	}
+	return &rangeReaderAt{store, path, size}, nil
+}
+
+func (r *rangeReaderAt) Size() int64 {
+	return r.size
+}
+
+func (r *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	data, err := r.store.GetRange(r.path, off, int64(len(p)))
+	n := copy(p, data)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
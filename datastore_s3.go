@@ -4,6 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	filestore "github.com/usace/filesapi"
 )
@@ -17,8 +21,9 @@ type FileDataStoreTypes interface {
 }
 
 type FileDataStore[T FileDataStoreTypes] struct {
-	fs   filestore.FileStore
-	root string
+	fs       filestore.FileStore
+	root     string
+	metadata *sidecarMetadataStore
 }
 
 func (fds *FileDataStore[T]) Get(path string, datapath string) (io.ReadCloser, error) {
@@ -34,6 +39,9 @@ func (fds *FileDataStore[T]) GetFilestore() filestore.FileStore {
 }
 
 func (fds *FileDataStore[T]) Put(reader io.Reader, path string, destDataPath string) (int, error) {
+	if _, ok := fds.fs.(*filestore.BlockFS); ok {
+		return -1, replaceFile(fds.root+"/"+path, reader)
+	}
 	poi := filestore.PutObjectInput{
 		Source: filestore.ObjectSource{
 			Reader: reader,
@@ -50,6 +58,47 @@ func (fds *FileDataStore[T]) Delete(path string) error {
 	return fds.Delete(fds.root + "/" + path) //@TODO...for real?  Does this even work?
 }
 
+func (fds *FileDataStore[T]) GetMetadata(key string, dest any) error {
+	return fds.metadata.GetMetadata(key, dest)
+}
+
+func (fds *FileDataStore[T]) PutMetadata(key string, val any) error {
+	return fds.metadata.PutMetadata(key, val)
+}
+
+func (fds *FileDataStore[T]) DeleteMetadata(key string) error {
+	return fds.metadata.DeleteMetadata(key)
+}
+
+func (fds *FileDataStore[T]) ListMetadata(prefix string) ([]MetadataEntry, error) {
+	return fds.metadata.ListMetadata(prefix)
+}
+
+// replaceFile writes a block file system file, creating the parent directories.  The data is
+// written to a temporary file in the same directory and renamed over the destination, so an
+// existing file is only replaced by a complete write and is never left partially overwritten.
+func replaceFile(path string, reader io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, reader)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 func (fds *FileDataStore[T]) GetSession() any {
 	switch v := any(fds.fs).(type) {
 	case *filestore.S3FS:
@@ -61,6 +110,117 @@ func (fds *FileDataStore[T]) GetSession() any {
 	}
 }
 
+// GetRange reads length bytes beginning at offset.  A short read at the end of the
+// object returns the bytes read and io.EOF.
+func (fds *FileDataStore[T]) GetRange(path string, offset int64, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range %d-%d for %s", offset, offset+length, path)
+	}
+	if _, ok := fds.fs.(*filestore.BlockFS); ok {
+		return fds.getFileRange(path, offset, length)
+	}
+	//s3 ranges are inclusive so the last byte requested is offset+length-1
+	if length == 0 {
+		return []byte{}, nil
+	}
+	fsgoi := filestore.GetObjectInput{
+		Path:  filestore.PathConfig{Path: fds.root + "/" + path},
+		Range: fmt.Sprintf("bytes=%d-%d", offset, offset+length-1),
+	}
+	reader, err := fds.fs.GetObject(fsgoi)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	buf := make([]byte, length)
+	n, err := io.ReadFull(reader, buf)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return buf[:n], io.EOF
+	}
+	return buf, err
+}
+
+// GetSize returns the size of an object in bytes
+func (fds *FileDataStore[T]) GetSize(path string) (int64, error) {
+	info, err := fds.fs.GetObjectInfo(filestore.PathConfig{Path: fds.root + "/" + path})
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// getFileRange reads a range directly from the block file system.  The filesapi
+// block store leaves the file open on range requests and pads reads past the end
+// of the file with zeros, so the file is read here instead.
+func (fds *FileDataStore[T]) getFileRange(path string, offset int64, length int64) ([]byte, error) {
+	f, err := os.Open(fds.root + "/" + path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf := make([]byte, length)
+	n, err := f.ReadAt(buf, offset)
+	if n < len(buf) && (err == nil || err == io.EOF) {
+		return buf[:n], io.EOF
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return buf[:n], err
+}
+
+// List returns the paths of the objects beginning with the prefix.
+// Paths are relative to the store root.  The sidecar metadata file is not listed.
+func (fds *FileDataStore[T]) List(prefix string) ([]string, error) {
+	root := storeRelativePath(fds.root)
+	dir := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = prefix[:i]
+	}
+
+	paths := []string{}
+	err := fds.fs.Walk(filestore.WalkInput{
+		Path: filestore.PathConfig{Path: strings.TrimSuffix(fds.root+"/"+dir, "/")},
+	}, func(objectPath string, info os.FileInfo) error {
+		if info.IsDir() {
+			return nil
+		}
+		rel := storeRelativePath(objectPath)
+		if root != "" {
+			rel = strings.TrimPrefix(rel, root+"/")
+		}
+		if strings.HasPrefix(rel, prefix) && rel != fds.metadata.relativePath() {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return paths, nil
+	}
+	sort.Strings(paths)
+	return paths, err
+}
+
+// Exists returns true if an object exists at the path
+func (fds *FileDataStore[T]) Exists(path string) (bool, error) {
+	_, err := fds.fs.GetObjectInfo(filestore.PathConfig{Path: fds.root + "/" + path})
+	var notFound *filestore.FileNotFoundError
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// storeRelativePath normalizes a store path for comparison.  Leading and
+// trailing separators and "." elements are removed
+func storeRelativePath(p string) string {
+	p = strings.Trim(filepath.ToSlash(filepath.Clean(p)), "/")
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
 func (fds *FileDataStore[T]) Connect(ds DataStore) (any, error) {
 	switch ds.StoreType {
 	case FSS3:
@@ -71,7 +231,7 @@ func (fds *FileDataStore[T]) Connect(ds DataStore) (any, error) {
 		}
 		if root, ok := ds.Parameters[S3ROOT]; ok {
 			if rootstr, ok := root.(string); ok {
-				return &FileDataStore[T]{fs, rootstr}, nil //@TODO why am i returning my original type?
+				return &FileDataStore[T]{fs, rootstr, newSidecarMetadataStore(fs, rootstr, ds)}, nil //@TODO why am i returning my original type?
 			} else {
 				return nil, errors.New("invalid s3 root parameter.  parameter must be a string")
 			}
@@ -79,8 +239,13 @@ func (fds *FileDataStore[T]) Connect(ds DataStore) (any, error) {
 			return nil, errors.New("missing s3 root parameter.  cannot create the store")
 		}
 	case FSB:
-		//no need to connect for a file store
-		return nil, nil
+		//no need to connect for a file store, but return a session so the store can be read and written
+		fs, err := filestore.NewFileStore(filestore.BlockFSConfig{})
+		if err != nil {
+			return nil, err
+		}
+		root := ds.Parameters.GetStringOrDefault(S3ROOT, ".")
+		return &FileDataStore[T]{fs, root, newSidecarMetadataStore(fs, root, ds)}, nil
 	}
 
 	//unsupported type
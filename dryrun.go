@@ -0,0 +1,226 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A dry run loads, checks and substitutes the payload and connects the stores,
+// but does not transfer any data:
+//
+//   - reads check that the resource exists and return an empty reader
+//   - writes consume the source into a no-op sink
+//   - RunActions reports what each action would read and write instead of running the actions
+//
+// Dry runs are enabled with CC_DRY_RUN or PluginManagerConfig.DryRun.
+
+// DryRunPath is a data source path that an action would read or write
+type DryRunPath struct {
+	DataSource string `json:"data_source"`
+	Store      string `json:"store"`
+	PathKey    string `json:"path_key"`
+	Path       string `json:"path"`
+
+	//number of store objects selected by a read path.  Zero for writes and unresolved paths
+	Matches int `json:"matches"`
+
+	//the path contains template variables ({VAR::}) that are only known when the data source is used
+	Unresolved bool `json:"unresolved,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+type DryRunAction struct {
+	Name string `json:"name"`
+
+	//an ActionRunner is registered for the action
+	Registered bool         `json:"registered"`
+	Reads      []DryRunPath `json:"reads"`
+	Writes     []DryRunPath `json:"writes"`
+}
+
+// DryRunReport lists the data read and written by the payload and each action
+type DryRunReport struct {
+	Reads   []DryRunPath   `json:"reads"`
+	Writes  []DryRunPath   `json:"writes"`
+	Actions []DryRunAction `json:"actions"`
+}
+
+// Err returns the joined errors of the report paths or nil if every read path exists
+func (r DryRunReport) Err() error {
+	errs := []error{}
+	collect := func(action string, paths []DryRunPath) {
+		for _, p := range paths {
+			if p.Error != "" {
+				errs = append(errs, fmt.Errorf("%sdata source %s path %s: %s", action, p.DataSource, p.PathKey, p.Error))
+			}
+		}
+	}
+	collect("", r.Reads)
+	collect("", r.Writes)
+	for _, a := range r.Actions {
+		collect("action "+a.Name+" ", a.Reads)
+		collect("action "+a.Name+" ", a.Writes)
+	}
+	return errors.Join(errs...)
+}
+
+func dryRunFromEnv() (bool, error) {
+	val := os.Getenv(CcDryRun)
+	if val == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value %q: %w", CcDryRun, val, err)
+	}
+	return enabled, nil
+}
+
+// DryRun returns true if the plugin manager is in dry run mode
+func (pm *PluginManager) DryRun() bool {
+	return pm.IOManager.isDryRun()
+}
+
+// PlanActions reports the data the payload and each action would read and write.
+// Read paths are checked for existence, with prefixes and patterns expanded through the store listing.
+func (pm *PluginManager) PlanActions() DryRunReport {
+	report := DryRunReport{
+		Reads:   pm.IOManager.planDataSources(pm.Inputs, true),
+		Writes:  pm.IOManager.planDataSources(pm.Outputs, false),
+		Actions: []DryRunAction{},
+	}
+	for i := range pm.Actions {
+		action := &pm.Actions[i]
+		registered := isActionRegistered(action.Name)
+		report.Actions = append(report.Actions, DryRunAction{
+			Name:       action.Name,
+			Registered: registered,
+			Reads:      action.IOManager.planDataSources(action.Inputs, true),
+			Writes:     action.IOManager.planDataSources(action.Outputs, false),
+		})
+	}
+	return report
+}
+
+// runDryRun logs the action plan and returns an error for any read path that does not exist
+func (pm *PluginManager) runDryRun() error {
+	report := pm.PlanActions()
+	logPaths := func(msg string, action string, paths []DryRunPath) {
+		for _, p := range paths {
+			pm.Logger.Info(msg, "action", action, "data_source", p.DataSource, "store", p.Store,
+				"path_key", p.PathKey, "path", p.Path, "matches", p.Matches, "unresolved", p.Unresolved, "error", p.Error)
+		}
+	}
+	logPaths("dry run read", "", report.Reads)
+	logPaths("dry run write", "", report.Writes)
+	for _, a := range report.Actions {
+		if !a.Registered {
+			pm.Logger.Warn("dry run action does not have a registered runner", "action", a.Name)
+		}
+		logPaths("dry run read", a.Name, a.Reads)
+		logPaths("dry run write", a.Name, a.Writes)
+	}
+	return report.Err()
+}
+
+// isDryRun returns true if the IOManager or a parent is in dry run mode
+func (im *IOManager) isDryRun() bool {
+	for m := im; m != nil; m = m.parent {
+		if m.dryRun {
+			return true
+		}
+	}
+	return false
+}
+
+func (im *IOManager) planDataSources(sources []DataSource, read bool) []DryRunPath {
+	paths := []DryRunPath{}
+	for _, ds := range sources {
+		for _, key := range sortedKeys(ds.Paths) {
+			p := DryRunPath{DataSource: ds.Name, Store: ds.StoreName, PathKey: key, Path: ds.Paths[key]}
+			p.Unresolved = containsTemplate(p.Path)
+			if !p.Unresolved {
+				p.Path = unescapeTemplateBraces(p.Path)
+			}
+			if _, err := im.GetStore(ds.StoreName); err != nil {
+				p.Error = err.Error()
+			} else if read && !p.Unresolved {
+				pattern, matches, err := im.expandPaths(ds, p.Path)
+				if err == nil && pattern.IsLiteral() {
+					err = im.checkExists(ds, p.Path)
+				}
+				if err != nil {
+					p.Error = err.Error()
+				} else if len(matches) == 0 {
+					p.Error = "path does not match any objects"
+				} else {
+					p.Matches = len(matches)
+				}
+			}
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// checkExists returns an error if a data source path does not exist in its store.
+// Stores that do not implement a StoreExistenceChecker are checked with a StoreLister.
+func (im *IOManager) checkExists(ds DataSource, path string) error {
+	store, err := im.GetStore(ds.StoreName)
+	if err != nil {
+		return err
+	}
+	var exists bool
+	switch s := store.Session.(type) {
+	case StoreExistenceChecker:
+		exists, err = s.Exists(path)
+	case StoreLister:
+		var listing []string
+		storePath := strings.TrimPrefix(path, "/")
+		listing, err = s.List(storePath)
+		for _, p := range listing {
+			exists = exists || p == storePath
+		}
+	default:
+		return fmt.Errorf("data store %s session can not check for existing resources", store.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("data source %s path %s does not exist in store %s", ds.Name, path, store.Name)
+	}
+	return nil
+}
+
+// dryRunReader checks that a data source path exists and returns an empty reader
+func (im *IOManager) dryRunReader(ds DataSource, path string) (io.ReadCloser, error) {
+	if err := im.checkExists(ds, path); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// storeWriter returns the StoreWriter of a store session, or a no-op sink in dry run mode.
+// Sessions that can not be written return false in both modes so a dry run reports
+// the same write errors as a real run.
+func (im *IOManager) storeWriter(store *DataStore) (StoreWriter, bool) {
+	writer, ok := store.Session.(StoreWriter)
+	if ok && im.isDryRun() {
+		return discardWriter{}, true
+	}
+	return writer, ok
+}
+
+// discardWriter is a StoreWriter that consumes and discards the source
+type discardWriter struct{}
+
+func (discardWriter) Put(srcReader io.Reader, destPath string, destDataPath string) (int, error) {
+	n, err := io.Copy(io.Discard, srcReader)
+	return int(n), err
+}
@@ -0,0 +1,97 @@
+package cc
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	im, root := testFileIOManager(t)
+	if err := os.MkdirAll(filepath.Join(root, "outputs/1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "outputs/1/depth.csv"), []byte("1.5"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pm := PluginManager{}
+	pm.IOManager = *im
+	pm.IOManager.dryRun = true
+	pm.Actions = []Action{{Name: "compute"}}
+	pm.Actions[0].IOManager.SetParent(&pm.IOManager)
+	pm.Actions[0].Inputs = []DataSource{{
+		Name:      "hydrographs",
+		StoreName: "local",
+		Paths:     map[string]string{"all": "outputs/*/depth.csv", "missing": "inputs/missing.csv"},
+	}}
+	action := pm.Actions[0]
+
+	//reads check existence and return empty readers
+	data, err := action.Get(DataSourceOpInput{DataSourceName: "depths", PathKey: "default", TemplateVars: map[string]string{"realization": "1"}})
+	if err != nil || len(data) != 0 {
+		t.Errorf("unexpected dry run read: %q %v", data, err)
+	}
+	_, err = action.Get(DataSourceOpInput{DataSourceName: "depths", PathKey: "default", TemplateVars: map[string]string{"realization": "2"}})
+	if err == nil {
+		t.Error("expected an error reading a missing path")
+	}
+	localPath := filepath.Join(t.TempDir(), "depth.csv")
+	if err = action.CopyToLocal(DataSourceOpInput{DataSourceName: "depths", PathKey: "default", TemplateVars: map[string]string{"realization": "1"}}, localPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(localPath); !os.IsNotExist(err) {
+		t.Errorf("expected the local file not to be written: %v", err)
+	}
+	err = action.ForEachReader(DataSourceOpInput{DataSourceName: "hydrographs", PathKey: "all"}, func(path string, reader io.Reader) error {
+		b, err := io.ReadAll(reader)
+		if len(b) != 0 {
+			t.Errorf("%s: expected an empty reader", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//writes go to a no-op sink
+	n, err := action.Put(PutOpInput{
+		SrcReader:         strings.NewReader("2.5"),
+		DataSourceOpInput: DataSourceOpInput{DataSourceName: "depths", PathKey: "default", TemplateVars: map[string]string{"realization": "3"}},
+	})
+	if err != nil || n != 3 {
+		t.Errorf("unexpected dry run write: %d %v", n, err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "outputs/3/depth.csv")); !os.IsNotExist(err) {
+		t.Errorf("expected the output not to be written: %v", err)
+	}
+
+	//sessions that can not be written are errors in a dry run
+	pm.Stores = append(pm.Stores, DataStore{Name: "readonly", StoreType: FSB, Session: struct{}{}})
+	pm.Actions[0].Outputs = []DataSource{{Name: "summary", StoreName: "readonly", Paths: map[string]string{"default": "summary.csv"}}}
+	_, err = pm.Actions[0].Put(PutOpInput{
+		SrcReader:         strings.NewReader("2.5"),
+		DataSourceOpInput: DataSourceOpInput{DataSourceName: "summary", PathKey: "default"},
+	})
+	if err == nil {
+		t.Error("expected an error writing to a store without a StoreWriter session")
+	}
+	pm.Stores = pm.Stores[:len(pm.Stores)-1]
+	pm.Actions[0].Outputs = nil
+
+	report := pm.PlanActions()
+	if len(report.Actions) != 1 || len(report.Actions[0].Reads) != 2 || report.Actions[0].Registered {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	all, missing := report.Actions[0].Reads[0], report.Actions[0].Reads[1]
+	if all.Matches != 1 || all.Error != "" || missing.Error == "" {
+		t.Errorf("unexpected action reads: %+v %+v", all, missing)
+	}
+	if !report.Reads[0].Unresolved || report.Writes[0].Path != "outputs/{VAR::realization}/depth.csv" {
+		t.Errorf("unexpected payload data sources: %+v %+v", report.Reads, report.Writes)
+	}
+	if err = report.Err(); err == nil || !strings.Contains(err.Error(), "action compute data source hydrographs path missing") {
+		t.Errorf("unexpected report error: %v", err)
+	}
+}
@@ -26,6 +26,8 @@ var Golang2AttrTypeMap map[reflect.Kind]ATTR_TYPE = map[reflect.Kind]ATTR_TYPE{
 	reflect.Float32: ATTR_FLOAT32,
 	reflect.Float64: ATTR_FLOAT64,
 	reflect.Uint8:   ATTR_UINT8,
+	reflect.Uint16:  ATTR_UINT16,
+	reflect.Uint32:  ATTR_UINT32,
 	reflect.Int8:    ATTR_INT8,
 	reflect.Int16:   ATTR_INT16,
 	reflect.Int32:   ATTR_INT32,
@@ -52,6 +54,8 @@ const (
 	ATTR_FLOAT32 ATTR_TYPE = 5
 	ATTR_FLOAT64 ATTR_TYPE = 6
 	ATTR_STRING  ATTR_TYPE = 7
+	ATTR_UINT16  ATTR_TYPE = 8
+	ATTR_UINT32  ATTR_TYPE = 9
 
 	ATTR_STRUCT_TAG string = "eventstore"
 )
@@ -63,9 +67,29 @@ type MultiDimensionalArrayStore interface {
 	CreateArray(input CreateArrayInput) error
 	PutArray(input PutArrayInput) error
 	GetArray(input GetArrayInput) (*ArrayResult, error)
-	PutMetadata(key string, val any) error
-	GetMetadata(key string, dest any) error
-	DeleteMetadata(key string) error
+	MetadataStore
+	DescribeArray(arrayPath string) (ArraySchema, error)
+	ListArrays(prefix string) ([]string, error)
+	ArrayExists(arrayPath string) (bool, error)
+	DeleteArray(arrayPath string) error
+	EvolveArray(input EvolveArrayInput) error
+}
+
+// ConsolidatingStore is implemented by array stores that write each put as a new fragment.
+// Consolidation merges the fragments of an array to improve read performance.
+// Vacuuming removes the fragments that were merged by consolidation, after which
+// time travel reads to the removed fragments are no longer possible.
+type ConsolidatingStore interface {
+	Consolidate(arrayPath string) error
+	Vacuum(arrayPath string) error
+	ListFragments(arrayPath string) ([]ArrayFragment, error)
+}
+
+// ArrayFragment describes a single write to an array
+type ArrayFragment struct {
+	URI       string
+	StartTime uint64 //unix epoch milliseconds
+	EndTime   uint64 //unix epoch milliseconds
 }
 
 type SimpleArrayStore interface {
@@ -73,10 +97,14 @@ type SimpleArrayStore interface {
 	GetSimpleArray(input GetSimpleArrayInput) (*ArrayResult, error)
 }
 
+// MetadataStore stores keyed metadata values.  Stores accept primitive values, slices of
+// primitives and JSON encodable structured values (structs and maps).  Keys may be
+// organized into hierarchical namespaces using NamespacedMetadataStore.
 type MetadataStore interface {
 	GetMetadata(key string, dest any) error
 	PutMetadata(key string, val any) error
 	DeleteMetadata(key string) error
+	ListMetadata(prefix string) ([]MetadataEntry, error)
 }
 
 type CreateSimpleArrayInput struct {
@@ -101,6 +129,10 @@ type PutSimpleArrayInput struct {
 	TileLayout LAYOUT_ORDER
 	PutLayout  LAYOUT_ORDER
 	TileExtent []int64
+
+	//optional spatial reference for two dimensional grids.
+	//stored alongside the array and returned with get results
+	GeoReference *GeoReference
 }
 
 type GetSimpleArrayInput struct {
@@ -108,6 +140,11 @@ type GetSimpleArrayInput struct {
 	XRange      []int64      //optional
 	YRange      []int64      //optional
 	SearchOrder LAYOUT_ORDER //optional
+
+	//optional map coordinate searches for georeferenced arrays.
+	//when provided these take precedence over the X and Y ranges
+	Coordinate  []float64    //optional: x,y map coordinate of a single cell
+	BoundingBox *BoundingBox //optional: all cells intersecting the box
 }
 
 type CreateArrayInput struct {
@@ -119,6 +156,14 @@ type CreateArrayInput struct {
 	TileLayout LAYOUT_ORDER
 }
 
+// EvolveArrayInput adds and/or drops attributes on an existing array.
+// Existing cells will return the attribute fill value for added attributes.
+type EvolveArrayInput struct {
+	ArrayPath      string
+	AddAttributes  []ArrayAttribute
+	DropAttributes []string
+}
+
 type ArrayAttribute struct {
 	Name     string
 	DataType ATTR_TYPE
@@ -151,6 +196,13 @@ type GetArrayInput struct {
 	DataPath    string
 	BufferRange []int64
 	SearchOrder LAYOUT_ORDER
+
+	//optional time travel: read the array as it existed at a unix epoch millisecond timestamp
+	Timestamp uint64
+
+	//optional time travel: read the array as it existed after the one based fragment was written.
+	//fragments are ordered by write time (see ConsolidatingStore ListFragments)
+	Fragment int
 }
 
 type ArraySchema struct {
@@ -159,6 +211,9 @@ type ArraySchema struct {
 	Domain         []int64
 	DomainNames    []string
 	ArrayType      ARRAY_TYPE
+	Dimensions     []ArrayDimension //dimension types, domains and tile extents
+	CellLayout     LAYOUT_ORDER
+	TileLayout     LAYOUT_ORDER
 }
 
 func (as ArraySchema) GetType(attrname string) (ATTR_TYPE, error) {
@@ -177,6 +232,9 @@ type ArrayResult struct {
 	Schema  ArraySchema
 	row     int
 	Attrs   []string
+
+	//georeference of the result window for georeferenced simple arrays
+	GeoReference *GeoReference
 }
 
 func (ar *ArrayResult) GetRow(rowindex int, attrindex int, dest any) {
@@ -0,0 +1,191 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// GeoReference describes the spatial reference of a two dimensional grid.
+// The GeoTransform follows the GDAL convention for mapping pixel corners
+// to map coordinates:
+//
+//	Xmap = GeoTransform[0] + col*GeoTransform[1] + row*GeoTransform[2]
+//	Ymap = GeoTransform[3] + col*GeoTransform[4] + row*GeoTransform[5]
+//
+// where row and col are zero based pixel offsets from the upper left corner
+// of the grid. Simple array indexes are one based, so the methods on
+// GeoReference accept and return one based row and column indexes.
+type GeoReference struct {
+	//coordinate reference system as well known text.  Optional if EPSG is provided
+	CRS string `json:"crs,omitempty"`
+
+	//EPSG code for the coordinate reference system.  Optional if CRS is provided
+	EPSG int `json:"epsg,omitempty"`
+
+	//affine transform from pixel space to map space
+	GeoTransform [6]float64 `json:"geotransform"`
+
+	//optional value representing cells without data
+	NoData *float64 `json:"nodata,omitempty"`
+}
+
+// BoundingBox is a rectangular extent in map coordinates
+type BoundingBox struct {
+	MinX float64 `json:"minx"`
+	MinY float64 `json:"miny"`
+	MaxX float64 `json:"maxx"`
+	MaxY float64 `json:"maxy"`
+}
+
+func (bb BoundingBox) Contains(x float64, y float64) bool {
+	return x >= bb.MinX && x <= bb.MaxX && y >= bb.MinY && y <= bb.MaxY
+}
+
+// Validate checks that a georeference has a usable transform and coordinate reference system
+func (g GeoReference) Validate() error {
+	if g.CRS == "" && g.EPSG == 0 {
+		return errors.New("georeference requires a CRS or an EPSG code")
+	}
+	if g.determinant() == 0 {
+		return errors.New("georeference geotransform is not invertible")
+	}
+	return nil
+}
+
+// IsGeographic returns true if the coordinate reference system uses angular (lat/lon) units
+func (g GeoReference) IsGeographic() bool {
+	if g.CRS != "" {
+		wkt := strings.ToUpper(strings.TrimSpace(g.CRS))
+		return strings.HasPrefix(wkt, "GEOGCS") || strings.HasPrefix(wkt, "GEOGCRS")
+	}
+	//EPSG geographic 2D systems are allocated in the 4000-4999 block (e.g. 4326, 4269)
+	return g.EPSG >= 4000 && g.EPSG < 5000
+}
+
+// CellToMap returns the map coordinate of the center of a one based row/col cell
+func (g GeoReference) CellToMap(row int64, col int64) (float64, float64) {
+	return g.pixelToMap(float64(row)-0.5, float64(col)-0.5)
+}
+
+// MapToCell returns the one based row and column of the cell containing the map coordinate.
+// The returned indexes are not bounded by the grid size.
+func (g GeoReference) MapToCell(x float64, y float64) (int64, int64, error) {
+	prow, pcol, err := g.mapToPixel(x, y)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(math.Floor(prow)) + 1, int64(math.Floor(pcol)) + 1, nil
+}
+
+// Bounds returns the map extent of a grid with the given number of rows and columns
+func (g GeoReference) Bounds(rows int64, cols int64) BoundingBox {
+	bb := BoundingBox{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, corner := range [][2]float64{{0, 0}, {0, float64(cols)}, {float64(rows), 0}, {float64(rows), float64(cols)}} {
+		x, y := g.pixelToMap(corner[0], corner[1])
+		bb.MinX = math.Min(bb.MinX, x)
+		bb.MinY = math.Min(bb.MinY, y)
+		bb.MaxX = math.Max(bb.MaxX, x)
+		bb.MaxY = math.Max(bb.MaxY, y)
+	}
+	return bb
+}
+
+// Window returns the one based row and column ranges of the cells in a rows x cols grid
+// that intersect the bounding box.  An error is returned if the box does not overlap the grid.
+func (g GeoReference) Window(bbox BoundingBox, rows int64, cols int64) ([]int64, []int64, error) {
+	minRow, minCol := math.Inf(1), math.Inf(1)
+	maxRow, maxCol := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{bbox.MinX, bbox.MinY}, {bbox.MinX, bbox.MaxY}, {bbox.MaxX, bbox.MinY}, {bbox.MaxX, bbox.MaxY}} {
+		prow, pcol, err := g.mapToPixel(corner[0], corner[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		minRow = math.Min(minRow, prow)
+		minCol = math.Min(minCol, pcol)
+		maxRow = math.Max(maxRow, prow)
+		maxCol = math.Max(maxCol, pcol)
+	}
+
+	//convert pixel edges to one based cell indexes and clamp to the grid
+	rowStart := max(int64(math.Floor(minRow))+1, 1)
+	colStart := max(int64(math.Floor(minCol))+1, 1)
+	rowEnd := min(int64(math.Ceil(maxRow)), rows)
+	colEnd := min(int64(math.Ceil(maxCol)), cols)
+	if rowStart > rowEnd || colStart > colEnd {
+		return nil, nil, fmt.Errorf("bounding box %v does not intersect the grid", bbox)
+	}
+	return []int64{rowStart, rowEnd}, []int64{colStart, colEnd}, nil
+}
+
+// Subset returns the georeference of a window whose upper left cell is the one based row/col
+func (g GeoReference) Subset(row int64, col int64) GeoReference {
+	sub := g
+	x, y := g.pixelToMap(float64(row-1), float64(col-1))
+	sub.GeoTransform[0] = x
+	sub.GeoTransform[3] = y
+	return sub
+}
+
+func (g GeoReference) pixelToMap(prow float64, pcol float64) (float64, float64) {
+	gt := g.GeoTransform
+	x := gt[0] + pcol*gt[1] + prow*gt[2]
+	y := gt[3] + pcol*gt[4] + prow*gt[5]
+	return x, y
+}
+
+func (g GeoReference) mapToPixel(x float64, y float64) (float64, float64, error) {
+	gt := g.GeoTransform
+	det := g.determinant()
+	if det == 0 {
+		return 0, 0, errors.New("georeference geotransform is not invertible")
+	}
+	dx := x - gt[0]
+	dy := y - gt[3]
+	pcol := (dx*gt[5] - dy*gt[2]) / det
+	prow := (dy*gt[1] - dx*gt[4]) / det
+	return prow, pcol, nil
+}
+
+func (g GeoReference) determinant() float64 {
+	gt := g.GeoTransform
+	return gt[1]*gt[5] - gt[2]*gt[4]
+}
+
+// ResolveWindow converts the map coordinate search options (Coordinate or BoundingBox)
+// into cell index X/Y ranges for a grid with the given dims (rows, cols).
+// Inputs without map coordinate options are returned unchanged.
+func (input GetSimpleArrayInput) ResolveWindow(georef *GeoReference, dims []int64) (GetSimpleArrayInput, error) {
+	if input.BoundingBox == nil && len(input.Coordinate) == 0 {
+		return input, nil
+	}
+	if georef == nil {
+		return input, fmt.Errorf("array %s is not georeferenced", input.DataPath)
+	}
+	if len(dims) != 2 {
+		return input, fmt.Errorf("map coordinate searches require a two dimensional array")
+	}
+	if len(input.Coordinate) > 0 {
+		if len(input.Coordinate) != 2 {
+			return input, errors.New("a map coordinate must have an x and y value")
+		}
+		row, col, err := georef.MapToCell(input.Coordinate[0], input.Coordinate[1])
+		if err != nil {
+			return input, err
+		}
+		if row < 1 || row > dims[0] || col < 1 || col > dims[1] {
+			return input, fmt.Errorf("coordinate %v is outside of the grid", input.Coordinate)
+		}
+		input.YRange = []int64{row, row}
+		input.XRange = []int64{col, col}
+		return input, nil
+	}
+	yrange, xrange, err := georef.Window(*input.BoundingBox, dims[0], dims[1])
+	if err != nil {
+		return input, err
+	}
+	input.YRange = yrange
+	input.XRange = xrange
+	return input, nil
+}
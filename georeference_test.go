@@ -0,0 +1,95 @@
+package cc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// 10 x 20 grid of 30m cells with an upper left corner at (1000,5000)
+var testGeoRef = GeoReference{
+	EPSG:         5070,
+	GeoTransform: [6]float64{1000, 30, 0, 5000, 0, -30},
+}
+
+func TestGeoReferenceMapToCell(t *testing.T) {
+	row, col, err := testGeoRef.MapToCell(1015, 4985)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row != 1 || col != 1 {
+		t.Errorf("expected cell 1,1 got %d,%d", row, col)
+	}
+
+	x, y := testGeoRef.CellToMap(3, 4)
+	row, col, _ = testGeoRef.MapToCell(x, y)
+	if row != 3 || col != 4 {
+		t.Errorf("expected cell 3,4 got %d,%d", row, col)
+	}
+}
+
+func TestGeoReferenceWindow(t *testing.T) {
+	bbox := BoundingBox{MinX: 1040, MinY: 4900, MaxX: 1100, MaxY: 4950}
+	yrange, xrange, err := testGeoRef.Window(bbox, 10, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if yrange[0] != 2 || yrange[1] != 4 || xrange[0] != 2 || xrange[1] != 4 {
+		t.Errorf("unexpected window: rows %v cols %v", yrange, xrange)
+	}
+
+	_, _, err = testGeoRef.Window(BoundingBox{MinX: 0, MinY: 0, MaxX: 10, MaxY: 10}, 10, 20)
+	if err == nil {
+		t.Error("expected an error for a bounding box outside of the grid")
+	}
+
+	sub := testGeoRef.Subset(yrange[0], xrange[0])
+	if sub.GeoTransform[0] != 1030 || sub.GeoTransform[3] != 4970 {
+		t.Errorf("unexpected subset origin: %v", sub.GeoTransform)
+	}
+}
+
+func TestResolveSimpleArrayWindow(t *testing.T) {
+	input := GetSimpleArrayInput{
+		DataPath:   "depth",
+		Coordinate: []float64{1045, 4955},
+	}
+	resolved, err := input.ResolveWindow(&testGeoRef, []int64{10, 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.YRange[0] != 2 || resolved.XRange[0] != 2 {
+		t.Errorf("unexpected cell: rows %v cols %v", resolved.YRange, resolved.XRange)
+	}
+
+	_, err = input.ResolveWindow(nil, []int64{10, 20})
+	if err == nil {
+		t.Error("expected an error resolving a coordinate without a georeference")
+	}
+}
+
+func TestArrayResultWriteGeoTiff(t *testing.T) {
+	nodata := -9999.0
+	georef := testGeoRef
+	georef.NoData = &nodata
+	result := ArrayResult{
+		Range:        []int64{1, 2, 1, 3},
+		Data:         []any{[]float32{1, 2, 3, 4, 5, 6}},
+		GeoReference: &georef,
+	}
+	buf := bytes.Buffer{}
+	err := result.WriteGeoTiff(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if string(data[:2]) != "II" || binary.LittleEndian.Uint16(data[2:4]) != 42 {
+		t.Fatal("invalid tiff header")
+	}
+	//the pixel data is written after the directory at the end of the file
+	pixels := make([]float32, 6)
+	binary.Read(bytes.NewReader(data[len(data)-24:]), binary.LittleEndian, pixels)
+	if pixels[5] != 6 {
+		t.Errorf("unexpected pixel data: %v", pixels)
+	}
+}
@@ -0,0 +1,662 @@
+package cc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+//geotiff.go implements a minimal pure go (Geo)TIFF encoder for exporting
+//grids from array stores and writing cloud optimized geotiffs.
+//Images are written as little endian classic TIFF.
+
+const (
+	tiffTagNewSubfileType   uint16 = 254
+	tiffTagImageWidth       uint16 = 256
+	tiffTagImageLength      uint16 = 257
+	tiffTagBitsPerSample    uint16 = 258
+	tiffTagCompression      uint16 = 259
+	tiffTagPhotometric      uint16 = 262
+	tiffTagStripOffsets     uint16 = 273
+	tiffTagSamplesPerPixel  uint16 = 277
+	tiffTagRowsPerStrip     uint16 = 278
+	tiffTagStripByteCounts  uint16 = 279
+	tiffTagPlanarConfig     uint16 = 284
+	tiffTagPredictor        uint16 = 317
+	tiffTagTileWidth        uint16 = 322
+	tiffTagTileLength       uint16 = 323
+	tiffTagTileOffsets      uint16 = 324
+	tiffTagTileByteCounts   uint16 = 325
+	tiffTagExtraSamples     uint16 = 338
+	tiffTagSampleFormat     uint16 = 339
+	tiffTagModelPixelScale  uint16 = 33550
+	tiffTagModelTiepoint    uint16 = 33922
+	tiffTagModelTransform   uint16 = 34264
+	tiffTagGeoKeyDirectory  uint16 = 34735
+	tiffTagGeoDoubleParams  uint16 = 34736
+	tiffTagGeoAsciiParams   uint16 = 34737
+	tiffTagGdalNoData       uint16 = 42113
+	tiffTypeByte            uint16 = 1
+	tiffTypeAscii           uint16 = 2
+	tiffTypeShort           uint16 = 3
+	tiffTypeLong            uint16 = 4
+	tiffTypeDouble          uint16 = 12
+	tiffSampleFormatUint    uint16 = 1
+	tiffSampleFormatInt     uint16 = 2
+	tiffSampleFormatFloat   uint16 = 3
+	tiffPlanarSeparate      uint16 = 2
+	tiffPhotometricMinBlack uint16 = 1
+	tiffCompressionNone     uint16 = 1
+	tiffCompressionDeflate  uint16 = 8
+	tiffCompressionAdobe    uint16 = 32946
+	tiffSubfileReducedImage uint32 = 1
+	tiffSubfileMask         uint32 = 4
+	tiffStripTargetSize     int    = 8192
+
+	geoKeyModelType        uint16 = 1024
+	geoKeyRasterType       uint16 = 1025
+	geoKeyCitation         uint16 = 1026
+	geoKeyGeographicType   uint16 = 2048
+	geoKeyProjectedType    uint16 = 3072
+	geoModelTypeProjected  uint16 = 1
+	geoModelTypeGeographic uint16 = 2
+	geoModelTypeUser       uint16 = 32767
+	geoRasterPixelIsArea   uint16 = 1
+)
+
+var tiffByteOrder = binary.LittleEndian
+
+type TIFF_COMPRESSION int
+type RESAMPLING int
+
+const (
+	COMPRESSION_NONE    TIFF_COMPRESSION = 0 //default compression
+	COMPRESSION_DEFLATE TIFF_COMPRESSION = 1
+
+	RESAMPLE_NEAREST RESAMPLING = 0 //default overview resampling
+	RESAMPLE_AVERAGE RESAMPLING = 1
+
+	defaultCogTileSize int = 256
+)
+
+// GeoTiffInput describes a grid to be written as a GeoTIFF
+type GeoTiffInput struct {
+	//one typed slice per band in row major order
+	Bands        []any
+	Rows         int
+	Cols         int
+	GeoReference GeoReference
+
+	//optional tile size in pixels. Must be a multiple of 16. Zero writes a striped tiff
+	TileSize int
+
+	//optional compression. Default is no compression
+	Compression TIFF_COMPRESSION
+
+	//optional overview decimation factors (e.g. 2,4,8). Overviews require a tiled tiff
+	Overviews          []int
+	OverviewResampling RESAMPLING
+}
+
+// WriteGeoTiff writes the input grid as a GeoTIFF to the writer
+func WriteGeoTiff(w io.Writer, input GeoTiffInput) error {
+	if err := validateGeoTiffInput(input); err != nil {
+		return err
+	}
+	if len(input.Overviews) > 0 && input.TileSize == 0 {
+		return errors.New("geotiff overviews require a tile size")
+	}
+	images := []tiffImage{}
+	img, err := buildTiffImage(input.Bands, input.Rows, input.Cols, input, false)
+	if err != nil {
+		return err
+	}
+	images = append(images, img)
+
+	for _, factor := range input.Overviews {
+		if factor < 2 {
+			return fmt.Errorf("invalid overview factor: %d", factor)
+		}
+		rows := (input.Rows + factor - 1) / factor
+		cols := (input.Cols + factor - 1) / factor
+		bands := make([]any, len(input.Bands))
+		for i, band := range input.Bands {
+			bands[i], err = downsample(band, input.Rows, input.Cols, factor, input.OverviewResampling, input.GeoReference.NoData)
+			if err != nil {
+				return err
+			}
+		}
+		img, err := buildTiffImage(bands, rows, cols, input, true)
+		if err != nil {
+			return err
+		}
+		images = append(images, img)
+	}
+	return encodeTiff(w, images)
+}
+
+// WriteCog writes the input grid as a Cloud Optimized GeoTIFF.  Unless specified in the input,
+// the image is written with 256 pixel deflate compressed tiles and overviews are generated
+// until the overview fits within a single tile.
+func WriteCog(w io.Writer, input GeoTiffInput) error {
+	if input.TileSize == 0 {
+		input.TileSize = defaultCogTileSize
+	}
+	if input.Compression == COMPRESSION_NONE {
+		input.Compression = COMPRESSION_DEFLATE
+	}
+	if input.Overviews == nil {
+		input.Overviews = defaultOverviews(input.Rows, input.Cols, input.TileSize)
+	}
+	return WriteGeoTiff(w, input)
+}
+
+func defaultOverviews(rows int, cols int, tileSize int) []int {
+	overviews := []int{}
+	for factor := 2; max(rows, cols)/(factor/2) > tileSize; factor *= 2 {
+		overviews = append(overviews, factor)
+	}
+	return overviews
+}
+
+// WriteGeoTiff exports a georeferenced two dimensional result window as a GeoTIFF.
+// Each attribute in the result is written as a band.
+func (ar *ArrayResult) WriteGeoTiff(w io.Writer) error {
+	if ar.GeoReference == nil {
+		return errors.New("array result is not georeferenced")
+	}
+	if len(ar.Range) != 4 {
+		return errors.New("geotiff export requires a two dimensional array result")
+	}
+	return WriteGeoTiff(w, GeoTiffInput{
+		Bands:        ar.Data,
+		Rows:         ar.Rows(),
+		Cols:         ar.Cols(),
+		GeoReference: *ar.GeoReference,
+	})
+}
+
+type tiffField struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+type tiffImage struct {
+	fields []tiffField
+
+	//strip or tile data in tiff order
+	blocks [][]byte
+	tiled  bool
+}
+
+func validateGeoTiffInput(input GeoTiffInput) error {
+	if len(input.Bands) == 0 {
+		return errors.New("geotiff requires at least one band")
+	}
+	if input.Rows < 1 || input.Cols < 1 {
+		return fmt.Errorf("invalid geotiff size: %d rows by %d cols", input.Rows, input.Cols)
+	}
+	if input.TileSize < 0 || input.TileSize%16 != 0 {
+		return fmt.Errorf("invalid tile size %d. tile size must be a multiple of 16", input.TileSize)
+	}
+	if err := input.GeoReference.Validate(); err != nil {
+		return err
+	}
+
+	bits, format, err := tiffSampleType(input.Bands[0])
+	if err != nil {
+		return err
+	}
+	for i, band := range input.Bands {
+		bbits, bformat, err := tiffSampleType(band)
+		if err != nil {
+			return err
+		}
+		if bbits != bits || bformat != format {
+			return fmt.Errorf("band %d data type does not match band 0", i)
+		}
+		if n := reflect.ValueOf(band).Len(); n != input.Rows*input.Cols {
+			return fmt.Errorf("band %d has %d values, expected %d", i, n, input.Rows*input.Cols)
+		}
+	}
+	return nil
+}
+
+// buildTiffImage encodes the bands as a planar tiff image.  Overview images are
+// flagged as reduced resolution images and do not carry the geokeys.
+func buildTiffImage(bands []any, rows int, cols int, input GeoTiffInput, overview bool) (tiffImage, error) {
+	img := tiffImage{tiled: input.TileSize > 0}
+	bits, format, _ := tiffSampleType(bands[0])
+	bytesPerSample := int(bits / 8)
+
+	for _, band := range bands {
+		var blocks [][]byte
+		var err error
+		if img.tiled {
+			blocks, err = tileBand(band, rows, cols, input.TileSize, input.GeoReference.NoData)
+		} else {
+			blocks, err = stripBand(band, rows, cols, bytesPerSample)
+		}
+		if err != nil {
+			return img, err
+		}
+		for _, block := range blocks {
+			if input.Compression == COMPRESSION_DEFLATE {
+				block, err = deflate(block)
+				if err != nil {
+					return img, err
+				}
+			}
+			img.blocks = append(img.blocks, block)
+		}
+	}
+
+	compression := tiffCompressionNone
+	if input.Compression == COMPRESSION_DEFLATE {
+		compression = tiffCompressionDeflate
+	}
+	spp := uint16(len(bands))
+	img.fields = []tiffField{
+		tiffLongField(tiffTagImageWidth, uint32(cols)),
+		tiffLongField(tiffTagImageLength, uint32(rows)),
+		tiffShortField(tiffTagBitsPerSample, repeat(bits, spp)...),
+		tiffShortField(tiffTagCompression, compression),
+		tiffShortField(tiffTagPhotometric, tiffPhotometricMinBlack),
+		tiffShortField(tiffTagSamplesPerPixel, spp),
+		tiffShortField(tiffTagPlanarConfig, tiffPlanarSeparate),
+		tiffShortField(tiffTagSampleFormat, repeat(format, spp)...),
+	}
+	if img.tiled {
+		img.fields = append(img.fields,
+			tiffLongField(tiffTagTileWidth, uint32(input.TileSize)),
+			tiffLongField(tiffTagTileLength, uint32(input.TileSize)),
+		)
+	} else {
+		img.fields = append(img.fields, tiffLongField(tiffTagRowsPerStrip, uint32(min(max(1, tiffStripTargetSize/(cols*bytesPerSample)), rows))))
+	}
+	if spp > 1 {
+		img.fields = append(img.fields, tiffShortField(tiffTagExtraSamples, repeat(uint16(0), spp-1)...))
+	}
+	if overview {
+		img.fields = append(img.fields, tiffLongField(tiffTagNewSubfileType, tiffSubfileReducedImage))
+		if input.GeoReference.NoData != nil {
+			img.fields = append(img.fields, tiffAsciiField(tiffTagGdalNoData, strconv.FormatFloat(*input.GeoReference.NoData, 'g', -1, 64)))
+		}
+	} else {
+		fields, err := geoTiffFields(input.GeoReference)
+		if err != nil {
+			return img, err
+		}
+		img.fields = append(img.fields, fields...)
+	}
+	return img, nil
+}
+
+func stripBand(band any, rows int, cols int, bytesPerSample int) ([][]byte, error) {
+	rowsPerStrip := min(max(1, tiffStripTargetSize/(cols*bytesPerSample)), rows)
+	bandVal := reflect.ValueOf(band)
+	strips := [][]byte{}
+	for row := 0; row < rows; row += rowsPerStrip {
+		end := min(row+rowsPerStrip, rows)
+		strip, err := encodeSamples(bandVal.Slice(row*cols, end*cols).Interface())
+		if err != nil {
+			return nil, err
+		}
+		strips = append(strips, strip)
+	}
+	return strips, nil
+}
+
+// tileBand splits a band into row major tiles.  Partial tiles on the right and bottom
+// edges are padded with the nodata value (or zero) to the full tile size.
+func tileBand(band any, rows int, cols int, tileSize int, nodata *float64) ([][]byte, error) {
+	bandVal := reflect.ValueOf(band)
+	elemType := bandVal.Type().Elem()
+	tilesAcross := (cols + tileSize - 1) / tileSize
+	tilesDown := (rows + tileSize - 1) / tileSize
+	tiles := [][]byte{}
+	for ty := 0; ty < tilesDown; ty++ {
+		for tx := 0; tx < tilesAcross; tx++ {
+			tile := reflect.MakeSlice(bandVal.Type(), tileSize*tileSize, tileSize*tileSize)
+			rowEnd := min((ty+1)*tileSize, rows)
+			colEnd := min((tx+1)*tileSize, cols)
+			if nodata != nil && (rowEnd-ty*tileSize < tileSize || colEnd-tx*tileSize < tileSize) {
+				fill := reflect.ValueOf(*nodata).Convert(elemType)
+				for i := 0; i < tile.Len(); i++ {
+					tile.Index(i).Set(fill)
+				}
+			}
+			for row := ty * tileSize; row < rowEnd; row++ {
+				tileRow := (row - ty*tileSize) * tileSize
+				reflect.Copy(
+					tile.Slice(tileRow, tileRow+colEnd-tx*tileSize),
+					bandVal.Slice(row*cols+tx*tileSize, row*cols+colEnd),
+				)
+			}
+			data, err := encodeSamples(tile.Interface())
+			if err != nil {
+				return nil, err
+			}
+			tiles = append(tiles, data)
+		}
+	}
+	return tiles, nil
+}
+
+func deflate(data []byte) ([]byte, error) {
+	buf := bytes.Buffer{}
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	err := zw.Close()
+	return buf.Bytes(), err
+}
+
+type numeric interface {
+	~uint8 | ~int8 | ~uint16 | ~int16 | ~uint32 | ~int32 | ~int64 | ~float32 | ~float64
+}
+
+func downsample(band any, rows int, cols int, factor int, resampling RESAMPLING, nodata *float64) (any, error) {
+	switch b := band.(type) {
+	case []uint8:
+		return downsampleBand(b, rows, cols, factor, resampling, nodata), nil
+	case []int8:
+		return downsampleBand(b, rows, cols, factor, resampling, nodata), nil
+	case []uint16:
+		return downsampleBand(b, rows, cols, factor, resampling, nodata), nil
+	case []int16:
+		return downsampleBand(b, rows, cols, factor, resampling, nodata), nil
+	case []uint32:
+		return downsampleBand(b, rows, cols, factor, resampling, nodata), nil
+	case []int32:
+		return downsampleBand(b, rows, cols, factor, resampling, nodata), nil
+	case []int64:
+		return downsampleBand(b, rows, cols, factor, resampling, nodata), nil
+	case []float32:
+		return downsampleBand(b, rows, cols, factor, resampling, nodata), nil
+	case []float64:
+		return downsampleBand(b, rows, cols, factor, resampling, nodata), nil
+	}
+	return nil, fmt.Errorf("unsupported band type: %T", band)
+}
+
+// downsampleBand reduces a band by the decimation factor.  Average resampling ignores
+// nodata cells and produces nodata only when every contributing cell is nodata.
+func downsampleBand[T numeric](src []T, rows int, cols int, factor int, resampling RESAMPLING, nodata *float64) []T {
+	orows := (rows + factor - 1) / factor
+	ocols := (cols + factor - 1) / factor
+	out := make([]T, orows*ocols)
+	for r := 0; r < orows; r++ {
+		for c := 0; c < ocols; c++ {
+			if resampling == RESAMPLE_NEAREST {
+				sr := min(r*factor+factor/2, rows-1)
+				sc := min(c*factor+factor/2, cols-1)
+				out[r*ocols+c] = src[sr*cols+sc]
+				continue
+			}
+			sum := 0.0
+			count := 0
+			for sr := r * factor; sr < min((r+1)*factor, rows); sr++ {
+				for sc := c * factor; sc < min((c+1)*factor, cols); sc++ {
+					v := float64(src[sr*cols+sc])
+					if nodata != nil && v == *nodata {
+						continue
+					}
+					sum += v
+					count++
+				}
+			}
+			if count == 0 && nodata != nil {
+				out[r*ocols+c] = T(*nodata)
+			} else if count > 0 {
+				out[r*ocols+c] = T(sum / float64(count))
+			}
+		}
+	}
+	return out
+}
+
+func geoTiffFields(g GeoReference) ([]tiffField, error) {
+	//geokey values are 16 bit
+	if g.EPSG < 0 || g.EPSG > math.MaxUint16 {
+		return nil, fmt.Errorf("epsg code %d can not be written to a geotiff", g.EPSG)
+	}
+	fields := []tiffField{}
+	gt := g.GeoTransform
+	if gt[2] == 0 && gt[4] == 0 {
+		fields = append(fields,
+			tiffDoubleField(tiffTagModelPixelScale, gt[1], -gt[5], 0),
+			tiffDoubleField(tiffTagModelTiepoint, 0, 0, 0, gt[0], gt[3], 0),
+		)
+	} else {
+		fields = append(fields, tiffDoubleField(tiffTagModelTransform,
+			gt[1], gt[2], 0, gt[0],
+			gt[4], gt[5], 0, gt[3],
+			0, 0, 0, 0,
+			0, 0, 0, 1,
+		))
+	}
+
+	//geokey directory: header followed by sorted key entries
+	//each entry is (key id, tiff tag location, count, value or offset)
+	keys := [][4]uint16{{geoKeyRasterType, 0, 1, geoRasterPixelIsArea}}
+	ascii := ""
+	switch {
+	case g.EPSG != 0 && g.IsGeographic():
+		keys = append(keys, [4]uint16{geoKeyModelType, 0, 1, geoModelTypeGeographic})
+		keys = append(keys, [4]uint16{geoKeyGeographicType, 0, 1, uint16(g.EPSG)})
+	case g.EPSG != 0:
+		keys = append(keys, [4]uint16{geoKeyModelType, 0, 1, geoModelTypeProjected})
+		keys = append(keys, [4]uint16{geoKeyProjectedType, 0, 1, uint16(g.EPSG)})
+	case g.IsGeographic():
+		keys = append(keys, [4]uint16{geoKeyModelType, 0, 1, geoModelTypeGeographic})
+	default:
+		keys = append(keys, [4]uint16{geoKeyModelType, 0, 1, geoModelTypeUser})
+	}
+	if g.CRS != "" {
+		ascii = g.CRS + "|"
+		if len(ascii) > math.MaxUint16 {
+			return nil, errors.New("crs is too long to be written to a geotiff")
+		}
+		keys = append(keys, [4]uint16{geoKeyCitation, tiffTagGeoAsciiParams, uint16(len(ascii)), 0})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i][0] < keys[j][0] })
+	directory := []uint16{1, 1, 0, uint16(len(keys))}
+	for _, k := range keys {
+		directory = append(directory, k[:]...)
+	}
+	fields = append(fields, tiffShortField(tiffTagGeoKeyDirectory, directory...))
+	if ascii != "" {
+		fields = append(fields, tiffAsciiField(tiffTagGeoAsciiParams, ascii))
+	}
+	if g.NoData != nil {
+		fields = append(fields, tiffAsciiField(tiffTagGdalNoData, strconv.FormatFloat(*g.NoData, 'g', -1, 64)))
+	}
+	return fields, nil
+}
+
+// encodeTiff writes a little endian classic tiff.  All image file directories are
+// written at the start of the file followed by the image data of the last image first
+// so that reduced resolution images precede the full resolution data.
+func encodeTiff(w io.Writer, images []tiffImage) error {
+	//add the offset and byte count fields so that the directory sizes are known
+	for i, img := range images {
+		offsetTag, countTag := tiffTagStripOffsets, tiffTagStripByteCounts
+		if img.tiled {
+			offsetTag, countTag = tiffTagTileOffsets, tiffTagTileByteCounts
+		}
+		counts := make([]uint32, len(img.blocks))
+		for j, b := range img.blocks {
+			counts[j] = uint32(len(b))
+		}
+		images[i].fields = append(images[i].fields,
+			tiffLongField(offsetTag, make([]uint32, len(img.blocks))...),
+			tiffLongField(countTag, counts...),
+		)
+		sort.Slice(images[i].fields, func(a, b int) bool {
+			return images[i].fields[a].tag < images[i].fields[b].tag
+		})
+	}
+
+	//layout the directories
+	position := uint64(8)
+	ifdOffsets := make([]uint64, len(images))
+	for i, img := range images {
+		ifdOffsets[i] = position
+		position += ifdSize(img.fields)
+	}
+
+	//layout the image data
+	blockOffsets := make([][]uint32, len(images))
+	for i := len(images) - 1; i >= 0; i-- {
+		blockOffsets[i] = make([]uint32, len(images[i].blocks))
+		for j, b := range images[i].blocks {
+			blockOffsets[i][j] = uint32(position)
+			position += uint64(len(b))
+		}
+	}
+	if position > math.MaxUint32 {
+		return errors.New("image is too large for a classic tiff")
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteString("II")
+	binary.Write(&buf, tiffByteOrder, uint16(42))
+	binary.Write(&buf, tiffByteOrder, uint32(ifdOffsets[0]))
+	for i, img := range images {
+		for j, f := range img.fields {
+			if f.tag == tiffTagStripOffsets || f.tag == tiffTagTileOffsets {
+				img.fields[j] = tiffLongField(f.tag, blockOffsets[i]...)
+			}
+		}
+		next := uint64(0)
+		if i < len(images)-1 {
+			next = ifdOffsets[i+1]
+		}
+		writeIfd(&buf, img.fields, ifdOffsets[i], next)
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	for i := len(images) - 1; i >= 0; i-- {
+		for _, b := range images[i].blocks {
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func ifdSize(fields []tiffField) uint64 {
+	size := uint64(2 + 12*len(fields) + 4)
+	for _, f := range fields {
+		if len(f.data) > 4 {
+			size += uint64(len(f.data) + len(f.data)%2)
+		}
+	}
+	return size
+}
+
+func writeIfd(buf *bytes.Buffer, fields []tiffField, ifdOffset uint64, nextIfd uint64) {
+	overflow := ifdOffset + uint64(2+12*len(fields)+4)
+	binary.Write(buf, tiffByteOrder, uint16(len(fields)))
+	for _, f := range fields {
+		binary.Write(buf, tiffByteOrder, f.tag)
+		binary.Write(buf, tiffByteOrder, f.typ)
+		binary.Write(buf, tiffByteOrder, f.count)
+		if len(f.data) <= 4 {
+			val := make([]byte, 4)
+			copy(val, f.data)
+			buf.Write(val)
+		} else {
+			binary.Write(buf, tiffByteOrder, uint32(overflow))
+			overflow += uint64(len(f.data) + len(f.data)%2)
+		}
+	}
+	binary.Write(buf, tiffByteOrder, uint32(nextIfd))
+	for _, f := range fields {
+		if len(f.data) > 4 {
+			buf.Write(f.data)
+			if len(f.data)%2 == 1 {
+				buf.WriteByte(0)
+			}
+		}
+	}
+}
+
+func tiffShortField(tag uint16, vals ...uint16) tiffField {
+	buf := bytes.Buffer{}
+	binary.Write(&buf, tiffByteOrder, vals)
+	return tiffField{tag, tiffTypeShort, uint32(len(vals)), buf.Bytes()}
+}
+
+func tiffLongField(tag uint16, vals ...uint32) tiffField {
+	buf := bytes.Buffer{}
+	binary.Write(&buf, tiffByteOrder, vals)
+	return tiffField{tag, tiffTypeLong, uint32(len(vals)), buf.Bytes()}
+}
+
+func tiffDoubleField(tag uint16, vals ...float64) tiffField {
+	buf := bytes.Buffer{}
+	binary.Write(&buf, tiffByteOrder, vals)
+	return tiffField{tag, tiffTypeDouble, uint32(len(vals)), buf.Bytes()}
+}
+
+func tiffAsciiField(tag uint16, val string) tiffField {
+	data := append([]byte(val), 0)
+	return tiffField{tag, tiffTypeAscii, uint32(len(data)), data}
+}
+
+// tiffSampleType returns the bits per sample and tiff sample format for a typed slice
+func tiffSampleType(buf any) (uint16, uint16, error) {
+	bt := reflect.TypeOf(buf)
+	if bt == nil || bt.Kind() != reflect.Slice {
+		return 0, 0, errors.New("band buffers must be slices")
+	}
+	switch bt.Elem().Kind() {
+	case reflect.Uint8:
+		return 8, tiffSampleFormatUint, nil
+	case reflect.Int8:
+		return 8, tiffSampleFormatInt, nil
+	case reflect.Uint16:
+		return 16, tiffSampleFormatUint, nil
+	case reflect.Int16:
+		return 16, tiffSampleFormatInt, nil
+	case reflect.Uint32:
+		return 32, tiffSampleFormatUint, nil
+	case reflect.Int32:
+		return 32, tiffSampleFormatInt, nil
+	case reflect.Int64:
+		return 64, tiffSampleFormatInt, nil
+	case reflect.Float32:
+		return 32, tiffSampleFormatFloat, nil
+	case reflect.Float64:
+		return 64, tiffSampleFormatFloat, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported band type: %s", bt.Elem().Kind())
+}
+
+func encodeSamples(buf any) ([]byte, error) {
+	out := bytes.Buffer{}
+	err := binary.Write(&out, tiffByteOrder, buf)
+	return out.Bytes(), err
+}
+
+func repeat[T any](val T, n uint16) []T {
+	vals := make([]T, n)
+	for i := range vals {
+		vals[i] = val
+	}
+	return vals
+}
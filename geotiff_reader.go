@@ -0,0 +1,810 @@
+package cc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//geotiff_reader.go implements a pure go (Geo)TIFF decoder that reads windows
+//from classic and big tiffs through an io.ReaderAt.  Only the directories and the
+//tiles or strips intersecting a window are read, so a ReaderAt backed by ranged
+//requests against an object store reads cloud optimized geotiffs efficiently.
+//Supported compression: none and deflate, with horizontal and floating point predictors.
+
+const (
+	tiffTypeSByte     uint16 = 6
+	tiffTypeUndefined uint16 = 7
+	tiffTypeSShort    uint16 = 8
+	tiffTypeSLong     uint16 = 9
+	tiffTypeFloat     uint16 = 11
+	tiffTypeIfd       uint16 = 13
+	tiffTypeLong8     uint16 = 16
+	tiffTypeSLong8    uint16 = 17
+	tiffTypeIfd8      uint16 = 18
+
+	tiffPredictorNone          uint16 = 1
+	tiffPredictorHorizontal    uint16 = 2
+	tiffPredictorFloatingPoint uint16 = 3
+	tiffPlanarContig           uint16 = 1
+	geoRasterPixelIsPoint      uint16 = 2
+	geoUserDefined             uint16 = 32767
+
+	//size of the initial read which should contain the directories of a cog
+	tiffHeaderReadSize int64 = 16384
+
+	//limits on sizes read from a tiff before they are allocated
+	maxTiffDirectories             = 1024
+	maxTiffDirectoryEntries        = 4096
+	maxTiffFieldSize        uint64 = 64 << 20
+	maxTiffBlockSize        uint64 = 256 << 20
+	maxTiffWindowSize       uint64 = 1 << 30
+	maxTiffDimension               = math.MaxUint32
+)
+
+var tiffTypeSizes = map[uint16]int{
+	tiffTypeByte:      1,
+	tiffTypeAscii:     1,
+	tiffTypeShort:     2,
+	tiffTypeLong:      4,
+	5:                 8, //rational
+	tiffTypeSByte:     1,
+	tiffTypeUndefined: 1,
+	tiffTypeSShort:    2,
+	tiffTypeSLong:     4,
+	10:                8, //signed rational
+	tiffTypeFloat:     4,
+	tiffTypeDouble:    8,
+	tiffTypeIfd:       4,
+	tiffTypeLong8:     8,
+	tiffTypeSLong8:    8,
+	tiffTypeIfd8:      8,
+}
+
+// RasterInfo describes the structure of a (Geo)TIFF
+type RasterInfo struct {
+	Rows         int
+	Cols         int
+	Bands        int
+	DataType     ATTR_TYPE
+	TileRows     int
+	TileCols     int
+	Tiled        bool
+	GeoReference *GeoReference
+
+	//overview sizes as [rows, cols] from largest to smallest
+	Overviews [][2]int
+}
+
+// GetRasterInput defines a window to read from a raster
+type GetRasterInput struct {
+	Path        string
+	Bands       []int        //optional: one based band indexes.  default is all bands
+	XRange      []int64      //optional: one based column range
+	YRange      []int64      //optional: one based row range
+	BoundingBox *BoundingBox //optional: map extent.  takes precedence over the x and y ranges
+	Overview    int          //optional: zero for full resolution, 1..n for the overview levels
+}
+
+// GeoTiff is an open (Geo)TIFF that reads windows on demand from the underlying reader
+type GeoTiff struct {
+	reader      io.ReaderAt
+	byteOrder   binary.ByteOrder
+	bigTiff     bool
+	images      []*tiffDirectory
+	overviews   []*tiffDirectory
+	GeoRef      *GeoReference
+	headerBlock []byte
+	size        int64 //size of the tiff in bytes, zero if unknown
+}
+
+type tiffDirectory struct {
+	width           int
+	height          int
+	blockWidth      int
+	blockHeight     int
+	tiled           bool
+	bitsPerSample   int
+	sampleFormat    uint16
+	samplesPerPixel int
+	planar          uint16
+	compression     uint16
+	predictor       uint16
+	subfileType     uint32
+	offsets         []uint64
+	byteCounts      []uint64
+	fields          map[uint16]tiffEntry
+}
+
+type tiffEntry struct {
+	typ   uint16
+	count uint64
+	data  []byte
+}
+
+// OpenGeoTiff reads the tiff header and image file directories.
+// If the reader has a Size method, offsets and byte counts in the tiff are
+// checked against the size before they are read.
+func OpenGeoTiff(reader io.ReaderAt) (*GeoTiff, error) {
+	gt := GeoTiff{reader: reader}
+	if sizer, ok := reader.(interface{ Size() int64 }); ok {
+		gt.size = sizer.Size()
+	}
+	header := make([]byte, tiffHeaderReadSize)
+	n, err := reader.ReadAt(header, 0)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	gt.headerBlock = header[:n]
+	if n < 8 {
+		return nil, errors.New("invalid tiff: file is too small")
+	}
+
+	switch string(header[:2]) {
+	case "II":
+		gt.byteOrder = binary.LittleEndian
+	case "MM":
+		gt.byteOrder = binary.BigEndian
+	default:
+		return nil, errors.New("invalid tiff byte order")
+	}
+
+	var ifdOffset uint64
+	switch gt.byteOrder.Uint16(header[2:4]) {
+	case 42:
+		ifdOffset = uint64(gt.byteOrder.Uint32(header[4:8]))
+	case 43:
+		if n < 16 {
+			return nil, errors.New("invalid bigtiff header")
+		}
+		gt.bigTiff = true
+		ifdOffset = gt.byteOrder.Uint64(header[8:16])
+	default:
+		return nil, errors.New("invalid tiff version")
+	}
+
+	visited := map[uint64]bool{}
+	for ifdOffset != 0 {
+		if visited[ifdOffset] || len(visited) >= maxTiffDirectories {
+			return nil, errors.New("invalid tiff: too many or circular image file directories")
+		}
+		visited[ifdOffset] = true
+		dir, next, err := gt.readDirectory(ifdOffset)
+		if err != nil {
+			return nil, err
+		}
+		if dir.subfileType&tiffSubfileMask == 0 {
+			if len(gt.images) > 0 && dir.subfileType&tiffSubfileReducedImage != 0 {
+				gt.overviews = append(gt.overviews, dir)
+			}
+			gt.images = append(gt.images, dir)
+		}
+		ifdOffset = next
+	}
+	if len(gt.images) == 0 {
+		return nil, errors.New("tiff does not contain an image")
+	}
+	gt.GeoRef, err = gt.readGeoReference(gt.images[0])
+	if err != nil {
+		return nil, err
+	}
+	return &gt, nil
+}
+
+// Info returns the structure of the full resolution image
+func (gt *GeoTiff) Info() RasterInfo {
+	img := gt.images[0]
+	dataType, _ := tiffAttrType(img.bitsPerSample, img.sampleFormat)
+	info := RasterInfo{
+		Rows:         img.height,
+		Cols:         img.width,
+		Bands:        img.samplesPerPixel,
+		DataType:     dataType,
+		TileRows:     img.blockHeight,
+		TileCols:     img.blockWidth,
+		Tiled:        img.tiled,
+		GeoReference: gt.GeoRef,
+	}
+	for _, ovr := range gt.overviews {
+		info.Overviews = append(info.Overviews, [2]int{ovr.height, ovr.width})
+	}
+	return info
+}
+
+// ReadWindow reads a window of the image or one of its overviews.  Each band is returned as a
+// typed slice in the result Data, the one based window is returned in the result Range and
+// the result GeoReference is adjusted to the window.
+func (gt *GeoTiff) ReadWindow(input GetRasterInput) (*ArrayResult, error) {
+	img := gt.images[0]
+	var georef *GeoReference
+	if gt.GeoRef != nil {
+		ref := *gt.GeoRef
+		georef = &ref
+	}
+	if input.Overview > 0 {
+		if input.Overview > len(gt.overviews) {
+			return nil, fmt.Errorf("invalid overview level %d. the raster has %d overviews", input.Overview, len(gt.overviews))
+		}
+		img = gt.overviews[input.Overview-1]
+		if georef != nil {
+			xscale := float64(gt.images[0].width) / float64(img.width)
+			yscale := float64(gt.images[0].height) / float64(img.height)
+			georef.GeoTransform[1] *= xscale
+			georef.GeoTransform[2] *= yscale
+			georef.GeoTransform[4] *= xscale
+			georef.GeoTransform[5] *= yscale
+		}
+	}
+
+	dataType, err := tiffAttrType(img.bitsPerSample, img.sampleFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	yrange := []int64{1, int64(img.height)}
+	xrange := []int64{1, int64(img.width)}
+	if input.BoundingBox != nil {
+		if georef == nil {
+			return nil, errors.New("raster is not georeferenced")
+		}
+		yrange, xrange, err = georef.Window(*input.BoundingBox, int64(img.height), int64(img.width))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if len(input.YRange) == 2 {
+			yrange = input.YRange
+		}
+		if len(input.XRange) == 2 {
+			xrange = input.XRange
+		}
+	}
+	if yrange[0] < 1 || yrange[1] > int64(img.height) || yrange[0] > yrange[1] ||
+		xrange[0] < 1 || xrange[1] > int64(img.width) || xrange[0] > xrange[1] {
+		return nil, fmt.Errorf("invalid raster window: rows %v cols %v", yrange, xrange)
+	}
+
+	bands := input.Bands
+	if len(bands) == 0 {
+		bands = make([]int, img.samplesPerPixel)
+		for i := range bands {
+			bands[i] = i + 1
+		}
+	}
+	for _, b := range bands {
+		if b < 1 || b > img.samplesPerPixel {
+			return nil, fmt.Errorf("invalid band %d. the raster has %d bands", b, img.samplesPerPixel)
+		}
+	}
+
+	rows := int(yrange[1] - yrange[0] + 1)
+	cols := int(xrange[1] - xrange[0] + 1)
+	bytesPerSample := img.bitsPerSample / 8
+	if pixels := uint64(rows) * uint64(cols); pixels > maxTiffWindowSize || pixels*uint64(bytesPerSample*len(bands)) > maxTiffWindowSize {
+		return nil, fmt.Errorf("raster window of %dx%d pixels and %d bands exceeds the limit of %d bytes", rows, cols, len(bands), maxTiffWindowSize)
+	}
+	bandData := make([][]byte, len(bands))
+	for i := range bandData {
+		bandData[i] = make([]byte, rows*cols*bytesPerSample)
+	}
+
+	//read each block intersecting the window and copy the requested samples
+	row0, col0 := int(yrange[0]-1), int(xrange[0]-1)
+	blocksAcross := (img.width + img.blockWidth - 1) / img.blockWidth
+	blocksDown := (img.height + img.blockHeight - 1) / img.blockHeight
+	planes := 1
+	if img.planar == tiffPlanarSeparate {
+		planes = img.samplesPerPixel
+	}
+	for by := row0 / img.blockHeight; by <= (row0+rows-1)/img.blockHeight; by++ {
+		for bx := col0 / img.blockWidth; bx <= (col0+cols-1)/img.blockWidth; bx++ {
+			for plane := 0; plane < planes; plane++ {
+				if planes > 1 && !containsInt(bands, plane+1) {
+					continue
+				}
+				blockIndex := plane*blocksAcross*blocksDown + by*blocksAcross + bx
+				block, err := gt.readBlock(img, blockIndex)
+				if err != nil {
+					return nil, err
+				}
+				gt.copyBlock(img, block, plane, bands, bx, by, row0, col0, rows, cols, bandData)
+			}
+		}
+	}
+
+	result := ArrayResult{
+		Range: []int64{yrange[0], yrange[1], xrange[0], xrange[1]},
+		Data:  make([]any, len(bands)),
+		Attrs: make([]string, len(bands)),
+		Schema: ArraySchema{
+			AttributeNames: make([]string, len(bands)),
+			AttributeTypes: make([]ATTR_TYPE, len(bands)),
+			Domain:         []int64{1, int64(img.height), 1, int64(img.width)},
+			DomainNames:    []string{"0", "1"},
+			ArrayType:      ARRAY_DENSE,
+		},
+	}
+	for i, b := range bands {
+		name := fmt.Sprintf("band_%d", b)
+		result.Attrs[i] = name
+		result.Schema.AttributeNames[i] = name
+		result.Schema.AttributeTypes[i] = dataType
+		result.Data[i], err = decodeSamples(bandData[i], gt.byteOrder, dataType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if georef != nil {
+		windowRef := georef.Subset(yrange[0], xrange[0])
+		result.GeoReference = &windowRef
+	}
+	return &result, nil
+}
+
+func (gt *GeoTiff) copyBlock(img *tiffDirectory, block []byte, plane int, bands []int, bx int, by int, row0 int, col0 int, rows int, cols int, bandData [][]byte) {
+	bytesPerSample := img.bitsPerSample / 8
+	samplesPerBlockPixel := 1
+	if img.planar != tiffPlanarSeparate {
+		samplesPerBlockPixel = img.samplesPerPixel
+	}
+	rowStart := max(by*img.blockHeight, row0)
+	rowEnd := min((by+1)*img.blockHeight, row0+rows, img.height)
+	colStart := max(bx*img.blockWidth, col0)
+	colEnd := min((bx+1)*img.blockWidth, col0+cols)
+	for i, b := range bands {
+		sample := b - 1
+		if img.planar == tiffPlanarSeparate {
+			if sample != plane {
+				continue
+			}
+			sample = 0
+		}
+		dest := bandData[i]
+		for r := rowStart; r < rowEnd; r++ {
+			for c := colStart; c < colEnd; c++ {
+				src := (((r-by*img.blockHeight)*img.blockWidth+(c-bx*img.blockWidth))*samplesPerBlockPixel + sample) * bytesPerSample
+				dst := ((r-row0)*cols + (c - col0)) * bytesPerSample
+				if src+bytesPerSample <= len(block) {
+					copy(dest[dst:dst+bytesPerSample], block[src:src+bytesPerSample])
+				}
+			}
+		}
+	}
+}
+
+func (gt *GeoTiff) readBlock(img *tiffDirectory, index int) ([]byte, error) {
+	if index >= len(img.offsets) || index >= len(img.byteCounts) {
+		return nil, fmt.Errorf("invalid tiff block index %d", index)
+	}
+	if err := gt.checkRange(img.offsets[index], img.byteCounts[index], maxTiffBlockSize); err != nil {
+		return nil, err
+	}
+	data := make([]byte, img.byteCounts[index])
+	if len(data) == 0 {
+		//sparse tiffs omit empty blocks
+		return data, nil
+	}
+	if err := gt.readAt(data, img.offsets[index]); err != nil {
+		return nil, err
+	}
+
+	switch img.compression {
+	case tiffCompressionNone:
+	case tiffCompressionDeflate, tiffCompressionAdobe:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		//limit the decompressed size to the size of a block
+		data, err = io.ReadAll(io.LimitReader(zr, int64(img.blockSize())+1))
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(data)) > img.blockSize() {
+			return nil, fmt.Errorf("invalid tiff: block %d decompresses past the block size", index)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported tiff compression: %d", img.compression)
+	}
+
+	samplesPerBlockPixel := 1
+	if img.planar != tiffPlanarSeparate {
+		samplesPerBlockPixel = img.samplesPerPixel
+	}
+	bytesPerSample := img.bitsPerSample / 8
+	rowSize := img.blockWidth * samplesPerBlockPixel * bytesPerSample
+	switch img.predictor {
+	case 0, tiffPredictorNone:
+	case tiffPredictorHorizontal:
+		undoHorizontalPredictor(data, rowSize, samplesPerBlockPixel, bytesPerSample, gt.byteOrder)
+	case tiffPredictorFloatingPoint:
+		undoFloatingPointPredictor(data, rowSize, samplesPerBlockPixel, bytesPerSample, gt.byteOrder)
+	default:
+		return nil, fmt.Errorf("unsupported tiff predictor: %d", img.predictor)
+	}
+	return data, nil
+}
+
+// checkRange returns an error if a range read from the tiff is larger than the limit
+// or extends past the end of the file.
+func (gt *GeoTiff) checkRange(offset uint64, length uint64, limit uint64) error {
+	if length > limit {
+		return fmt.Errorf("invalid tiff: %d bytes exceeds the limit of %d", length, limit)
+	}
+	if offset+length < offset || (gt.size > 0 && offset+length > uint64(gt.size)) {
+		return fmt.Errorf("invalid tiff: range %d-%d is past the end of the file", offset, offset+length)
+	}
+	return nil
+}
+
+func (gt *GeoTiff) readAt(data []byte, offset uint64) error {
+	end := offset + uint64(len(data))
+	if end <= uint64(len(gt.headerBlock)) {
+		copy(data, gt.headerBlock[offset:end])
+		return nil
+	}
+	n, err := gt.reader.ReadAt(data, int64(offset))
+	if n == len(data) {
+		return nil
+	}
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (gt *GeoTiff) readDirectory(offset uint64) (*tiffDirectory, uint64, error) {
+	countSize, entrySize, offsetSize := 2, 12, 4
+	if gt.bigTiff {
+		countSize, entrySize, offsetSize = 8, 20, 8
+	}
+	countBuf := make([]byte, countSize)
+	if err := gt.readAt(countBuf, offset); err != nil {
+		return nil, 0, err
+	}
+	var numEntries uint64
+	if gt.bigTiff {
+		numEntries = gt.byteOrder.Uint64(countBuf)
+	} else {
+		numEntries = uint64(gt.byteOrder.Uint16(countBuf))
+	}
+	if numEntries > maxTiffDirectoryEntries {
+		return nil, 0, fmt.Errorf("invalid tiff: directory has %d entries", numEntries)
+	}
+	entriesSize := numEntries*uint64(entrySize) + uint64(offsetSize)
+	if err := gt.checkRange(offset+uint64(countSize), entriesSize, entriesSize); err != nil {
+		return nil, 0, err
+	}
+
+	entries := make([]byte, entriesSize)
+	if err := gt.readAt(entries, offset+uint64(countSize)); err != nil {
+		return nil, 0, err
+	}
+
+	dir := tiffDirectory{fields: make(map[uint16]tiffEntry)}
+	for i := 0; i < int(numEntries); i++ {
+		entry := entries[i*entrySize : (i+1)*entrySize]
+		tag := gt.byteOrder.Uint16(entry[0:2])
+		typ := gt.byteOrder.Uint16(entry[2:4])
+		typeSize, ok := tiffTypeSizes[typ]
+		if !ok {
+			continue //skip unknown field types
+		}
+		var count uint64
+		var valueBytes []byte
+		if gt.bigTiff {
+			count = gt.byteOrder.Uint64(entry[4:12])
+			valueBytes = entry[12:20]
+		} else {
+			count = uint64(gt.byteOrder.Uint32(entry[4:8]))
+			valueBytes = entry[8:12]
+		}
+		if count > maxTiffFieldSize/uint64(typeSize) {
+			return nil, 0, fmt.Errorf("invalid tiff: field %d has %d values", tag, count)
+		}
+		size := count * uint64(typeSize)
+		data := make([]byte, size)
+		if size <= uint64(len(valueBytes)) {
+			copy(data, valueBytes)
+		} else {
+			var valueOffset uint64
+			if gt.bigTiff {
+				valueOffset = gt.byteOrder.Uint64(valueBytes)
+			} else {
+				valueOffset = uint64(gt.byteOrder.Uint32(valueBytes))
+			}
+			if err := gt.checkRange(valueOffset, size, maxTiffFieldSize); err != nil {
+				return nil, 0, err
+			}
+			if err := gt.readAt(data, valueOffset); err != nil {
+				return nil, 0, err
+			}
+		}
+		dir.fields[tag] = tiffEntry{typ, count, data}
+	}
+
+	var next uint64
+	nextBytes := entries[int(numEntries)*entrySize:]
+	if gt.bigTiff {
+		next = gt.byteOrder.Uint64(nextBytes)
+	} else {
+		next = uint64(gt.byteOrder.Uint32(nextBytes))
+	}
+
+	err := gt.parseDirectory(&dir)
+	return &dir, next, err
+}
+
+func (gt *GeoTiff) parseDirectory(dir *tiffDirectory) error {
+	for _, tag := range []uint16{tiffTagImageWidth, tiffTagImageLength, tiffTagTileWidth, tiffTagTileLength, tiffTagRowsPerStrip} {
+		if gt.uintField(dir, tag, 0) > maxTiffDimension {
+			return errors.New("invalid tiff image dimensions")
+		}
+	}
+	if gt.uintField(dir, tiffTagSamplesPerPixel, 1) > math.MaxUint16 || gt.uintField(dir, tiffTagBitsPerSample, 1) > 64 {
+		return errors.New("invalid tiff sample size")
+	}
+	dir.width = int(gt.uintField(dir, tiffTagImageWidth, 0))
+	dir.height = int(gt.uintField(dir, tiffTagImageLength, 0))
+	dir.samplesPerPixel = int(gt.uintField(dir, tiffTagSamplesPerPixel, 1))
+	dir.bitsPerSample = int(gt.uintField(dir, tiffTagBitsPerSample, 1))
+	dir.sampleFormat = uint16(gt.uintField(dir, tiffTagSampleFormat, uint64(tiffSampleFormatUint)))
+	dir.planar = uint16(gt.uintField(dir, tiffTagPlanarConfig, uint64(tiffPlanarContig)))
+	dir.compression = uint16(gt.uintField(dir, tiffTagCompression, uint64(tiffCompressionNone)))
+	dir.predictor = uint16(gt.uintField(dir, tiffTagPredictor, uint64(tiffPredictorNone)))
+	dir.subfileType = uint32(gt.uintField(dir, tiffTagNewSubfileType, 0))
+
+	if _, ok := dir.fields[tiffTagTileWidth]; ok {
+		dir.tiled = true
+		dir.blockWidth = int(gt.uintField(dir, tiffTagTileWidth, 0))
+		dir.blockHeight = int(gt.uintField(dir, tiffTagTileLength, 0))
+		dir.offsets = gt.uintFields(dir, tiffTagTileOffsets)
+		dir.byteCounts = gt.uintFields(dir, tiffTagTileByteCounts)
+	} else {
+		dir.blockWidth = dir.width
+		dir.blockHeight = int(gt.uintField(dir, tiffTagRowsPerStrip, uint64(dir.height)))
+		dir.offsets = gt.uintFields(dir, tiffTagStripOffsets)
+		dir.byteCounts = gt.uintFields(dir, tiffTagStripByteCounts)
+	}
+	if dir.blockHeight > dir.height && !dir.tiled {
+		dir.blockHeight = dir.height
+	}
+	if dir.width == 0 || dir.height == 0 || dir.blockWidth == 0 || dir.blockHeight == 0 {
+		return errors.New("invalid tiff image dimensions")
+	}
+	if dir.samplesPerPixel == 0 {
+		return errors.New("invalid tiff sample size")
+	}
+	if dir.bitsPerSample%8 != 0 {
+		return fmt.Errorf("unsupported tiff bits per sample: %d", dir.bitsPerSample)
+	}
+	if uint64(dir.blockWidth)*uint64(dir.blockHeight) > maxTiffBlockSize || dir.blockSize() > maxTiffBlockSize {
+		return fmt.Errorf("invalid tiff: %dx%d blocks exceed the block size limit", dir.blockWidth, dir.blockHeight)
+	}
+	blocks := uint64((dir.width+dir.blockWidth-1)/dir.blockWidth) * uint64((dir.height+dir.blockHeight-1)/dir.blockHeight)
+	if dir.planar == tiffPlanarSeparate {
+		blocks *= uint64(dir.samplesPerPixel)
+	}
+	if uint64(len(dir.offsets)) < blocks || uint64(len(dir.byteCounts)) < blocks {
+		return fmt.Errorf("invalid tiff: expected %d blocks", blocks)
+	}
+	return nil
+}
+
+// blockSize returns the decoded size of a tile or strip in bytes
+func (dir *tiffDirectory) blockSize() uint64 {
+	samples := uint64(1)
+	if dir.planar != tiffPlanarSeparate {
+		samples = uint64(dir.samplesPerPixel)
+	}
+	return uint64(dir.blockWidth) * uint64(dir.blockHeight) * samples * uint64(dir.bitsPerSample/8)
+}
+
+func (gt *GeoTiff) readGeoReference(dir *tiffDirectory) (*GeoReference, error) {
+	georef := GeoReference{}
+	scale := gt.floatFields(dir, tiffTagModelPixelScale)
+	tiepoint := gt.floatFields(dir, tiffTagModelTiepoint)
+	transform := gt.floatFields(dir, tiffTagModelTransform)
+	switch {
+	case len(transform) >= 16:
+		georef.GeoTransform = [6]float64{transform[3], transform[0], transform[1], transform[7], transform[4], transform[5]}
+	case len(scale) >= 2 && len(tiepoint) >= 6:
+		georef.GeoTransform = [6]float64{
+			tiepoint[3] - tiepoint[0]*scale[0], scale[0], 0,
+			tiepoint[4] + tiepoint[1]*scale[1], 0, -scale[1],
+		}
+	default:
+		return nil, nil
+	}
+
+	//geokey directory entries: key id, tiff tag location, count, value or offset
+	keys := gt.uintFields(dir, tiffTagGeoKeyDirectory)
+	ascii := ""
+	if entry, ok := dir.fields[tiffTagGeoAsciiParams]; ok {
+		ascii = string(entry.data)
+	}
+	for i := 4; i+3 < len(keys); i += 4 {
+		key, location, count, value := uint16(keys[i]), uint16(keys[i+1]), int(keys[i+2]), int(keys[i+3])
+		switch key {
+		case geoKeyProjectedType, geoKeyGeographicType:
+			if location == 0 && value != int(geoUserDefined) && georef.EPSG == 0 {
+				georef.EPSG = value
+			}
+		case geoKeyRasterType:
+			if location == 0 && value == int(geoRasterPixelIsPoint) {
+				//shift the pixel centered transform to the upper left pixel corner
+				gtf := georef.GeoTransform
+				georef.GeoTransform[0] = gtf[0] - 0.5*gtf[1] - 0.5*gtf[2]
+				georef.GeoTransform[3] = gtf[3] - 0.5*gtf[4] - 0.5*gtf[5]
+			}
+		case geoKeyCitation:
+			if location == tiffTagGeoAsciiParams && value+count <= len(ascii) {
+				citation := strings.TrimRight(ascii[value:value+count], "|\x00")
+				if strings.Contains(citation, "[") {
+					georef.CRS = citation
+				}
+			}
+		}
+	}
+	if entry, ok := dir.fields[tiffTagGdalNoData]; ok {
+		nodata, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimRight(string(entry.data), "\x00")), 64)
+		if err == nil {
+			georef.NoData = &nodata
+		}
+	}
+	return &georef, nil
+}
+
+func (gt *GeoTiff) uintField(dir *tiffDirectory, tag uint16, defaultVal uint64) uint64 {
+	vals := gt.uintFields(dir, tag)
+	if len(vals) == 0 {
+		return defaultVal
+	}
+	return vals[0]
+}
+
+func (gt *GeoTiff) uintFields(dir *tiffDirectory, tag uint16) []uint64 {
+	entry, ok := dir.fields[tag]
+	if !ok {
+		return nil
+	}
+	vals := make([]uint64, entry.count)
+	for i := range vals {
+		switch entry.typ {
+		case tiffTypeByte, tiffTypeUndefined:
+			vals[i] = uint64(entry.data[i])
+		case tiffTypeShort:
+			vals[i] = uint64(gt.byteOrder.Uint16(entry.data[2*i:]))
+		case tiffTypeLong, tiffTypeIfd:
+			vals[i] = uint64(gt.byteOrder.Uint32(entry.data[4*i:]))
+		case tiffTypeLong8, tiffTypeIfd8:
+			vals[i] = gt.byteOrder.Uint64(entry.data[8*i:])
+		default:
+			return nil
+		}
+	}
+	return vals
+}
+
+func (gt *GeoTiff) floatFields(dir *tiffDirectory, tag uint16) []float64 {
+	entry, ok := dir.fields[tag]
+	if !ok || entry.typ != tiffTypeDouble {
+		return nil
+	}
+	vals := make([]float64, entry.count)
+	for i := range vals {
+		vals[i] = math.Float64frombits(gt.byteOrder.Uint64(entry.data[8*i:]))
+	}
+	return vals
+}
+
+func undoHorizontalPredictor(data []byte, rowSize int, samplesPerPixel int, bytesPerSample int, order binary.ByteOrder) {
+	stride := samplesPerPixel * bytesPerSample
+	for row := 0; row+rowSize <= len(data); row += rowSize {
+		for i := row + stride; i < row+rowSize; i += bytesPerSample {
+			prev := i - stride
+			switch bytesPerSample {
+			case 1:
+				data[i] += data[prev]
+			case 2:
+				order.PutUint16(data[i:], order.Uint16(data[i:])+order.Uint16(data[prev:]))
+			case 4:
+				order.PutUint32(data[i:], order.Uint32(data[i:])+order.Uint32(data[prev:]))
+			case 8:
+				order.PutUint64(data[i:], order.Uint64(data[i:])+order.Uint64(data[prev:]))
+			}
+		}
+	}
+}
+
+// undoFloatingPointPredictor reverses the byte wise differencing and the byte plane
+// shuffle of the floating point predictor.  The shuffled bytes are most significant first.
+func undoFloatingPointPredictor(data []byte, rowSize int, samplesPerPixel int, bytesPerSample int, order binary.ByteOrder) {
+	values := rowSize / bytesPerSample
+	tmp := make([]byte, rowSize)
+	for row := 0; row+rowSize <= len(data); row += rowSize {
+		rowData := data[row : row+rowSize]
+		for i := samplesPerPixel; i < rowSize; i++ {
+			rowData[i] += rowData[i-samplesPerPixel]
+		}
+		copy(tmp, rowData)
+		for v := 0; v < values; v++ {
+			for b := 0; b < bytesPerSample; b++ {
+				msbFirst := tmp[b*values+v]
+				if order == binary.LittleEndian {
+					rowData[v*bytesPerSample+bytesPerSample-1-b] = msbFirst
+				} else {
+					rowData[v*bytesPerSample+b] = msbFirst
+				}
+			}
+		}
+	}
+}
+
+func decodeSamples(data []byte, order binary.ByteOrder, dataType ATTR_TYPE) (any, error) {
+	var out any
+	n := len(data)
+	switch dataType {
+	case ATTR_UINT8:
+		out = make([]uint8, n)
+	case ATTR_INT8:
+		out = make([]int8, n)
+	case ATTR_UINT16:
+		out = make([]uint16, n/2)
+	case ATTR_INT16:
+		out = make([]int16, n/2)
+	case ATTR_UINT32:
+		out = make([]uint32, n/4)
+	case ATTR_INT32:
+		out = make([]int32, n/4)
+	case ATTR_INT64:
+		out = make([]int64, n/8)
+	case ATTR_FLOAT32:
+		out = make([]float32, n/4)
+	case ATTR_FLOAT64:
+		out = make([]float64, n/8)
+	default:
+		return nil, fmt.Errorf("unsupported raster data type: %d", dataType)
+	}
+	err := binary.Read(bytes.NewReader(data), order, out)
+	return out, err
+}
+
+func tiffAttrType(bitsPerSample int, sampleFormat uint16) (ATTR_TYPE, error) {
+	switch {
+	case sampleFormat == tiffSampleFormatUint && bitsPerSample == 8:
+		return ATTR_UINT8, nil
+	case sampleFormat == tiffSampleFormatInt && bitsPerSample == 8:
+		return ATTR_INT8, nil
+	case sampleFormat == tiffSampleFormatUint && bitsPerSample == 16:
+		return ATTR_UINT16, nil
+	case sampleFormat == tiffSampleFormatInt && bitsPerSample == 16:
+		return ATTR_INT16, nil
+	case sampleFormat == tiffSampleFormatUint && bitsPerSample == 32:
+		return ATTR_UINT32, nil
+	case sampleFormat == tiffSampleFormatInt && bitsPerSample == 32:
+		return ATTR_INT32, nil
+	case sampleFormat == tiffSampleFormatInt && bitsPerSample == 64:
+		return ATTR_INT64, nil
+	case sampleFormat == tiffSampleFormatFloat && bitsPerSample == 32:
+		return ATTR_FLOAT32, nil
+	case sampleFormat == tiffSampleFormatFloat && bitsPerSample == 64:
+		return ATTR_FLOAT64, nil
+	}
+	return 0, fmt.Errorf("unsupported tiff sample type: %d bit format %d", bitsPerSample, sampleFormat)
+}
+
+func containsInt(vals []int, val int) bool {
+	for _, v := range vals {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package cc
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	filestore "github.com/usace/filesapi"
+)
+
+func testRaster(rows int, cols int) GeoTiffInput {
+	nodata := -9999.0
+	georef := testGeoRef
+	georef.NoData = &nodata
+	band1 := make([]float32, rows*cols)
+	band2 := make([]float32, rows*cols)
+	for i := range band1 {
+		band1[i] = float32(i)
+		band2[i] = float32(2 * i)
+	}
+	return GeoTiffInput{
+		Bands:        []any{band1, band2},
+		Rows:         rows,
+		Cols:         cols,
+		GeoReference: georef,
+	}
+}
+
+func TestCogRoundTrip(t *testing.T) {
+	rows, cols := 600, 530
+	buf := bytes.Buffer{}
+	err := WriteCog(&buf, testRaster(rows, cols))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gt, err := OpenGeoTiff(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := gt.Info()
+	if info.Rows != rows || info.Cols != cols || info.Bands != 2 || !info.Tiled {
+		t.Fatalf("unexpected raster info: %+v", info)
+	}
+	if len(info.Overviews) != 2 || info.Overviews[0] != [2]int{300, 265} {
+		t.Errorf("unexpected overviews: %v", info.Overviews)
+	}
+	if info.GeoReference.EPSG != 5070 || info.GeoReference.GeoTransform != testGeoRef.GeoTransform || *info.GeoReference.NoData != -9999 {
+		t.Errorf("unexpected georeference: %+v", info.GeoReference)
+	}
+
+	//window crossing tile boundaries
+	result, err := gt.ReadWindow(GetRasterInput{YRange: []int64{250, 260}, XRange: []int64{255, 270}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	band1 := result.Data[0].([]float32)
+	band2 := result.Data[1].([]float32)
+	if len(band1) != 11*16 || band1[0] != float32(249*cols+254) || band1[len(band1)-1] != float32(259*cols+269) {
+		t.Errorf("unexpected band 1 window data")
+	}
+	if band2[17] != float32(2*(250*cols+255)) {
+		t.Errorf("unexpected band 2 window data")
+	}
+	if result.GeoReference.GeoTransform[0] != 1000+254*30 {
+		t.Errorf("unexpected window georeference: %v", result.GeoReference.GeoTransform)
+	}
+
+	//bounding box read from the first overview
+	bbox := BoundingBox{MinX: 1000, MinY: 4880, MaxX: 1120, MaxY: 5000}
+	result, err = gt.ReadWindow(GetRasterInput{BoundingBox: &bbox, Bands: []int{1}, Overview: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//nearest neighbor overviews sample the center of each 2x2 block
+	if result.Rows() != 2 || result.Cols() != 2 || result.Data[0].([]float32)[0] != float32(cols+1) {
+		t.Errorf("unexpected overview window: %v %v", result.Range, result.Data[0])
+	}
+}
+
+func TestOpenGeoTiffLimits(t *testing.T) {
+	//directory entry count larger than the file
+	tiff := []byte{'I', 'I', 42, 0, 8, 0, 0, 0, 0xff, 0x0f}
+	if _, err := OpenGeoTiff(bytes.NewReader(tiff)); err == nil {
+		t.Error("expected an error for a directory past the end of the file")
+	}
+	//directory that points to itself
+	tiff = []byte{'I', 'I', 42, 0, 8, 0, 0, 0, 0, 0, 8, 0, 0, 0}
+	if _, err := OpenGeoTiff(bytes.NewReader(tiff)); err == nil {
+		t.Error("expected an error for a circular directory")
+	}
+	//strip byte count larger than the file
+	buf := bytes.Buffer{}
+	raster := GeoTiffInput{Bands: []any{[]uint8{1, 2, 3, 4}}, Rows: 2, Cols: 2, GeoReference: testGeoRef}
+	if err := WriteGeoTiff(&buf, raster); err != nil {
+		t.Fatal(err)
+	}
+	gt, err := OpenGeoTiff(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gt.images[0].byteCounts[0] = 1 << 40
+	if _, err = gt.ReadWindow(GetRasterInput{}); err == nil {
+		t.Error("expected an error for a block larger than the file")
+	}
+	gt.images[0].width, gt.images[0].height = 1<<20, 1<<20
+	if _, err = gt.ReadWindow(GetRasterInput{}); err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("expected an error for a window larger than the limit, got %v", err)
+	}
+
+	georef := testGeoRef
+	georef.EPSG = 102100
+	raster.GeoReference = georef
+	if err := WriteGeoTiff(&bytes.Buffer{}, raster); err == nil {
+		t.Error("expected an error writing an epsg code larger than 16 bits")
+	}
+}
+
+func TestCogDataStore(t *testing.T) {
+	ds := DataStore{
+		Name:       "terrain",
+		StoreType:  COG,
+		Parameters: PayloadAttributes{"root": t.TempDir(), "backend": "FS"},
+	}
+	registerStoreTypes()
+	conn, err := (&CogDataStore{}).Connect(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := conn.(RasterStore)
+	band := make([]uint16, 40*30)
+	for i := range band {
+		band[i] = uint16(i)
+	}
+	raster := GeoTiffInput{Bands: []any{band, band}, Rows: 40, Cols: 30, GeoReference: testGeoRef}
+	_, err = store.PutRaster(PutRasterInput{Path: "dem.tif", GeoTiffInput: raster})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := store.GetRaster(GetRasterInput{Path: "dem.tif", Bands: []int{2}, YRange: []int64{2, 2}, XRange: []int64{3, 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := result.Data[0].([]uint16)
+	if len(data) != 2 || data[0] != 32 || data[1] != 33 {
+		t.Errorf("unexpected raster data: %v", data)
+	}
+	//range readers report the resource size so tiff offsets are checked against it
+	reader, err := NewRangeReaderAt(conn.(StoreRangeReader), "dem.tif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(ds.Parameters.GetStringOrDefault("root", ""), "dem.tif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gt, err := OpenGeoTiff(reader); err != nil || gt.size != info.Size() {
+		t.Errorf("expected the geotiff size to be %d: %v", info.Size(), err)
+	}
+}
+
+func TestFileStoreGetRange(t *testing.T) {
+	root := t.TempDir()
+	conn, err := (&FileDataStore[filestore.BlockFS]{}).Connect(DataStore{StoreType: FSB, Parameters: PayloadAttributes{"root": root}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(root+"/data.bin", []byte("0123456789"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := conn.(StoreRangeReader)
+	data, err := store.GetRange("data.bin", 2, 3)
+	if err != nil || string(data) != "234" {
+		t.Errorf("unexpected range: %q %v", data, err)
+	}
+	data, err = store.GetRange("data.bin", 8, 4)
+	if err != io.EOF || string(data) != "89" {
+		t.Errorf("expected a short read and io.EOF: %q %v", data, err)
+	}
+	data, err = store.GetRange("data.bin", 12, 4)
+	if err != io.EOF || len(data) != 0 {
+		t.Errorf("expected io.EOF reading past the end: %q %v", data, err)
+	}
+}
@@ -7,11 +7,13 @@ toolchain go1.23.4
 //replace github.com/usace/filesapi => /Users/rdcrlrsg/Projects/programming/go/src/github.com/usace/filesapi
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/aws/aws-sdk-go-v2 v1.36.3
 	github.com/aws/aws-sdk-go-v2/config v1.27.11
 	github.com/google/uuid v1.6.0
 	github.com/spf13/cast v1.6.0
 	github.com/usace/filesapi v0.0.0-20250320132414-61c781325b9a
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -45,5 +47,4 @@ require (
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 	go4.org/unsafe/assume-no-moving-gc v0.0.0-20230525183740-e7c30c78aeb2 // indirect
 	golang.org/x/net v0.27.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
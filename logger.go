@@ -140,6 +140,7 @@ func sliceToAnySlice[T any](t []T) []any {
 }
 
 // ccLoggerOpts returns slog.HandlerOptions configured with custom log levels and attribute replacement.
+// Resolved secret values are redacted from messages and attributes (see RedactSecrets).
 func ccLoggerOpts(cclevel slog.Level) *slog.HandlerOptions {
 	return &slog.HandlerOptions{
 		Level: cclevel,
@@ -151,8 +152,9 @@ func ccLoggerOpts(cclevel slog.Level) *slog.HandlerOptions {
 					levelLabel = level.String()
 				}
 				a.Value = slog.StringValue(levelLabel)
+				return a
 			}
-			return a
+			return redactLogAttr(a)
 		},
 	}
 }
@@ -21,5 +21,5 @@ func TestLogger(t *testing.T) {
 	logger.Error("Test Error")
 	logger.Warn("Test Warn")
 	logger.Action("TEST Action")
-	logger.SendMessage("KANAWHA", "TestMessage", slog.Attr{"arg1", slog.StringValue("val1")})
+	logger.SendMessage("KANAWHA", "TestMessage", slog.Attr{Key: "arg1", Value: slog.StringValue("val1")})
 }
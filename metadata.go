@@ -0,0 +1,237 @@
+package cc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	filestore "github.com/usace/filesapi"
+)
+
+const (
+	MetadataNamespaceSeparator = "/"
+
+	//default sidecar file used for metadata on stores without native metadata
+	defaultMetadataSidecar = ".cc_metadata.json"
+	metadataPathParam      = "metadata_path"
+)
+
+// MetadataEntry describes a metadata key and the time the key was last written
+type MetadataEntry struct {
+	Key     string    `json:"key"`
+	Updated time.Time `json:"updated"`
+}
+
+// NamespacedMetadataStore scopes the keys of a metadata store to a hierarchical
+// namespace (for example plugin/action/event).  Keys are stored in the underlying
+// store as namespace/key.
+type NamespacedMetadataStore struct {
+	store  MetadataStore
+	prefix string
+}
+
+func NewNamespacedMetadataStore(store MetadataStore, namespace ...string) (*NamespacedMetadataStore, error) {
+	ns := &NamespacedMetadataStore{store: store}
+	return ns.Namespace(namespace...)
+}
+
+// Namespace returns a child namespace of the current namespace
+func (ns *NamespacedMetadataStore) Namespace(namespace ...string) (*NamespacedMetadataStore, error) {
+	prefix := ns.prefix
+	for _, name := range namespace {
+		if name == "" || strings.Contains(name, MetadataNamespaceSeparator) {
+			return nil, fmt.Errorf("invalid metadata namespace: %q", name)
+		}
+		prefix = prefix + name + MetadataNamespaceSeparator
+	}
+	return &NamespacedMetadataStore{ns.store, prefix}, nil
+}
+
+func (ns *NamespacedMetadataStore) GetMetadata(key string, dest any) error {
+	return ns.store.GetMetadata(ns.prefix+key, dest)
+}
+
+func (ns *NamespacedMetadataStore) PutMetadata(key string, val any) error {
+	return ns.store.PutMetadata(ns.prefix+key, val)
+}
+
+func (ns *NamespacedMetadataStore) DeleteMetadata(key string) error {
+	return ns.store.DeleteMetadata(ns.prefix + key)
+}
+
+// ListMetadata lists the keys in the namespace beginning with the prefix.
+// Returned keys are relative to the namespace.
+func (ns *NamespacedMetadataStore) ListMetadata(prefix string) ([]MetadataEntry, error) {
+	entries, err := ns.store.ListMetadata(ns.prefix + prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Key = strings.TrimPrefix(entries[i].Key, ns.prefix)
+	}
+	return entries, nil
+}
+
+// IsStructuredMetadata returns true for metadata values that are not primitives or slices of
+// primitives (e.g. structs and maps).  Stores without native support for these values
+// encode them as JSON.
+func IsStructuredMetadata(val any) bool {
+	t := reflect.TypeOf(val)
+	if t == nil {
+		return true
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return false
+	}
+	return true
+}
+
+// sortMetadataEntries orders entries by key
+func sortMetadataEntries(entries []MetadataEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+}
+
+// sidecarMetadataStore is a MetadataStore for file stores without native metadata.
+// All keys are kept in a single JSON file in the store.  Writes within a process are
+// serialized, but concurrent writers in separate processes are not supported.
+type sidecarMetadataStore struct {
+	fs    filestore.FileStore
+	path  string
+	name  string //path relative to the store root
+	mutex sync.Mutex
+}
+
+type sidecarMetadataValue struct {
+	Value   json.RawMessage `json:"value"`
+	Updated time.Time       `json:"updated"`
+}
+
+func newSidecarMetadataStore(fs filestore.FileStore, root string, ds DataStore) *sidecarMetadataStore {
+	path := ds.Parameters.GetStringOrDefault(metadataPathParam, defaultMetadataSidecar)
+	return &sidecarMetadataStore{fs: fs, path: root + "/" + path, name: storeRelativePath(path)}
+}
+
+func (sms *sidecarMetadataStore) relativePath() string {
+	if sms == nil {
+		return ""
+	}
+	return sms.name
+}
+
+func (sms *sidecarMetadataStore) GetMetadata(key string, dest any) error {
+	if reflect.TypeOf(dest) == nil || reflect.TypeOf(dest).Kind() != reflect.Ptr {
+		return errors.New("dest type must be a pointer")
+	}
+	sms.mutex.Lock()
+	defer sms.mutex.Unlock()
+	metadata, err := sms.read()
+	if err != nil {
+		return err
+	}
+	val, ok := metadata[key]
+	if !ok {
+		return fmt.Errorf("metadata key %s not found", key)
+	}
+	return json.Unmarshal(val.Value, dest)
+}
+
+func (sms *sidecarMetadataStore) PutMetadata(key string, val any) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	sms.mutex.Lock()
+	defer sms.mutex.Unlock()
+	metadata, err := sms.read()
+	if err != nil {
+		return err
+	}
+	metadata[key] = sidecarMetadataValue{data, time.Now().UTC()}
+	return sms.write(metadata)
+}
+
+func (sms *sidecarMetadataStore) DeleteMetadata(key string) error {
+	sms.mutex.Lock()
+	defer sms.mutex.Unlock()
+	metadata, err := sms.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := metadata[key]; !ok {
+		return nil
+	}
+	delete(metadata, key)
+	return sms.write(metadata)
+}
+
+func (sms *sidecarMetadataStore) ListMetadata(prefix string) ([]MetadataEntry, error) {
+	sms.mutex.Lock()
+	defer sms.mutex.Unlock()
+	metadata, err := sms.read()
+	if err != nil {
+		return nil, err
+	}
+	entries := []MetadataEntry{}
+	for key, val := range metadata {
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, MetadataEntry{key, val.Updated})
+		}
+	}
+	sortMetadataEntries(entries)
+	return entries, nil
+}
+
+func (sms *sidecarMetadataStore) read() (map[string]sidecarMetadataValue, error) {
+	metadata := make(map[string]sidecarMetadataValue)
+	reader, err := sms.fs.GetObject(filestore.GetObjectInput{
+		Path: filestore.PathConfig{Path: sms.path},
+	})
+	if err != nil {
+		var notFound *filestore.FileNotFoundError
+		if errors.As(err, &notFound) {
+			return metadata, nil
+		}
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return metadata, nil
+	}
+	err = json.Unmarshal(data, &metadata)
+	return metadata, err
+}
+
+func (sms *sidecarMetadataStore) write(metadata map[string]sidecarMetadataValue) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, ok := sms.fs.(*filestore.BlockFS); ok {
+		return replaceFile(sms.path, bytes.NewReader(data))
+	}
+	_, err = sms.fs.PutObject(filestore.PutObjectInput{
+		Source: filestore.ObjectSource{Data: data},
+		Dest:   filestore.PathConfig{Path: sms.path},
+	})
+	return err
+}
@@ -0,0 +1,107 @@
+package cc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	filestore "github.com/usace/filesapi"
+)
+
+type testRunInfo struct {
+	Runs   int               `json:"runs"`
+	Labels map[string]string `json:"labels"`
+}
+
+func TestSidecarMetadataStore(t *testing.T) {
+	conn, err := (&FileDataStore[filestore.BlockFS]{}).Connect(DataStore{
+		StoreType:  FSB,
+		Parameters: PayloadAttributes{"root": t.TempDir()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewNamespacedMetadataStore(conn.(MetadataStore), "hydraulics", "compute", "event-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.PutMetadata("run", testRunInfo{3, map[string]string{"basin": "kanawha"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.PutMetadata("peak", 1250.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := testRunInfo{}
+	err = store.GetMetadata("run", &run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Runs != 3 || run.Labels["basin"] != "kanawha" {
+		t.Errorf("unexpected structured metadata: %+v", run)
+	}
+
+	entries, err := store.ListMetadata("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Key != "peak" || entries[1].Key != "run" || entries[0].Updated.IsZero() {
+		t.Errorf("unexpected metadata entries: %v", entries)
+	}
+
+	//keys are stored under the namespace in the underlying store
+	all, err := conn.(MetadataStore).ListMetadata("hydraulics/compute/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].Key != "hydraulics/compute/event-1/peak" {
+		t.Errorf("unexpected namespaced keys: %v", all)
+	}
+
+	err = store.DeleteMetadata("run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _ = store.ListMetadata("")
+	if len(entries) != 1 {
+		t.Errorf("expected one metadata entry after delete, got %v", entries)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestFileStorePutReplacesFiles(t *testing.T) {
+	root := t.TempDir()
+	conn, err := (&FileDataStore[filestore.BlockFS]{}).Connect(DataStore{StoreType: FSB, Parameters: PayloadAttributes{"root": root}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := conn.(StoreWriter)
+	if _, err = store.Put(strings.NewReader("a longer first version"), "runs/result.txt", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Put(strings.NewReader("second"), "runs/result.txt", ""); err != nil {
+		t.Fatal(err)
+	}
+	//a failed write leaves the existing file in place
+	if _, err = store.Put(failingReader{}, "runs/result.txt", ""); err == nil {
+		t.Error("expected an error for a failed write")
+	}
+	data, err := os.ReadFile(filepath.Join(root, "runs/result.txt"))
+	if err != nil || string(data) != "second" {
+		t.Errorf("unexpected file contents: %q %v", data, err)
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "runs"))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected temporary files to be removed: %v %v", entries, err)
+	}
+}
@@ -0,0 +1,125 @@
+package cc
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Data source paths can select many objects in a store:
+//
+//   - a path ending in "/" is a prefix and selects every object under the prefix
+//   - a path with glob characters selects the matching objects.  * and ? match within a
+//     single path segment, ** matches across segments and [abc] matches a character class
+//
+// Any other path is a literal path to a single object.
+const globChars = "*?["
+
+// PathPattern is a parsed data source path
+type PathPattern struct {
+	Pattern string
+
+	//literal part of the pattern before the first glob character.  Used to list the store
+	Prefix string
+
+	matcher *regexp.Regexp
+}
+
+func NewPathPattern(pattern string) (*PathPattern, error) {
+	pp := PathPattern{Pattern: pattern, Prefix: pattern}
+	i := strings.IndexAny(pattern, globChars)
+	if i < 0 {
+		return &pp, nil
+	}
+	pp.Prefix = pattern[:i]
+	matcher, err := globRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	pp.matcher = matcher
+	return &pp, nil
+}
+
+// IsLiteral returns true for a pattern that selects a single object
+func (pp *PathPattern) IsLiteral() bool {
+	return pp.matcher == nil && !strings.HasSuffix(pp.Pattern, "/")
+}
+
+// Match returns true if the store path is selected by the pattern
+func (pp *PathPattern) Match(storePath string) bool {
+	switch {
+	case pp.matcher != nil:
+		return pp.matcher.MatchString(storePath)
+	case pp.IsLiteral():
+		return storePath == pp.Pattern
+	default:
+		return strings.HasPrefix(storePath, pp.Pattern)
+	}
+}
+
+// Base returns the directory containing the literal prefix.  Matched paths
+// are copied to local directories relative to the base.
+func (pp *PathPattern) Base() string {
+	if strings.HasSuffix(pp.Prefix, "/") {
+		return pp.Prefix
+	}
+	dir := path.Dir(pp.Prefix)
+	if dir == "." {
+		return ""
+	}
+	return dir + "/"
+}
+
+// Filter returns the sorted store paths selected by the pattern
+func (pp *PathPattern) Filter(storePaths []string) []string {
+	matches := []string{}
+	for _, p := range storePaths {
+		if pp.Match(p) {
+			matches = append(matches, p)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				//**/ also matches zero directories
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					expr.WriteString("(?:.*/)?")
+				} else {
+					expr.WriteString(".*")
+				}
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
@@ -0,0 +1,40 @@
+package cc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathPattern(t *testing.T) {
+	listing := []string{
+		"events/1/hydrographs/elk.csv",
+		"events/1/hydrographs/coal.csv",
+		"events/1/hydrographs/coal.dss",
+		"events/1/hydrographs/upper/gauley.csv",
+		"events/10/hydrographs/elk.csv",
+	}
+	tests := []struct {
+		pattern  string
+		base     string
+		expected []string
+	}{
+		{"events/1/hydrographs/*.csv", "events/1/hydrographs/", []string{"events/1/hydrographs/coal.csv", "events/1/hydrographs/elk.csv"}},
+		{"events/1/hydrographs/**/*.csv", "events/1/hydrographs/", []string{"events/1/hydrographs/coal.csv", "events/1/hydrographs/elk.csv", "events/1/hydrographs/upper/gauley.csv"}},
+		{"events/1/", "events/1/", []string{"events/1/hydrographs/coal.csv", "events/1/hydrographs/coal.dss", "events/1/hydrographs/elk.csv", "events/1/hydrographs/upper/gauley.csv"}},
+		{"events/?/hydrographs/[ce]*.csv", "events/", []string{"events/1/hydrographs/coal.csv", "events/1/hydrographs/elk.csv"}},
+		{"events/*/hydrographs/[!c]*", "events/", []string{"events/1/hydrographs/elk.csv", "events/10/hydrographs/elk.csv"}},
+		{"events/1/hydrographs/elk.csv", "events/1/hydrographs/", []string{"events/1/hydrographs/elk.csv"}},
+	}
+	for _, test := range tests {
+		pattern, err := NewPathPattern(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matches := pattern.Filter(listing); !reflect.DeepEqual(matches, test.expected) {
+			t.Errorf("%s: unexpected matches %v", test.pattern, matches)
+		}
+		if pattern.Base() != test.base {
+			t.Errorf("%s: unexpected base %s", test.pattern, pattern.Base())
+		}
+	}
+}
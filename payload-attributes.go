@@ -3,15 +3,19 @@ package cc
 import (
 	"errors"
 	"fmt"
-	"log"
+	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/invopop/jsonschema"
-	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cast"
 )
 
+// PayloadAttributes are the payload and action attributes.  Attribute names can be a
+// dotted path into nested maps and lists (e.g. "model.boundary.upstream" or "gages.0"),
+// with an exact match on the full name taking precedence.
 type PayloadAttributes map[string]any
 
 func (p PayloadAttributes) JSONSchemaExtend(schema *jsonschema.Schema) {
@@ -34,17 +38,7 @@ func (p PayloadAttributes) GetIntOrDefault(name string, defaultValue int) int {
 }
 
 func (p PayloadAttributes) GetIntSlice(name string) ([]int, error) {
-	vals, ok := p[name]
-	if !ok {
-		return nil, fmt.Errorf("Invalid value for %s\n", name)
-	}
-
-	floatVals := Slice2Type[float64](vals.([]any))
-	intvals := make([]int, len(floatVals))
-	for i, f := range floatVals {
-		intvals[i] = int(f)
-	}
-	return intvals, nil
+	return GetSlice[int](p, name)
 }
 
 func (p PayloadAttributes) GetInt64(name string) (int64, error) {
@@ -72,11 +66,62 @@ func (p PayloadAttributes) GetFloatOrDefault(name string, defaultValue float64)
 }
 
 func (p PayloadAttributes) GetFloatSlice(name string) ([]float64, error) {
-	vals, ok := p[name]
+	return GetSlice[float64](p, name)
+}
+
+// GetQuantity reads a unit annotated number such as "12.5 ft" or {"value": 12.5, "unit": "ft"}
+// (see ParseQuantity).  Numbers without a unit take the unit of a registered constraint for the attribute.
+func (p PayloadAttributes) GetQuantity(name string) (Quantity, error) {
+	val, ok := p.lookup(name)
 	if !ok {
-		return nil, fmt.Errorf("Invalid value for %s\n", name)
+		return Quantity{}, &AttributeError{name, ErrAttributeMissing}
+	}
+	q, err := ParseQuantity(val)
+	if err != nil {
+		return q, &AttributeError{name, err}
 	}
-	return Slice2Type[float64](vals.([]any)), nil
+	if constraint, ok := AttributeConstraints[name]; ok {
+		if msgs := constraint.Violations(val); len(msgs) > 0 {
+			return q, &AttributeError{name, errors.New(strings.Join(msgs, "; "))}
+		}
+		if q.Unit == "" {
+			q.Unit = constraint.Unit
+		}
+	}
+	return q, nil
+}
+
+// GetFloatAs reads a unit annotated number converted to unit.  Numbers without a unit, and without
+// a registered constraint unit, are assumed to be in the unit.  Incompatible units are errors.
+func (p PayloadAttributes) GetFloatAs(name string, unit string) (float64, error) {
+	q, err := p.GetQuantity(name)
+	if err != nil {
+		return 0, err
+	}
+	val, err := q.As(unit)
+	if err != nil {
+		return 0, &AttributeError{name, err}
+	}
+	return val, nil
+}
+
+func (p PayloadAttributes) GetFloatAsOrFail(name string, unit string) float64 {
+	val, err := p.GetFloatAs(name, unit)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+func (p PayloadAttributes) GetFloatAsOrDefault(name string, unit string, defaultValue float64) float64 {
+	val, err := p.GetFloatAs(name, unit)
+	if errors.Is(err, ErrAttributeMissing) {
+		return defaultValue
+	}
+	if err != nil {
+		panic(err)
+	}
+	return val
 }
 
 func (p PayloadAttributes) GetString(name string) (string, error) {
@@ -84,11 +129,7 @@ func (p PayloadAttributes) GetString(name string) (string, error) {
 }
 
 func (p PayloadAttributes) GetStringSlice(name string) ([]string, error) {
-	vals, ok := p[name]
-	if !ok {
-		return nil, fmt.Errorf("Invalid value for %s\n", name)
-	}
-	return Slice2Type[string](vals.([]any)), nil
+	return GetSlice[string](p, name)
 }
 
 func (p PayloadAttributes) GetStringOrFail(name string) string {
@@ -99,6 +140,8 @@ func (p PayloadAttributes) GetStringOrDefault(name string, defaultVal string) st
 	return GetOrDefault[string](p, name, defaultVal)
 }
 
+// GetBoolean accepts booleans, numbers and the strings accepted by strconv.ParseBool
+// (1, t, T, TRUE, true, True, 0, f, F, FALSE, false, False)
 func (p PayloadAttributes) GetBoolean(name string) (bool, error) {
 	return GetAttribute[bool](p, name)
 }
@@ -115,78 +158,451 @@ func (p PayloadAttributes) GetMap(name string) (map[string]any, error) {
 	return GetAttribute[map[string]any](p, name)
 }
 
-func (p PayloadAttributes) Decode(name string, dest any) error {
-	attrmap, err := GetAttribute[map[string]any](p, name)
+func (p PayloadAttributes) GetMapSlice(name string) ([]map[string]any, error) {
+	return GetSlice[map[string]any](p, name)
+}
+
+// GetDuration reads a duration string such as "90s" or "1h30m".  Numbers are seconds.
+func (p PayloadAttributes) GetDuration(name string) (time.Duration, error) {
+	return GetAttribute[time.Duration](p, name)
+}
+
+func (p PayloadAttributes) GetDurationOrFail(name string) time.Duration {
+	return GetOrFail[time.Duration](p, name)
+}
+
+func (p PayloadAttributes) GetDurationOrDefault(name string, defaultVal time.Duration) time.Duration {
+	return GetOrDefault[time.Duration](p, name, defaultVal)
+}
+
+// GetTime reads an RFC3339 time such as "2024-01-02T15:04:05Z"
+func (p PayloadAttributes) GetTime(name string) (time.Time, error) {
+	return GetAttribute[time.Time](p, name)
+}
+
+func (p PayloadAttributes) GetTimeOrFail(name string) time.Time {
+	return GetOrFail[time.Time](p, name)
+}
+
+func (p PayloadAttributes) GetTimeOrDefault(name string, defaultVal time.Time) time.Time {
+	return GetOrDefault[time.Time](p, name, defaultVal)
+}
+
+func (p PayloadAttributes) GetURL(name string) (*url.URL, error) {
+	u, err := GetAttribute[url.URL](p, name)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return mapstructure.Decode(attrmap, &dest)
+	return &u, nil
+}
+
+func (p PayloadAttributes) GetURLOrFail(name string) *url.URL {
+	u := GetOrFail[url.URL](p, name)
+	return &u
+}
+
+// Decode decodes an attribute into dest, which must be a pointer.  An empty name decodes all
+// of the attributes.  Struct fields are matched to attribute keys using the field "cc" tag,
+// then the "json" tag, then the field name (case insensitive).  Struct fields can be any type
+// supported by GetSlice, including nested structs.
+//
+// Fields are checked with the rules in a "validate" tag:
+//
+//   - required: the attribute must be present
+//   - min=n, max=n: bounds on numbers, or on the length of strings, lists and maps
+//   - oneof=a b c: the value must be one of the space separated options
+func (p PayloadAttributes) Decode(name string, dest any) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() {
+		return errors.New("decode destination must be a non-nil pointer")
+	}
+	var val any = map[string]any(p)
+	if name != "" {
+		var ok bool
+		val, ok = p.lookup(name)
+		if !ok {
+			return &AttributeError{name, ErrAttributeMissing}
+		}
+	}
+	err := convertAttribute(val, dv.Elem())
+	if err != nil && name != "" {
+		return attributeError(name, err)
+	}
+	return err
+}
+
+func (p PayloadAttributes) lookup(name string) (any, bool) {
+	return lookupAttribute(p, name)
 }
 
 type PayloadAttributeTypes interface {
-	int64 | int32 | int | float64 | string | bool | map[string]any
+	int64 | int32 | int | float64 | string | bool | map[string]any | time.Duration | time.Time | url.URL
 }
 
+// GetOrFail returns an attribute or panics with an *AttributeError.  The panic is recovered
+// and returned as an error by RunActions for action runners and by InitPluginManager for
+// data store connections.  Other callers must recover the panic or use GetAttribute.
 func GetOrFail[T PayloadAttributeTypes](pa PayloadAttributes, attr string) T {
 	val, err := GetAttribute[T](pa, attr)
 	if err != nil {
-		log.Fatalf("Invalid value for %v\n", err)
+		panic(err)
 	}
 	return val
 }
 
+// GetOrDefault returns an attribute, or the default if the attribute is missing.  Invalid
+// values, including constraint violations, panic with an *AttributeError like GetOrFail.
 func GetOrDefault[T PayloadAttributeTypes](pa PayloadAttributes, attr string, defaultVal T) T {
 	val, err := GetAttribute[T](pa, attr)
+	if errors.Is(err, ErrAttributeMissing) {
+		return defaultVal
+	}
 	if err != nil {
-		val = defaultVal
-		log.Printf("Invalid value for %v. Using default of: %v\n", err, defaultVal)
+		panic(err)
 	}
 	return val
 }
 
+// GetAttribute returns an attribute converted to T.  Values are checked against a
+// registered constraint for the attribute (see AttributeConstraints).
+// Errors are *AttributeError.
 func GetAttribute[T PayloadAttributeTypes](pa PayloadAttributes, name string) (T, error) {
 	var t T
-	if attr, ok := pa[name]; ok {
-		tve := reflect.ValueOf(&t).Elem()
-		tk := tve.Kind()
-		switch tk {
-		case reflect.Int64:
-			i, err := cast.ToInt64E(attr)
-			tve.Set(reflect.ValueOf(i))
-			return t, err
-		case reflect.Int:
-			i, err := cast.ToInt64E(attr)
-			tve.Set(reflect.ValueOf(int(i)))
-			return t, err
-		case reflect.Int32:
-			i, err := cast.ToInt64E(attr)
-			tve.Set(reflect.ValueOf(int32(i)))
-			return t, err
-		case reflect.String:
-			s, err := cast.ToStringE(attr)
-			tve.Set(reflect.ValueOf(s))
-			return t, err
-		case reflect.Bool:
-			s, err := cast.ToStringE(attr)
+	attr, ok := pa.lookup(name)
+	if !ok {
+		return t, &AttributeError{name, ErrAttributeMissing}
+	}
+	if err := convertAttribute(attr, reflect.ValueOf(&t).Elem()); err != nil {
+		return t, attributeError(name, err)
+	}
+	if constraint, ok := AttributeConstraints[name]; ok {
+		if msgs := constraint.Violations(attr); len(msgs) > 0 {
+			return t, &AttributeError{name, errors.New(strings.Join(msgs, "; "))}
+		}
+	}
+	return t, nil
+}
+
+// GetSlice returns a list attribute with each element converted to T.  T can be a
+// PayloadAttributeTypes type, a map or a struct (see Decode).
+func GetSlice[T any](pa PayloadAttributes, name string) ([]T, error) {
+	var t []T
+	attr, ok := pa.lookup(name)
+	if !ok {
+		return nil, &AttributeError{name, ErrAttributeMissing}
+	}
+	if err := convertAttribute(attr, reflect.ValueOf(&t).Elem()); err != nil {
+		return nil, attributeError(name, err)
+	}
+	return t, nil
+}
+
+var ErrAttributeMissing = errors.New("attribute is not in the payload")
+
+// AttributeError is an error reading, converting or validating the attribute at Path
+type AttributeError struct {
+	Path string
+	Err  error
+}
+
+func (e *AttributeError) Error() string {
+	return fmt.Sprintf("attribute %s: %s", e.Path, e.Err)
+}
+
+func (e *AttributeError) Unwrap() error {
+	return e.Err
+}
+
+// recoverAttributeError recovers a panic with an *AttributeError (see GetOrFail) and sets err.
+// Other panics are not recovered.
+func recoverAttributeError(err *error) {
+	if r := recover(); r != nil {
+		if ae, ok := r.(*AttributeError); ok {
+			*err = ae
+			return
+		}
+		panic(r)
+	}
+}
+
+// attributeError prefixes the path of a nested attribute error
+func attributeError(path string, err error) error {
+	var ae *AttributeError
+	if errors.As(err, &ae) {
+		if strings.HasPrefix(ae.Path, "[") {
+			return &AttributeError{path + ae.Path, ae.Err}
+		}
+		return &AttributeError{path + "." + ae.Path, ae.Err}
+	}
+	return &AttributeError{path, err}
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	urlType      = reflect.TypeOf(url.URL{})
+)
+
+// convertAttribute converts an attribute value and sets dest
+func convertAttribute(val any, dest reflect.Value) error {
+	typ := dest.Type()
+	castError := func(err error) error {
+		return fmt.Errorf("cannot convert %v (%T) to %s: %w", val, val, typ, err)
+	}
+	switch typ {
+	case durationType:
+		var d time.Duration
+		var err error
+		if s, ok := val.(string); ok {
+			d, err = time.ParseDuration(s)
+		} else {
+			var seconds float64
+			seconds, err = cast.ToFloat64E(val)
+			d = time.Duration(seconds * float64(time.Second))
+		}
+		if err != nil {
+			return castError(err)
+		}
+		dest.SetInt(int64(d))
+		return nil
+	case timeType:
+		s, err := cast.ToStringE(val)
+		if err != nil {
+			return castError(err)
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return castError(err)
+		}
+		dest.Set(reflect.ValueOf(t))
+		return nil
+	case urlType:
+		s, err := cast.ToStringE(val)
+		if err != nil {
+			return castError(err)
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return castError(err)
+		}
+		dest.Set(reflect.ValueOf(*u))
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := cast.ToInt64E(val)
+		if err != nil {
+			return castError(err)
+		}
+		if dest.OverflowInt(i) {
+			return fmt.Errorf("%d overflows %s", i, typ)
+		}
+		dest.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := cast.ToUint64E(val)
+		if err != nil {
+			return castError(err)
+		}
+		if dest.OverflowUint(i) {
+			return fmt.Errorf("%d overflows %s", i, typ)
+		}
+		dest.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := cast.ToFloat64E(val)
+		if err != nil {
+			return castError(err)
+		}
+		dest.SetFloat(f)
+	case reflect.String:
+		s, err := cast.ToStringE(val)
+		if err != nil {
+			return castError(err)
+		}
+		dest.SetString(s)
+	case reflect.Bool:
+		b, err := cast.ToBoolE(val)
+		if err != nil {
+			return castError(err)
+		}
+		dest.SetBool(b)
+	case reflect.Interface:
+		if val != nil {
+			dest.Set(reflect.ValueOf(val))
+		}
+	case reflect.Pointer:
+		if val == nil {
+			return nil
+		}
+		ptr := reflect.New(typ.Elem())
+		if err := convertAttribute(val, ptr.Elem()); err != nil {
+			return err
+		}
+		dest.Set(ptr)
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key type %s", typ.Key())
+		}
+		m, err := cast.ToStringMapE(val)
+		if err != nil {
+			return castError(err)
+		}
+		result := reflect.MakeMapWithSize(typ, len(m))
+		for k, v := range m {
+			elem := reflect.New(typ.Elem()).Elem()
+			if err := convertAttribute(v, elem); err != nil {
+				return attributeError(k, err)
+			}
+			result.SetMapIndex(reflect.ValueOf(k).Convert(typ.Key()), elem)
+		}
+		dest.Set(result)
+	case reflect.Slice:
+		vals := reflect.ValueOf(val)
+		if val == nil || vals.Kind() != reflect.Slice {
+			return fmt.Errorf("cannot convert %v (%T) to %s: expected a list", val, val, typ)
+		}
+		result := reflect.MakeSlice(typ, vals.Len(), vals.Len())
+		for i := 0; i < vals.Len(); i++ {
+			if err := convertAttribute(vals.Index(i).Interface(), result.Index(i)); err != nil {
+				return attributeError(fmt.Sprintf("[%d]", i), err)
+			}
+		}
+		dest.Set(result)
+	case reflect.Struct:
+		m, err := cast.ToStringMapE(val)
+		if err != nil {
+			return castError(err)
+		}
+		return decodeStruct(m, dest)
+	default:
+		return fmt.Errorf("unsupported attribute type %s", typ)
+	}
+	return nil
+}
+
+// decodeStruct decodes an attribute map into the exported fields of a struct
+func decodeStruct(attrs map[string]any, dest reflect.Value) error {
+	typ := dest.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := attributeFieldName(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && name == field.Name {
+			if err := decodeStruct(attrs, dest.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		key := name
+		val, ok := attrs[key]
+		if !ok {
+			for k, v := range attrs {
+				if strings.EqualFold(k, name) {
+					key, val, ok = k, v, true
+					break
+				}
+			}
+		}
+		if !ok || val == nil {
+			if hasValidationRule(field, "required") {
+				return attributeError(name, errors.New("attribute is required"))
+			}
+			continue
+		}
+		if err := convertAttribute(val, dest.Field(i)); err != nil {
+			return attributeError(key, err)
+		}
+		if err := validateAttributeField(field, dest.Field(i)); err != nil {
+			return attributeError(key, err)
+		}
+	}
+	return nil
+}
+
+// attributeFieldName returns the attribute key of a struct field from the cc or json
+// tag or the field name.  A name of "-" skips the field.
+func attributeFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"cc", "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func hasValidationRule(field reflect.StructField, rule string) bool {
+	for _, r := range strings.Split(field.Tag.Get("validate"), ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAttributeField checks a decoded field against the rules in its validate tag
+func validateAttributeField(field reflect.StructField, val reflect.Value) error {
+	tag := field.Tag.Get("validate")
+	if tag == "" {
+		return nil
+	}
+	for val.Kind() == reflect.Pointer && !val.IsNil() {
+		val = val.Elem()
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		rule = strings.TrimSpace(rule)
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "", "required":
+		case "min", "max":
+			bound, err := strconv.ParseFloat(arg, 64)
 			if err != nil {
-				return t, err
+				return fmt.Errorf("invalid validation rule %s", rule)
+			}
+			size, measure := validationSize(val)
+			if measure == "" {
+				return fmt.Errorf("validation rule %s does not apply to %s", rule, val.Type())
+			}
+			if name == "min" && size < bound {
+				return fmt.Errorf("%s %v is less than the minimum of %v", measure, size, bound)
+			}
+			if name == "max" && size > bound {
+				return fmt.Errorf("%s %v is greater than the maximum of %v", measure, size, bound)
+			}
+		case "oneof":
+			options := strings.Fields(arg)
+			s := fmt.Sprintf("%v", val.Interface())
+			found := false
+			for _, option := range options {
+				found = found || s == option
+			}
+			if !found {
+				return fmt.Errorf("value %s is not one of %v", s, options)
 			}
-			val := (strings.ToLower(s) == "true")
-			tve.Set(reflect.ValueOf(val))
-			return t, err
-		case reflect.Float64:
-			f, err := cast.ToFloat64E(attr)
-			tve.Set(reflect.ValueOf(f))
-			return t, err
-		case reflect.Map:
-			i := cast.ToStringMap(attr)
-			tve.Set(reflect.ValueOf(i))
-			return t, nil
 		default:
-			return t, errors.New("Unsupported type for cast")
+			return fmt.Errorf("unsupported validation rule %s", rule)
 		}
 	}
-	return t, errors.New(fmt.Sprintf("Attribute %s is not in the payload\n", name))
+	return nil
+}
+
+// validationSize returns the value of a number or the length of a string, list or map
+func validationSize(val reflect.Value) (float64, string) {
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), "value"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(val.Uint()), "value"
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), "value"
+	case reflect.String, reflect.Slice, reflect.Map:
+		return float64(val.Len()), "length"
+	}
+	return 0, ""
 }
 
 func Slice2Type[T any](input []any) []T {
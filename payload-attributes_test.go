@@ -0,0 +1,127 @@
+package cc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAttributeAccessors(t *testing.T) {
+	payload, err := UnmarshalPayload([]byte(`{"attributes": {
+		"model": {"boundary": {"upstream": "elk-river", "stations": [1, 2, "3"]}},
+		"timestep": "90s",
+		"timeout": 30,
+		"start": "2024-03-01T06:00:00Z",
+		"endpoint": "https://example.com/api?basin=kanawha",
+		"enabled": "T",
+		"bad_bool": "yes",
+		"gages": [{"name": "elk", "flow": 120.5}, {"name": "coal", "flow": "80"}],
+		"mixed": [1, "two"]
+	}}`), PayloadJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := payload.Attributes
+
+	if upstream, err := attrs.GetString("model.boundary.upstream"); err != nil || upstream != "elk-river" {
+		t.Errorf("unexpected dotted path value: %q %v", upstream, err)
+	}
+	if stations, err := attrs.GetIntSlice("model.boundary.stations"); err != nil || len(stations) != 3 || stations[2] != 3 {
+		t.Errorf("unexpected int slice: %v %v", stations, err)
+	}
+	if d, err := attrs.GetDuration("timestep"); err != nil || d != 90*time.Second {
+		t.Errorf("unexpected duration: %v %v", d, err)
+	}
+	if d := attrs.GetDurationOrDefault("timeout", time.Minute); d != 30*time.Second {
+		t.Errorf("unexpected numeric duration: %v", d)
+	}
+	if start, err := attrs.GetTime("start"); err != nil || start.Hour() != 6 {
+		t.Errorf("unexpected time: %v %v", start, err)
+	}
+	if u, err := attrs.GetURL("endpoint"); err != nil || u.Host != "example.com" || u.Query().Get("basin") != "kanawha" {
+		t.Errorf("unexpected url: %v %v", u, err)
+	}
+	if enabled, err := attrs.GetBoolean("enabled"); err != nil || !enabled {
+		t.Errorf("unexpected boolean: %v %v", enabled, err)
+	}
+	if _, err := attrs.GetBoolean("bad_bool"); err == nil {
+		t.Error("expected an error for an invalid boolean")
+	}
+	if maps, err := attrs.GetMapSlice("gages"); err != nil || len(maps) != 2 || maps[1]["name"] != "coal" {
+		t.Errorf("unexpected map slice: %v %v", maps, err)
+	}
+
+	type gage struct {
+		Name string  `json:"name" validate:"required"`
+		Flow float64 `cc:"flow" validate:"min=0"`
+	}
+	gages, err := GetSlice[gage](attrs, "gages")
+	if err != nil || len(gages) != 2 || gages[1].Flow != 80 {
+		t.Errorf("unexpected struct slice: %+v %v", gages, err)
+	}
+
+	//element errors name the element
+	_, err = GetSlice[int](attrs, "mixed")
+	var attrErr *AttributeError
+	if !errors.As(err, &attrErr) || attrErr.Path != "mixed[1]" {
+		t.Errorf("expected an element error, got %v", err)
+	}
+	if _, err = attrs.GetStringSlice("timestep"); err == nil {
+		t.Error("expected an error for a value that is not a list")
+	}
+}
+
+func TestAttributeDecode(t *testing.T) {
+	type boundary struct {
+		Upstream string  `cc:"upstream" validate:"required,oneof=elk-river coal-river"`
+		Stations []int   `json:"stations" validate:"min=1"`
+		Scale    float64 `validate:"max=10"`
+	}
+	type model struct {
+		Boundary boundary      `json:"boundary"`
+		Timestep time.Duration `json:"timestep"`
+		Solver   *string       `json:"solver"`
+		Ignored  string        `json:"-"`
+	}
+	attrs := PayloadAttributes{
+		"model": map[string]any{
+			"boundary": map[string]any{"upstream": "elk-river", "stations": []any{1.0, 2.0}, "scale": 2.5},
+			"timestep": "30s",
+			"solver":   "implicit",
+			"ignored":  "x",
+		},
+	}
+	m := model{}
+	if err := attrs.Decode("model", &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Boundary.Upstream != "elk-river" || len(m.Boundary.Stations) != 2 || m.Boundary.Scale != 2.5 ||
+		m.Timestep != 30*time.Second || m.Solver == nil || *m.Solver != "implicit" || m.Ignored != "" {
+		t.Errorf("unexpected decoded model: %+v", m)
+	}
+
+	whole := struct {
+		Model model `json:"model"`
+	}{}
+	if err := attrs.Decode("", &whole); err != nil || whole.Model.Boundary.Upstream != "elk-river" {
+		t.Errorf("unexpected decode of all attributes: %+v %v", whole, err)
+	}
+
+	tests := map[string]map[string]any{
+		"model.boundary.upstream": {"stations": []any{1.0}},
+		"model.boundary.stations": {"upstream": "elk-river", "stations": []any{}},
+		"model.boundary.scale":    {"upstream": "elk-river", "scale": 11.0},
+	}
+	for path, b := range tests {
+		attrs["model"].(map[string]any)["boundary"] = b
+		err := attrs.Decode("model", &model{})
+		var attrErr *AttributeError
+		if !errors.As(err, &attrErr) || attrErr.Path != path {
+			t.Errorf("%s: expected a validation error, got %v", path, err)
+		}
+	}
+	attrs["model"].(map[string]any)["boundary"] = map[string]any{"upstream": "gauley"}
+	if err := attrs.Decode("model", &model{}); err == nil {
+		t.Error("expected a oneof validation error")
+	}
+}
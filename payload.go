@@ -7,6 +7,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 )
 
 type DataSourceIoType string
@@ -18,15 +20,25 @@ const (
 )
 
 type Payload struct {
-	IOManager
-	Actions []Action `json:"actions"`
+	IOManager `yaml:",inline"`
+
+	//paths of payload fragments merged into the payload (see Resolve)
+	Includes []string `json:"includes,omitempty" yaml:"includes,omitempty" toml:"includes,omitempty"`
+
+	//named store definitions that stores can extend
+	StoreProfiles []DataStore `json:"store_profiles,omitempty" yaml:"store_profiles,omitempty" toml:"store_profiles,omitempty"`
+	Actions       []Action    `json:"actions" yaml:"actions" toml:"actions"`
+	included      []Payload   //loaded includes
 }
 
 type Action struct {
-	IOManager
-	Type        string `json:"type,omitempty"`
-	Description string `json:"description,omitempty"`
-	Name        string `json:"name,omitempty"`
+	IOManager   `yaml:",inline"`
+	Type        string `json:"type,omitempty" yaml:"type,omitempty" toml:"type,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty" toml:"description,omitempty"`
+	Name        string `json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+
+	//names of the actions that must complete before the action runs (see RunActions)
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty" toml:"depends_on,omitempty"`
 }
 
 // -----------------------------------------------
@@ -61,27 +73,65 @@ func (a Action) Put(input PutOpInput) (int, error) {
 	return a.IOManager.Put(input)
 }
 
-func (a Action) Copy(src DataSourceOpInput, dest DataSourceOpInput) error {
+func (a Action) Copy(src DataSourceOpInput, dest DataSourceOpInput) (CopyResult, error) {
 	return a.IOManager.Copy(src, dest)
 }
 
+func (a Action) CopyAll(src DataSourceOpInput, dest DataSourceOpInput, pathKeys ...string) ([]CopyResult, error) {
+	return a.IOManager.CopyAll(src, dest, pathKeys...)
+}
+
 func (a Action) CopyFileToLocal(dsName string, pathkey string, dataPathKey string, localPath string) error {
 	return a.IOManager.CopyFileToLocal(dsName, pathkey, dataPathKey, localPath)
 }
 
+func (a Action) CopyToLocal(input DataSourceOpInput, localPath string) error {
+	return a.IOManager.CopyToLocal(input, localPath)
+}
+
+func (a Action) ExpandPaths(input DataSourceOpInput) ([]string, error) {
+	return a.IOManager.ExpandPaths(input)
+}
+
+func (a Action) ForEachReader(input DataSourceOpInput, readerFunction func(path string, reader io.Reader) error) error {
+	return a.IOManager.ForEachReader(input, readerFunction)
+}
+
+func (a Action) CopyDataSourceToLocal(input DataSourceOpInput, localDir string) ([]string, error) {
+	return a.IOManager.CopyDataSourceToLocal(input, localDir)
+}
+
 func (a Action) CopyFileToRemote(input CopyFileToRemoteInput) error {
 	return a.IOManager.CopyFileToRemote(input)
 }
 
+func (a Action) GetMetadataStore(storeName string, namespace ...string) (*NamespacedMetadataStore, error) {
+	return a.IOManager.GetMetadataStore(storeName, namespace...)
+}
+
+func (a Action) GetRaster(input DataSourceOpInput, window GetRasterInput) (*ArrayResult, error) {
+	return a.IOManager.GetRaster(input, window)
+}
+
+func (a Action) PutRaster(input DataSourceOpInput, raster GeoTiffInput) (int, error) {
+	return a.IOManager.PutRaster(input, raster)
+}
+
 // -----------------------------------------------
 // IOManager
 // -----------------------------------------------
 type IOManager struct {
-	Attributes PayloadAttributes `json:"attributes,omitempty"`
-	Stores     []DataStore       `json:"stores"`
-	Inputs     []DataSource      `json:"inputs"`
-	Outputs    []DataSource      `json:"outputs"`
+	Attributes PayloadAttributes `json:"attributes,omitempty" yaml:"attributes,omitempty" toml:"attributes,omitempty"`
+	Stores     []DataStore       `json:"stores" yaml:"stores,omitempty" toml:"stores,omitempty"`
+	Inputs     []DataSource      `json:"inputs" yaml:"inputs,omitempty" toml:"inputs,omitempty"`
+	Outputs    []DataSource      `json:"outputs" yaml:"outputs,omitempty" toml:"outputs,omitempty"`
 	parent     *IOManager
+	recorder   *provenanceRecorder //provenance records of the payload and actions
+	actionName string              //name of the action for action IOManagers
+	dryRun     bool                //reads check existence and writes are discarded (see PluginManager.DryRun)
+
+	//set when a data source is found in the parent IOManager.  only tracked for cached actions
+	parentSources *atomic.Bool
 }
 
 type GetDsInput struct {
@@ -142,7 +192,11 @@ func (im *IOManager) GetDataSource(input GetDsInput) (DataSource, error) {
 		}
 	}
 	if im.parent != nil {
-		return im.parent.GetDataSource(input)
+		ds, err := im.parent.GetDataSource(input)
+		if err == nil && im.parentSources != nil {
+			im.parentSources.Store(true)
+		}
+		return ds, err
 	}
 	return DataSource{}, fmt.Errorf("data source %s not found", input.DsName)
 }
@@ -172,22 +226,49 @@ func (im *IOManager) GetAbsolutePath(storename string, sourcename string, pathna
 
 	root := store.Parameters.GetStringOrDefault("root", "/")
 	if path, ok := ds.Paths[pathname]; ok {
-		return filepath.Clean(fmt.Sprintf("%s%c%s", root, os.PathSeparator, path)), nil
+		return filepath.Clean(fmt.Sprintf("%s%c%s", root, os.PathSeparator, unescapeTemplateBraces(path))), nil
 	}
 	return "", fmt.Errorf("invalid path name: %s", pathname)
 
 }
 
-func (im *IOManager) GetReader(input DataSourceOpInput) (io.ReadCloser, error) {
-	var err error
-	var dataSource DataSource
-	if input.DataSource == nil {
-		dataSource, err = im.GetInputDataSource(input.DataSourceName)
+// opDataSource returns the data source for an operation.  The input DataSource
+// takes precedence over a lookup by name in the inputs or outputs
+func (im *IOManager) opDataSource(input DataSourceOpInput, ioType DataSourceIoType) (DataSource, error) {
+	if input.DataSource != nil {
+		return *input.DataSource, nil
+	}
+	return im.GetDataSource(GetDsInput{ioType, input.DataSourceName})
+}
+
+// opPaths returns the data source path and data path for an operation with
+// the operation template variables applied.  Unresolved {VAR::} templates are errors.
+func opPaths(ds DataSource, input DataSourceOpInput) (string, string, error) {
+	path, ok := ds.Paths[input.PathKey]
+	if !ok {
+		return "", "", fmt.Errorf("data source path %s not found", input.PathKey)
+	}
+	path, err := templateVarSubstitution(path, input.TemplateVars)
+	if err != nil {
+		return "", "", fmt.Errorf("data source %s path %s: %w", ds.Name, input.PathKey, err)
+	}
+	datapath := ""
+	if input.DataPathKey != "" {
+		if datapath, ok = ds.DataPaths[input.DataPathKey]; !ok {
+			return "", "", fmt.Errorf("expected data source data path %s not found", input.DataPathKey)
+		}
+		datapath, err = templateVarSubstitution(datapath, input.TemplateVars)
 		if err != nil {
-			return nil, err
+			return "", "", fmt.Errorf("data source %s data path %s: %w", ds.Name, input.DataPathKey, err)
 		}
-	} else {
-		dataSource = *input.DataSource
+	}
+	return path, datapath, nil
+}
+
+func (im *IOManager) GetReader(input DataSourceOpInput) (io.ReadCloser, error) {
+	dataSource, err := im.opDataSource(input, DataSourceInput)
+	if err != nil {
+		return nil, err
 	}
 
 	dataStore, err := im.GetStore(dataSource.StoreName)
@@ -195,17 +276,20 @@ func (im *IOManager) GetReader(input DataSourceOpInput) (io.ReadCloser, error) {
 		return nil, err
 	}
 	if readerStore, ok := dataStore.Session.(StoreReader); ok {
-		path := dataSource.Paths[input.PathKey]
-		//
-		if len(input.TemplateVars) > 0 {
-			path = templateVarSubstitution(path, input.TemplateVars)
+		path, datapath, err := opPaths(dataSource, input)
+		if err != nil {
+			return nil, err
 		}
-		//
-		datapath := ""
-		if input.DataPathKey != "" {
-			datapath = dataSource.DataPaths[input.DataPathKey]
+		var reader io.ReadCloser
+		if im.isDryRun() {
+			reader, err = im.dryRunReader(dataSource, path)
+		} else {
+			reader, err = readerStore.Get(path, datapath)
 		}
-		return readerStore.Get(path, datapath)
+		if err != nil {
+			return nil, err
+		}
+		return im.recordReader(reader, dataSource, path, datapath), nil
 	}
 	return nil, fmt.Errorf("data store %s session does not implement a StoreReader", dataStore.Name)
 }
@@ -215,6 +299,7 @@ func (im *IOManager) Get(input DataSourceOpInput) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer reader.Close()
 
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(reader)
@@ -222,7 +307,7 @@ func (im *IOManager) Get(input DataSourceOpInput) ([]byte, error) {
 }
 
 func (im *IOManager) Put(input PutOpInput) (int, error) {
-	ds, err := im.GetOutputDataSource(input.DataSourceName)
+	ds, err := im.opDataSource(input.DataSourceOpInput, DataSourceOutput)
 	if err != nil {
 		return 0, err
 	}
@@ -232,73 +317,159 @@ func (im *IOManager) Put(input PutOpInput) (int, error) {
 		return 0, err
 	}
 
-	if writer, ok := store.Session.(StoreWriter); ok {
-		if path, ok := ds.Paths[input.PathKey]; ok {
-			datapath := ""
-			if input.DataPathKey != "" {
-				var dpok bool
-				if datapath, dpok = ds.DataPaths[input.DataPathKey]; !dpok {
-					return 0, fmt.Errorf("expected data source data path %s not found", input.DataPathKey)
-				}
-			}
-			return writer.Put(input.SrcReader, path, datapath)
+	if writer, ok := im.storeWriter(store); ok {
+		path, datapath, err := opPaths(ds, input.DataSourceOpInput)
+		if err != nil {
+			return 0, err
+		}
+		reader := newChecksumReader(input.SrcReader)
+		n, err := writer.Put(reader, path, datapath)
+		if err == nil {
+			im.recordOperation(ProvenanceWrite, ds, path, datapath, reader.n, reader.Sum())
 		}
-		return 0, fmt.Errorf("data source path %s not found", input.PathKey)
+		return n, err
 	}
 	return 0, fmt.Errorf("data store %s session does not implement a storewriter", ds.StoreName)
 }
 
-func (im *IOManager) Copy(src DataSourceOpInput, dest DataSourceOpInput) error {
-	srcds, err := im.GetOutputDataSource(src.DataSourceName)
+// CopyResult describes a completed copy between data sources
+type CopyResult struct {
+	SourcePath      string `json:"source_path"`
+	DestinationPath string `json:"destination_path"`
+	Bytes           int64  `json:"bytes"`
+	Sha256          string `json:"sha256"` //hex encoded sha256 of the copied bytes
+}
+
+// Copy streams a data source path to an output data source path.  The source is resolved from
+// the inputs and then the outputs, and the destination from the outputs.  DataSource overrides
+// and template variables in src and dest are honored.
+func (im *IOManager) Copy(src DataSourceOpInput, dest DataSourceOpInput) (CopyResult, error) {
+	result := CopyResult{}
+	srcds, err := im.copySourceDataSource(src)
 	if err != nil {
-		return err
+		return result, err
 	}
 
 	srcstore, err := im.GetStore(srcds.StoreName)
 	if err != nil {
-		return err
+		return result, err
 	}
 
-	destds, err := im.GetOutputDataSource(dest.DataSourceName)
+	destds, err := im.opDataSource(dest, DataSourceOutput)
 	if err != nil {
-		return err
+		return result, err
 	}
 
 	deststore, err := im.GetStore(destds.StoreName)
 	if err != nil {
-		return err
+		return result, err
 	}
 
-	if srcReader, ok := srcstore.Session.(StoreReader); ok {
-		if destwriter, ok := deststore.Session.(StoreWriter); ok {
+	srcReader, ok := srcstore.Session.(StoreReader)
+	if !ok {
+		return result, fmt.Errorf("Source Data Store %s session does not implement a StoreReader", srcstore.Name)
+	}
+	destwriter, ok := im.storeWriter(deststore)
+	if !ok {
+		return result, fmt.Errorf("Destination Data Store %s session does not implement a StoreWriter", deststore.Name)
+	}
 
-			//get the reader
-			srcpath := srcds.Paths[src.PathKey]
-			srcdatapath := ""
-			if src.DataPathKey != "" {
-				srcdatapath = srcds.DataPaths[src.DataPathKey]
-			}
-			reader, err := srcReader.Get(srcpath, srcdatapath)
-			if err != nil {
-				return err
-			}
+	srcpath, srcdatapath, err := opPaths(srcds, src)
+	if err != nil {
+		return result, err
+	}
+	destpath, destdatapath, err := opPaths(destds, dest)
+	if err != nil {
+		return result, err
+	}
+	result.SourcePath = srcpath
+	result.DestinationPath = destpath
+
+	//dry runs check the source without reading it
+	if im.isDryRun() {
+		if err = im.checkExists(srcds, srcpath); err != nil {
+			return result, err
+		}
+		im.recordOperation(ProvenanceRead, srcds, srcpath, srcdatapath, 0, "")
+		im.recordOperation(ProvenanceWrite, destds, destpath, destdatapath, 0, "")
+		return result, nil
+	}
+
+	//get the reader
+	reader, err := srcReader.Get(srcpath, srcdatapath)
+	if err != nil {
+		return result, err
+	}
+	defer reader.Close()
+
+	//write while computing the checksum
+	checksum := newChecksumReader(reader)
+	_, err = destwriter.Put(checksum, destpath, destdatapath)
+	if err != nil {
+		return result, err
+	}
+	result.Bytes = checksum.n
+	result.Sha256 = checksum.Sum()
+	im.recordOperation(ProvenanceRead, srcds, srcpath, srcdatapath, result.Bytes, result.Sha256)
+	im.recordOperation(ProvenanceWrite, destds, destpath, destdatapath, result.Bytes, result.Sha256)
+	return result, nil
+}
 
-			//write
-			destpath := destds.Paths[dest.PathKey]
-			destdatapath := ""
-			if dest.DataPathKey != "" {
-				destdatapath = destds.DataPaths[dest.DataPathKey]
+// CopyAll copies several path keys from src to dest.  Each path key is copied to the same
+// path key in the destination.  If no path keys are given every source path key that is also
+// a destination path key is copied.  The copy stops at the first error and returns the
+// results of the completed copies.
+func (im *IOManager) CopyAll(src DataSourceOpInput, dest DataSourceOpInput, pathKeys ...string) ([]CopyResult, error) {
+	if len(pathKeys) == 0 {
+		srcds, err := im.copySourceDataSource(src)
+		if err != nil {
+			return nil, err
+		}
+		destds, err := im.opDataSource(dest, DataSourceOutput)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range sortedKeys(srcds.Paths) {
+			if _, ok := destds.Paths[key]; ok {
+				pathKeys = append(pathKeys, key)
 			}
-			_, err = destwriter.Put(reader, destpath, destdatapath)
-			return err
 		}
-		return fmt.Errorf("Destination Data Store %s session does not implement a StoreWriter", srcstore.Name)
 	}
-	return fmt.Errorf("Source Data Store %s session does not implement a StoreReader", srcstore.Name)
+
+	results := []CopyResult{}
+	for _, key := range pathKeys {
+		src.PathKey = key
+		dest.PathKey = key
+		result, err := im.Copy(src, dest)
+		if err != nil {
+			return results, fmt.Errorf("failed to copy path %s: %w", key, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// copySourceDataSource resolves a copy source from the inputs and then the outputs
+func (im *IOManager) copySourceDataSource(src DataSourceOpInput) (DataSource, error) {
+	ds, err := im.opDataSource(src, DataSourceInput)
+	if err != nil {
+		return im.opDataSource(src, DataSourceOutput)
+	}
+	return ds, nil
 }
 
 func (im *IOManager) CopyFileToLocal(dsName string, pathkey string, dataPathKey string, localPath string) error {
-	ds, err := im.GetDataSource(GetDsInput{DataSourceInput, dsName})
+	return im.CopyToLocal(DataSourceOpInput{
+		DataSourceName: dsName,
+		PathKey:        pathkey,
+		DataPathKey:    dataPathKey,
+	}, localPath)
+}
+
+// CopyToLocal copies an input data source path to a local file.
+// Template variables in the input are applied to the data source path and data path.
+func (im *IOManager) CopyToLocal(input DataSourceOpInput, localPath string) error {
+	ds, err := im.opDataSource(input, DataSourceInput)
 	if err != nil {
 		return err
 	}
@@ -308,17 +479,26 @@ func (im *IOManager) CopyFileToLocal(dsName string, pathkey string, dataPathKey
 		return err
 	}
 
-	path := ds.Paths[pathkey]
-	datapath := ""
-	if dataPathKey != "" {
-		datapath = ds.DataPaths[dataPathKey]
+	path, datapath, err := opPaths(ds, input)
+	if err != nil {
+		return err
 	}
 
 	if storeReader, ok := store.Session.(StoreReader); ok {
+		//dry runs check the source without creating the local file
+		if im.isDryRun() {
+			if err = im.checkExists(ds, path); err != nil {
+				return err
+			}
+			im.recordOperation(ProvenanceRead, ds, path, datapath, 0, "")
+			return nil
+		}
+
 		reader, err := storeReader.Get(path, datapath)
 		if err != nil {
 			return err
 		}
+		reader = im.recordReader(reader, ds, path, datapath)
 		defer reader.Close()
 
 		writer, err := os.Create(localPath)
@@ -334,6 +514,146 @@ func (im *IOManager) CopyFileToLocal(dsName string, pathkey string, dataPathKey
 	return fmt.Errorf("Data Store %s session does not implement a StoreReader", store.Name)
 }
 
+// ExpandPaths returns the store paths selected by an input data source path.  The path can be a
+// literal path, a prefix ending in "/" or a glob pattern (see PathPattern).  Prefixes and
+// patterns require a store implementing StoreLister.  Matches are sorted.
+func (im *IOManager) ExpandPaths(input DataSourceOpInput) ([]string, error) {
+	ds, err := im.opDataSource(input, DataSourceInput)
+	if err != nil {
+		return nil, err
+	}
+	path, _, err := opPaths(ds, input)
+	if err != nil {
+		return nil, err
+	}
+	_, paths, err := im.expandPaths(ds, path)
+	return paths, err
+}
+
+func (im *IOManager) expandPaths(ds DataSource, path string) (*PathPattern, []string, error) {
+	//store listings are relative to the store root
+	lead := ""
+	if strings.HasPrefix(path, "/") {
+		lead = "/"
+	}
+	pattern, err := NewPathPattern(strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid data source path pattern %s: %w", path, err)
+	}
+	if pattern.IsLiteral() {
+		return pattern, []string{path}, nil
+	}
+
+	store, err := im.GetStore(ds.StoreName)
+	if err != nil {
+		return nil, nil, err
+	}
+	lister, ok := store.Session.(StoreLister)
+	if !ok {
+		return nil, nil, fmt.Errorf("data store %s session does not implement a StoreLister", store.Name)
+	}
+	listing, err := lister.List(pattern.Prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	paths := pattern.Filter(listing)
+	for i := range paths {
+		paths[i] = lead + paths[i]
+	}
+	return pattern, paths, nil
+}
+
+// ForEachReader calls readerFunction with a reader for each path selected by an input data source path
+// (see ExpandPaths).  Readers are closed when readerFunction returns.  Iteration stops at the first error.
+func (im *IOManager) ForEachReader(input DataSourceOpInput, readerFunction func(path string, reader io.Reader) error) error {
+	ds, err := im.opDataSource(input, DataSourceInput)
+	if err != nil {
+		return err
+	}
+	paths, err := im.ExpandPaths(input)
+	if err != nil {
+		return err
+	}
+	store, err := im.GetStore(ds.StoreName)
+	if err != nil {
+		return err
+	}
+	storeReader, ok := store.Session.(StoreReader)
+	if !ok {
+		return fmt.Errorf("data store %s session does not implement a StoreReader", store.Name)
+	}
+	_, datapath, err := opPaths(ds, input)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		err = func() error {
+			var reader io.ReadCloser
+			if im.isDryRun() {
+				reader, err = im.dryRunReader(ds, path)
+			} else {
+				reader, err = storeReader.Get(path, datapath)
+			}
+			if err != nil {
+				return err
+			}
+			reader = im.recordReader(reader, ds, path, datapath)
+			defer reader.Close()
+			return readerFunction(path, reader)
+		}()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// CopyDataSourceToLocal copies every object selected by an input data source path to a local directory.
+// Objects keep their paths relative to the directory containing the literal part of the pattern,
+// so "events/1/" or "events/1/*.csv" copies events/1/a.csv to localDir/a.csv.
+// The local paths are returned.
+func (im *IOManager) CopyDataSourceToLocal(input DataSourceOpInput, localDir string) ([]string, error) {
+	ds, err := im.opDataSource(input, DataSourceInput)
+	if err != nil {
+		return nil, err
+	}
+	path, _, err := opPaths(ds, input)
+	if err != nil {
+		return nil, err
+	}
+	pattern, paths, err := im.expandPaths(ds, path)
+	if err != nil {
+		return nil, err
+	}
+
+	localPaths := make([]string, len(paths))
+	for i, p := range paths {
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, "/"), pattern.Base())
+		if pattern.IsLiteral() {
+			rel = filepath.Base(p)
+		}
+		localPath := filepath.Join(localDir, filepath.FromSlash(rel))
+		if !im.isDryRun() {
+			if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+				return nil, err
+			}
+		}
+		dsCopy := ds
+		dsCopy.Paths = map[string]string{input.PathKey: p}
+		err = im.CopyToLocal(DataSourceOpInput{
+			DataSource:   &dsCopy,
+			PathKey:      input.PathKey,
+			DataPathKey:  input.DataPathKey,
+			TemplateVars: input.TemplateVars,
+		}, localPath)
+		if err != nil {
+			return nil, err
+		}
+		localPaths[i] = localPath
+	}
+	return localPaths, nil
+}
+
 type CopyFileToRemoteInput struct {
 	RemoteStoreName string
 	RemotePath      string
@@ -341,6 +661,7 @@ type CopyFileToRemoteInput struct {
 	RemoteDsName    string
 	DsPathKey       string
 	DsDataPathKey   string
+	TemplateVars    map[string]string //applied to the remote path or the data source paths
 }
 
 func (im *IOManager) CopyFileToRemote(input CopyFileToRemoteInput) error {
@@ -354,11 +675,20 @@ func (im *IOManager) CopyFileToRemote(input CopyFileToRemoteInput) error {
 			return err
 		}
 		storeName = ds.StoreName
-		path = ds.Paths[input.DsPathKey]
-		if input.DsDataPathKey != "" {
-			datapath = ds.DataPaths[input.DsDataPathKey]
+		path, datapath, err = opPaths(ds, DataSourceOpInput{
+			PathKey:      input.DsPathKey,
+			DataPathKey:  input.DsDataPathKey,
+			TemplateVars: input.TemplateVars,
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		var err error
+		path, err = templateVarSubstitution(path, input.TemplateVars)
+		if err != nil {
+			return err
 		}
-
 	}
 
 	store, err := im.GetStore(storeName)
@@ -366,19 +696,130 @@ func (im *IOManager) CopyFileToRemote(input CopyFileToRemoteInput) error {
 		return err
 	}
 
-	if writer, ok := store.Session.(StoreWriter); ok {
+	if writer, ok := im.storeWriter(store); ok {
 		reader, err := os.Open(input.LocalPath)
 		if err != nil {
 			return err
 		}
+		defer reader.Close()
 
-		_, err = writer.Put(reader, path, datapath)
+		checksum := newChecksumReader(reader)
+		_, err = writer.Put(checksum, path, datapath)
+		if err == nil {
+			ds := DataSource{Name: input.RemoteDsName, StoreName: storeName}
+			im.recordOperation(ProvenanceWrite, ds, path, datapath, checksum.n, checksum.Sum())
+		}
 		return err
 	}
 
 	return fmt.Errorf("Data Store %s session does not implement a StoreWriter", store.Name)
 }
 
+// GetMetadataStore returns the metadata of a data store scoped to a namespace (e.g. plugin/action/event).
+// The data store session must implement a MetadataStore.
+func (im *IOManager) GetMetadataStore(storeName string, namespace ...string) (*NamespacedMetadataStore, error) {
+	store, err := im.GetStore(storeName)
+	if err != nil {
+		return nil, err
+	}
+	if metadataStore, ok := store.Session.(MetadataStore); ok {
+		return NewNamespacedMetadataStore(metadataStore, namespace...)
+	}
+	return nil, fmt.Errorf("data store %s session does not implement a MetadataStore", storeName)
+}
+
+// GetRaster reads a window from a geotiff input data source.  The data source store must be a
+// RasterStore or support range reads (StoreRangeReader).  The window path is ignored and the
+// data source path is used.
+func (im *IOManager) GetRaster(input DataSourceOpInput, window GetRasterInput) (*ArrayResult, error) {
+	dataSource, err := im.opDataSource(input, DataSourceInput)
+	if err != nil {
+		return nil, err
+	}
+
+	dataStore, err := im.GetStore(dataSource.StoreName)
+	if err != nil {
+		return nil, err
+	}
+	path, _, err := opPaths(dataSource, input)
+	if err != nil {
+		return nil, err
+	}
+	window.Path = path
+
+	//dry runs check the raster without reading the window
+	if im.isDryRun() {
+		if err = im.checkExists(dataSource, path); err != nil {
+			return nil, err
+		}
+		im.recordOperation(ProvenanceRead, dataSource, path, "", 0, "")
+		return &ArrayResult{}, nil
+	}
+
+	var result *ArrayResult
+	switch store := dataStore.Session.(type) {
+	case RasterStore:
+		result, err = store.GetRaster(window)
+	case StoreRangeReader:
+		var reader io.ReaderAt
+		var gt *GeoTiff
+		reader, err = NewRangeReaderAt(store, path)
+		if err == nil {
+			gt, err = OpenGeoTiff(reader)
+		}
+		if err == nil {
+			result, err = gt.ReadWindow(window)
+		}
+	default:
+		return nil, fmt.Errorf("data store %s session does not support raster reads", dataStore.Name)
+	}
+	if err == nil {
+		//windowed reads are partial so the size and checksum are not recorded
+		im.recordOperation(ProvenanceRead, dataSource, path, "", 0, "")
+	}
+	return result, err
+}
+
+// PutRaster writes a raster as a cloud optimized geotiff to an output data source
+func (im *IOManager) PutRaster(input DataSourceOpInput, raster GeoTiffInput) (int, error) {
+	ds, err := im.opDataSource(input, DataSourceOutput)
+	if err != nil {
+		return 0, err
+	}
+
+	store, err := im.GetStore(ds.StoreName)
+	if err != nil {
+		return 0, err
+	}
+
+	path, _, err := opPaths(ds, input)
+	if err != nil {
+		return 0, err
+	}
+
+	if rasterStore, ok := store.Session.(RasterStore); ok && !im.isDryRun() {
+		n, err := rasterStore.PutRaster(PutRasterInput{path, raster})
+		if err == nil {
+			im.recordOperation(ProvenanceWrite, ds, path, "", int64(n), "")
+		}
+		return n, err
+	}
+	if writer, ok := im.storeWriter(store); ok {
+		buf := bytes.Buffer{}
+		if err := WriteCog(&buf, raster); err != nil {
+			return 0, err
+		}
+		size := buf.Len()
+		checksum := newChecksumReader(&buf)
+		_, err = writer.Put(checksum, path, "")
+		if err == nil {
+			im.recordOperation(ProvenanceWrite, ds, path, "", checksum.n, checksum.Sum())
+		}
+		return size, err
+	}
+	return 0, fmt.Errorf("data store %s session does not implement a storewriter", ds.StoreName)
+}
+
 func GetStoreAs[T any](mgr *IOManager, name string) (T, error) {
 	for _, s := range mgr.Stores {
 		if s.Name == name {
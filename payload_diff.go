@@ -0,0 +1,248 @@
+package cc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+type PayloadDifferenceKind string
+
+const (
+	PayloadValueAdded   PayloadDifferenceKind = "added"
+	PayloadValueRemoved PayloadDifferenceKind = "removed"
+	PayloadValueChanged PayloadDifferenceKind = "changed"
+)
+
+// PayloadDifference is a value that differs between two payloads.  Path is a dotted path
+// into the payload with named stores, data sources and actions written as [name], for example
+// actions[compute].inputs[terrain].paths.default.  Before and After are the json values
+// and are nil for added and removed values.
+type PayloadDifference struct {
+	Path   string                `json:"path"`
+	Kind   PayloadDifferenceKind `json:"kind"`
+	Before any                   `json:"before,omitempty"`
+	After  any                   `json:"after,omitempty"`
+}
+
+func (d PayloadDifference) String() string {
+	switch d.Kind {
+	case PayloadValueAdded:
+		return fmt.Sprintf("%s %s: %s", d.Kind, d.Path, diffValueString(d.After))
+	case PayloadValueRemoved:
+		return fmt.Sprintf("%s %s: %s", d.Kind, d.Path, diffValueString(d.Before))
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", d.Kind, d.Path, diffValueString(d.Before), diffValueString(d.After))
+}
+
+// payload lists that are compared by element name rather than position.  Actions are
+// kept in order because the order of actions without depends_on is the run order.
+var namedPayloadLists = []string{"stores", "store_profiles", "inputs", "outputs"}
+
+// Hash returns a sha256 hash of the canonical form of the resolved payload (see Resolve).
+// Stores and data sources are compared by name so the hash does not depend on their
+// order or on the order of attributes.  Actions are hashed in order.
+// Resolved secrets are hashed as their {SECRET::name} template.
+func (p Payload) Hash() (string, error) {
+	resolved, err := p.Resolve()
+	if err != nil {
+		return "", err
+	}
+	resolved, err = redactPayload(resolved)
+	if err != nil {
+		return "", err
+	}
+	doc, err := canonicalPayload(resolved)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash payload: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// DiffPayloads returns the differences between two payloads ordered by path.  Payloads are compared
+// as given, so payloads with includes or store profiles should be resolved first.
+// Actions are compared by name.  If both payloads have the same actions in a different
+// order, the action names are reported as a change of actions.order.
+func DiffPayloads(a Payload, b Payload) ([]PayloadDifference, error) {
+	docA, err := canonicalPayload(a)
+	if err != nil {
+		return nil, err
+	}
+	docB, err := canonicalPayload(b)
+	if err != nil {
+		return nil, err
+	}
+	orderA, orderB := namedActions(docA), namedActions(docB)
+	if !slices.Equal(orderA, orderB) && sameElements(orderA, orderB) {
+		docA["actions"].(map[string]any)["order"] = strings.Join(orderA, "")
+		docB["actions"].(map[string]any)["order"] = strings.Join(orderB, "")
+	}
+	diffs := []PayloadDifference{}
+	diffValues("", docA, docB, &diffs)
+	return diffs, nil
+}
+
+// namedActions replaces the canonical action list with actions keyed by name
+// and returns the action keys in order
+func namedActions(doc map[string]any) []string {
+	list, ok := doc["actions"].([]any)
+	if !ok {
+		return nil
+	}
+	named, names := namedElements(list)
+	doc["actions"] = named
+	return names
+}
+
+// sameElements returns true if two lists have the same elements in any order
+func sameElements(a []string, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// MergePayloads merges the override payload over the base payload in the same way payload
+// includes are merged: attributes are merged recursively, and stores, data sources and actions
+// replace the base elements with the same name.  The conflicts are the base values that
+// were changed or dropped by the merge.  Includes are not merged.
+func MergePayloads(base Payload, override Payload) (Payload, []PayloadDifference, error) {
+	merged := mergePayloads(base, override)
+	diffs, err := DiffPayloads(base, merged)
+	if err != nil {
+		return Payload{}, nil, err
+	}
+	conflicts := []PayloadDifference{}
+	for _, d := range diffs {
+		if d.Kind != PayloadValueAdded {
+			conflicts = append(conflicts, d)
+		}
+	}
+	return merged, conflicts, nil
+}
+
+// canonicalPayload returns the payload as a json document with the named payload lists
+// replaced by maps keyed by [name].  Empty values are removed.
+func canonicalPayload(p Payload) (map[string]any, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	doc := map[string]any{}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+	canonicalNamedLists(doc)
+	if actions, ok := doc["actions"].([]any); ok {
+		for _, action := range actions {
+			if a, ok := action.(map[string]any); ok {
+				canonicalNamedLists(a)
+			}
+		}
+	}
+	return doc, nil
+}
+
+func canonicalNamedLists(doc map[string]any) {
+	for key, val := range doc {
+		if isEmptyValue(val) {
+			delete(doc, key)
+		}
+	}
+	for _, key := range namedPayloadLists {
+		list, ok := doc[key].([]any)
+		if !ok {
+			continue
+		}
+		doc[key], _ = namedElements(list)
+	}
+}
+
+// namedElements keys list elements by [name], or by [index] for unnamed elements,
+// and returns the keys in list order
+func namedElements(list []any) (map[string]any, []string) {
+	named := make(map[string]any, len(list))
+	keys := make([]string, 0, len(list))
+	for i, element := range list {
+		name := fmt.Sprintf("[%d]", i)
+		if m, ok := element.(map[string]any); ok {
+			if n, ok := m["name"].(string); ok && n != "" {
+				name = "[" + n + "]"
+			}
+		}
+		if _, exists := named[name]; exists {
+			name = fmt.Sprintf("%s[%d]", name, i)
+		}
+		named[name] = element
+		keys = append(keys, name)
+	}
+	return named, keys
+}
+
+func isEmptyValue(val any) bool {
+	switch v := val.(type) {
+	case nil:
+		return true
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// diffValues adds the differences between two json values.  Maps are compared key by key
+// and other values, including lists, are compared as a whole.
+func diffValues(path string, a any, b any, diffs *[]PayloadDifference) {
+	mapA, okA := a.(map[string]any)
+	mapB, okB := b.(map[string]any)
+	if !okA || !okB {
+		if !reflect.DeepEqual(a, b) {
+			*diffs = append(*diffs, PayloadDifference{Path: path, Kind: PayloadValueChanged, Before: a, After: b})
+		}
+		return
+	}
+	keys := make(map[string]bool, len(mapA)+len(mapB))
+	for key := range mapA {
+		keys[key] = true
+	}
+	for key := range mapB {
+		keys[key] = true
+	}
+	for _, key := range sortedKeys(keys) {
+		childPath := key
+		switch {
+		case path == "":
+		case strings.HasPrefix(key, "["):
+			childPath = path + key
+		default:
+			childPath = path + "." + key
+		}
+		valA, inA := mapA[key]
+		valB, inB := mapB[key]
+		switch {
+		case !inA:
+			*diffs = append(*diffs, PayloadDifference{Path: childPath, Kind: PayloadValueAdded, After: valB})
+		case !inB:
+			*diffs = append(*diffs, PayloadDifference{Path: childPath, Kind: PayloadValueRemoved, Before: valA})
+		default:
+			diffValues(childPath, valA, valB, diffs)
+		}
+	}
+}
+
+func diffValueString(val any) string {
+	if s, ok := val.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return templateValue(val)
+}
@@ -0,0 +1,103 @@
+package cc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPayloadHashDiffMerge(t *testing.T) {
+	a, err := UnmarshalPayload([]byte(`{
+  "attributes": {"scenario": "2yr", "model": {"dt": 30, "solver": "implicit"}},
+  "stores": [{"name": "models", "store_type": "S3", "params": {"root": "/models"}},
+             {"name": "outputs", "store_type": "S3", "params": {"root": "/outputs"}}],
+  "inputs": [{"name": "terrain", "store_name": "models", "paths": {"default": "terrain.tif"}}],
+  "actions": [{"name": "compute", "attributes": {"threads": 4}}]
+}`), PayloadJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the same payload with stores and attributes in a different order
+	reordered, err := UnmarshalPayload([]byte(`{
+  "actions": [{"name": "compute", "attributes": {"threads": 4}}],
+  "inputs": [{"paths": {"default": "terrain.tif"}, "name": "terrain", "store_name": "models"}],
+  "stores": [{"name": "outputs", "store_type": "S3", "params": {"root": "/outputs"}},
+             {"name": "models", "store_type": "S3", "params": {"root": "/models"}}],
+  "attributes": {"model": {"solver": "implicit", "dt": 30.0}, "scenario": "2yr"}
+}`), PayloadJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashA, err := a.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashB, err := reordered.Hash(); err != nil || hashA != hashB {
+		t.Errorf("expected equal hashes for reordered payloads: %s %s %v", hashA, hashB, err)
+	}
+	if diffs, err := DiffPayloads(a, reordered); err != nil || len(diffs) != 0 {
+		t.Errorf("expected no differences, got %v %v", diffs, err)
+	}
+
+	//actions are run in order so reordered actions are a different payload
+	ab := Payload{Actions: []Action{{Name: "a"}, {Name: "b"}}}
+	ba := Payload{Actions: []Action{{Name: "b"}, {Name: "a"}}}
+	hashAB, err := ab.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashBA, err := ba.Hash(); err != nil || hashAB == hashBA {
+		t.Errorf("expected different hashes for reordered actions: %s %s %v", hashAB, hashBA, err)
+	}
+	if diffs, err := DiffPayloads(ab, ba); err != nil || len(diffs) != 1 || diffs[0].String() != `changed actions.order: "[a][b]" -> "[b][a]"` {
+		t.Errorf("expected an action order difference, got %v %v", diffs, err)
+	}
+
+	b, err := UnmarshalPayload([]byte(`{
+  "attributes": {"scenario": "5yr", "model": {"dt": 30}, "basin": "kanawha"},
+  "stores": [{"name": "models", "store_type": "S3", "params": {"root": "/models"}}],
+  "inputs": [{"name": "terrain", "store_name": "models", "paths": {"default": "terrain-v2.tif"}}],
+  "actions": [{"name": "compute", "attributes": {"threads": 8}}]
+}`), PayloadJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashB, _ := b.Hash(); hashA == hashB {
+		t.Error("expected different hashes for different payloads")
+	}
+	diffs, err := DiffPayloads(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`changed actions[compute].attributes.threads: 4 -> 8`,
+		`added attributes.basin: "kanawha"`,
+		`removed attributes.model.solver: "implicit"`,
+		`changed attributes.scenario: "2yr" -> "5yr"`,
+		`changed inputs[terrain].paths.default: "terrain.tif" -> "terrain-v2.tif"`,
+		`removed stores[outputs]: {"name":"outputs","params":{"root":"/outputs"},"store_type":"S3"}`,
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("expected %d differences, got %v", len(expected), diffs)
+	}
+	for i, d := range diffs {
+		if d.String() != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], d)
+		}
+	}
+
+	merged, conflicts, err := MergePayloads(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Stores) != 2 || merged.Attributes["basin"] != "kanawha" ||
+		merged.Attributes["model"].(map[string]any)["solver"] != "implicit" {
+		t.Errorf("unexpected merged payload: %+v", merged)
+	}
+	paths := []string{}
+	for _, c := range conflicts {
+		paths = append(paths, c.Path)
+	}
+	if strings.Join(paths, ",") != "actions[compute].attributes.threads,attributes.scenario,inputs[terrain].paths.default" {
+		t.Errorf("unexpected conflicts: %v", conflicts)
+	}
+}
@@ -0,0 +1,217 @@
+package cc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type PayloadFormat string
+
+const (
+	PayloadJson PayloadFormat = "json"
+	PayloadYaml PayloadFormat = "yaml"
+	PayloadToml PayloadFormat = "toml"
+)
+
+// payload files are searched in order.  the extensionless payload file is
+// the file written by cloud compute and takes precedence over hand authored files
+var payloadFileNames = []string{
+	payloadFileName,
+	payloadFileName + ".yaml",
+	payloadFileName + ".yml",
+	payloadFileName + ".toml",
+	payloadFileName + ".json",
+}
+
+// toml documents begin with a table header or a key/value assignment
+var tomlSniffRegex = regexp.MustCompile(`^(\[\[?[A-Za-z0-9_."-]+\]\]?|[A-Za-z0-9_"-]+\s*=)`)
+
+// PayloadFormatFromPath returns the payload format for a file extension
+func PayloadFormatFromPath(path string) (PayloadFormat, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return PayloadJson, true
+	case ".yaml", ".yml":
+		return PayloadYaml, true
+	case ".toml":
+		return PayloadToml, true
+	}
+	return "", false
+}
+
+// PayloadFormatFromEnv returns the payload format set in CC_PAYLOAD_FORMAT.
+// If the variable is not set an empty format is returned.
+func PayloadFormatFromEnv() (PayloadFormat, error) {
+	format := PayloadFormat(strings.ToLower(os.Getenv(CcPayloadFormat)))
+	switch format {
+	case "", PayloadJson, PayloadYaml, PayloadToml:
+		return format, nil
+	case "yml":
+		return PayloadYaml, nil
+	}
+	return "", fmt.Errorf("unsupported payload format: %s", format)
+}
+
+// DetectPayloadFormat guesses the format of a payload document
+func DetectPayloadFormat(data []byte) PayloadFormat {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		if line[0] == '{' {
+			return PayloadJson
+		}
+		if tomlSniffRegex.Match(line) {
+			return PayloadToml
+		}
+		return PayloadYaml
+	}
+	return PayloadJson
+}
+
+// MarshalPayload writes a payload in the requested format.  Formatted only applies
+// to json payloads, yaml and toml payloads are always indented.
+func MarshalPayload(p Payload, format PayloadFormat, formatted bool) ([]byte, error) {
+	switch format {
+	case PayloadJson, "":
+		if formatted {
+			return json.MarshalIndent(p, "", "  ")
+		}
+		return json.Marshal(p)
+	case PayloadYaml:
+		buf := bytes.Buffer{}
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(p); err != nil {
+			return nil, err
+		}
+		err := encoder.Close()
+		return buf.Bytes(), err
+	case PayloadToml:
+		buf := bytes.Buffer{}
+		err := toml.NewEncoder(&buf).Encode(p)
+		return buf.Bytes(), err
+	}
+	return nil, fmt.Errorf("unsupported payload format: %s", format)
+}
+
+// UnmarshalPayload reads a payload in the requested format.
+// If the format is empty it is detected from the document.
+func UnmarshalPayload(data []byte, format PayloadFormat) (Payload, error) {
+	payload := Payload{}
+	if format == "" {
+		format = DetectPayloadFormat(data)
+	}
+	var err error
+	switch format {
+	case PayloadJson:
+		err = json.Unmarshal(data, &payload)
+	case PayloadYaml:
+		err = yaml.Unmarshal(data, &payload)
+	case PayloadToml:
+		err = toml.Unmarshal(data, &payload)
+	default:
+		err = fmt.Errorf("unsupported payload format: %s", format)
+	}
+	return payload, err
+}
+
+// readPayload unmarshals a payload file.  The format is taken from the file extension,
+// then CC_PAYLOAD_FORMAT, and is detected from the document if neither is set.
+func readPayload(path string, data []byte) (Payload, error) {
+	format, ok := PayloadFormatFromPath(path)
+	if !ok {
+		var err error
+		format, err = PayloadFormatFromEnv()
+		if err != nil {
+			return Payload{}, err
+		}
+	}
+	payload, err := UnmarshalPayload(data, format)
+	if err != nil {
+		return payload, fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	return payload, nil
+}
+
+// writePayload marshals a payload in the CC_PAYLOAD_FORMAT format (json by default).
+// Resolved secret values are written as their {SECRET::name} template.
+func writePayload(p Payload) ([]byte, error) {
+	format, err := PayloadFormatFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	p, err = redactPayload(p)
+	if err != nil {
+		return nil, err
+	}
+	_, shouldFormat := os.LookupEnv(CcPayloadFormatted)
+	data, err := MarshalPayload(p, format, shouldFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	return data, nil
+}
+
+// redactPayload returns a copy of the payload with the resolved secret values redacted
+func redactPayload(p Payload) (Payload, error) {
+	if resolvedSecrets.empty() {
+		return p, nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return p, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	var doc any
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return p, fmt.Errorf("failed to redact payload: %w", err)
+	}
+	data, err = json.Marshal(redactValue(doc))
+	if err != nil {
+		return p, fmt.Errorf("failed to redact payload: %w", err)
+	}
+	return UnmarshalPayload(data, PayloadJson)
+}
+
+// UnmarshalYAML decodes yaml attributes with the same value types as json attributes
+// (float64 numbers, []any and map[string]any) so attribute handling does not depend
+// on the payload format
+func (p *PayloadAttributes) UnmarshalYAML(value *yaml.Node) error {
+	attrs := map[string]any{}
+	if err := value.Decode(&attrs); err != nil {
+		return err
+	}
+	return p.normalize(attrs)
+}
+
+// UnmarshalTOML decodes toml attributes with the same value types as json attributes.
+// toml datetimes become RFC 3339 strings.
+func (p *PayloadAttributes) UnmarshalTOML(data any) error {
+	attrs, ok := data.(map[string]any)
+	if !ok {
+		return fmt.Errorf("invalid attributes: expected a table, got %T", data)
+	}
+	return p.normalize(attrs)
+}
+
+func (p *PayloadAttributes) normalize(attrs map[string]any) error {
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+	normalized := map[string]any{}
+	if err = json.Unmarshal(data, &normalized); err != nil {
+		return err
+	}
+	*p = normalized
+	return nil
+}
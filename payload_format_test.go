@@ -0,0 +1,146 @@
+package cc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+const yamlTestPayload = `# hand authored payload
+attributes:
+  scenario: base
+  timestep: 60
+  gages: [kanawha, elk]
+stores:
+  - name: local
+    store_type: FS
+    params:
+      root: /data
+inputs:
+  - name: terrain
+    store_name: local
+    paths:
+      default: terrain/dem.tif
+outputs: []
+actions:
+  - name: compute
+    attributes:
+      tolerance: 0.01
+      options:
+        verbose: true
+    inputs: []
+    outputs: []
+`
+
+const tomlTestPayload = `inputs = []
+outputs = []
+
+[attributes]
+scenario = "base"
+timestep = 60
+gages = ["kanawha", "elk"]
+
+[[stores]]
+name = "local"
+store_type = "FS"
+[stores.params]
+root = "/data"
+
+[[actions]]
+name = "compute"
+inputs = []
+outputs = []
+[actions.attributes]
+tolerance = 0.01
+[actions.attributes.options]
+verbose = true
+`
+
+func TestPayloadFormats(t *testing.T) {
+	yamlPayload, err := UnmarshalPayload([]byte(yamlTestPayload), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tomlPayload, err := UnmarshalPayload([]byte(tomlTestPayload), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//attribute values have the json types regardless of format
+	expected := PayloadAttributes{"scenario": "base", "timestep": 60.0, "gages": []any{"kanawha", "elk"}}
+	for name, p := range map[string]Payload{"yaml": yamlPayload, "toml": tomlPayload} {
+		if !reflect.DeepEqual(p.Attributes, expected) {
+			t.Errorf("unexpected %s attributes: %#v", name, p.Attributes)
+		}
+		if len(p.Stores) != 1 || p.Stores[0].StoreType != FSB || p.Stores[0].Parameters["root"] != "/data" {
+			t.Errorf("unexpected %s stores: %+v", name, p.Stores)
+		}
+		if p.Actions[0].Name != "compute" || p.Actions[0].Attributes["options"].(map[string]any)["verbose"] != true {
+			t.Errorf("unexpected %s actions: %+v", name, p.Actions)
+		}
+	}
+
+	//round trip through every format
+	id := uuid.New()
+	yamlPayload.Inputs[0].ID = &id
+	for _, format := range []PayloadFormat{PayloadJson, PayloadYaml, PayloadToml} {
+		data, err := MarshalPayload(yamlPayload, format, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if detected := DetectPayloadFormat(data); detected != format {
+			t.Errorf("detected %s for a %s payload", detected, format)
+		}
+		p, err := UnmarshalPayload(data, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p.Attributes, yamlPayload.Attributes) || !reflect.DeepEqual(p.Stores, yamlPayload.Stores) ||
+			!reflect.DeepEqual(p.Inputs, yamlPayload.Inputs) || len(p.Outputs) != 0 ||
+			!reflect.DeepEqual(p.Actions[0].Attributes, yamlPayload.Actions[0].Attributes) {
+			t.Errorf("%s round trip changed the payload:\n%s", format, data)
+		}
+	}
+}
+
+func TestFSBYamlPayload(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(FsbRootPath, root)
+	t.Setenv(CcPayloadFormat, "")
+	err := os.MkdirAll(filepath.Join(root, "payload-1"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(root, "payload-1", "payload.yml"), []byte(yamlTestPayload), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFSBCcStore("manifest-1", "payload-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := store.GetPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.Inputs[0].Paths["default"] != "terrain/dem.tif" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+
+	//the payload is written in the CC_PAYLOAD_FORMAT format
+	t.Setenv(CcPayloadFormat, "toml")
+	err = store.SetPayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "payload-1", payloadFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if DetectPayloadFormat(data) != PayloadToml {
+		t.Errorf("expected a toml payload:\n%s", data)
+	}
+}
@@ -0,0 +1,226 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Payloads can be composed from shared fragments.  A payload lists fragment paths in "includes"
+// and the fragments are loaded from the CcStore (see LoadIncludes).  Fragments are payload
+// documents and can include other fragments.  When the payload is resolved:
+//
+//   - later includes take precedence over earlier includes and the payload takes
+//     precedence over all of its includes
+//   - attributes are merged by key.  Nested attribute maps are merged recursively
+//   - stores, store profiles, data sources and actions are merged by name.  A definition
+//     replaces an included definition with the same name.  Definitions with the same name
+//     in one document are kept, they are never merged with each other
+//
+// Actions take precedence over the payload through the action IOManager parent lookups:
+// action stores, data sources and attributes are used before the payload values.
+//
+// Stores can extend a named store profile from "store_profiles".  The profile store type,
+// credential profile and parameters are defaults for the store.  Profiles can not extend other profiles.
+
+const maxIncludeDepth = 10
+
+// PayloadIncludeReader reads the payload fragments referenced by payload includes.
+// Include paths are relative to the store root.
+type PayloadIncludeReader interface {
+	GetPayloadInclude(path string) (Payload, error)
+}
+
+// LoadIncludes loads the payload includes, and the includes of the includes, from a store.
+// Include paths can contain {ENV::} and {CC::} templates.
+func (p *Payload) LoadIncludes(store CcStore) error {
+	if len(p.Includes) == 0 {
+		return nil
+	}
+	reader, ok := store.(PayloadIncludeReader)
+	if !ok {
+		return errors.New("cc store does not implement a PayloadIncludeReader")
+	}
+	return p.loadIncludes(reader, []string{})
+}
+
+func (p *Payload) loadIncludes(reader PayloadIncludeReader, chain []string) error {
+	if len(chain) > maxIncludeDepth {
+		return fmt.Errorf("payload includes exceed the maximum depth of %d: %v", maxIncludeDepth, chain)
+	}
+	p.included = make([]Payload, len(p.Includes))
+	for i, include := range p.Includes {
+		path, err := substituteTemplate(include, envTemplateResolver)
+		if err != nil {
+			return fmt.Errorf("invalid payload include %s: %w", include, err)
+		}
+		for _, c := range chain {
+			if c == path {
+				return fmt.Errorf("payload include cycle: %v -> %s", chain, path)
+			}
+		}
+		fragment, err := reader.GetPayloadInclude(path)
+		if err != nil {
+			return fmt.Errorf("failed to load payload include %s: %w", path, err)
+		}
+		err = fragment.loadIncludes(reader, append(chain[:len(chain):len(chain)], path))
+		if err != nil {
+			return err
+		}
+		p.included[i] = fragment
+	}
+	return nil
+}
+
+// Resolve returns the payload merged with its loaded includes and with store profiles
+// applied to the payload and action stores.  The resolved payload does not have
+// includes or store profiles.  A payload without includes is not merged.
+// The stores and actions of the payload are not modified.
+func (p Payload) Resolve() (Payload, error) {
+	resolved := p
+	if len(p.Includes) == 0 {
+		resolved.Stores = slices.Clone(p.Stores)
+		resolved.Actions = slices.Clone(p.Actions)
+	} else {
+		var err error
+		resolved, err = p.merge()
+		if err != nil {
+			return Payload{}, err
+		}
+	}
+
+	profiles := map[string]DataStore{}
+	for _, profile := range resolved.StoreProfiles {
+		profiles[profile.Name] = profile
+	}
+	if err := applyStoreProfiles(resolved.Stores, profiles, "stores"); err != nil {
+		return Payload{}, err
+	}
+	for i := range resolved.Actions {
+		path := fmt.Sprintf("actions[%d].stores", i)
+		resolved.Actions[i].Stores = slices.Clone(resolved.Actions[i].Stores)
+		if err := applyStoreProfiles(resolved.Actions[i].Stores, profiles, path); err != nil {
+			return Payload{}, err
+		}
+	}
+	resolved.StoreProfiles = nil
+	return resolved, nil
+}
+
+// merge merges the payload over its includes.  Store profiles are merged but not applied
+// so that included stores can extend profiles defined by the including payload.
+func (p Payload) merge() (Payload, error) {
+	if len(p.included) != len(p.Includes) {
+		return Payload{}, errors.New("payload includes have not been loaded")
+	}
+	merged := Payload{}
+	for i, fragment := range p.included {
+		m, err := fragment.merge()
+		if err != nil {
+			return Payload{}, fmt.Errorf("payload include %s: %w", p.Includes[i], err)
+		}
+		merged = mergePayloads(merged, m)
+	}
+	return mergePayloads(merged, p), nil
+}
+
+// mergePayloads merges the override payload over the base payload
+func mergePayloads(base Payload, override Payload) Payload {
+	merged := Payload{
+		IOManager: IOManager{
+			Attributes: mergeAttributes(base.Attributes, override.Attributes),
+			Stores:     mergeByName(base.Stores, override.Stores, storeName),
+			Inputs:     mergeByName(base.Inputs, override.Inputs, dataSourceName),
+			Outputs:    mergeByName(base.Outputs, override.Outputs, dataSourceName),
+		},
+		StoreProfiles: mergeByName(base.StoreProfiles, override.StoreProfiles, storeName),
+		Actions:       mergeByName(base.Actions, override.Actions, func(a Action) string { return a.Name }),
+	}
+	return merged
+}
+
+func storeName(ds DataStore) string {
+	return ds.Name
+}
+
+func dataSourceName(ds DataSource) string {
+	return ds.Name
+}
+
+// mergeByName replaces base elements with override elements of the same name and
+// appends the remaining override elements.  Each base element is replaced at most once
+// and override elements are never merged with each other, so same name elements within
+// one document are all kept.  Unnamed elements are always appended.
+func mergeByName[T any](base []T, override []T, name func(T) string) []T {
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := make([]T, 0, len(base)+len(override))
+	merged = append(merged, base...)
+	replacedBase := make([]bool, len(base))
+	for _, o := range override {
+		replaced := false
+		if n := name(o); n != "" {
+			for i := range base {
+				if !replacedBase[i] && name(base[i]) == n {
+					merged[i] = o
+					replacedBase[i] = true
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			merged = append(merged, o)
+		}
+	}
+	return merged
+}
+
+// mergeAttributes merges override attributes over base attributes.  Nested maps are merged recursively.
+func mergeAttributes(base PayloadAttributes, override PayloadAttributes) PayloadAttributes {
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := maps.Clone(base)
+	if merged == nil {
+		merged = PayloadAttributes{}
+	}
+	for k, v := range override {
+		baseMap, baseOk := merged[k].(map[string]any)
+		overrideMap, overrideOk := v.(map[string]any)
+		if baseOk && overrideOk {
+			merged[k] = map[string]any(mergeAttributes(baseMap, overrideMap))
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// applyStoreProfiles sets the defaults of stores that extend a store profile
+func applyStoreProfiles(stores []DataStore, profiles map[string]DataStore, path string) error {
+	for i, store := range stores {
+		if store.Extends == "" {
+			continue
+		}
+		profile, ok := profiles[store.Extends]
+		if !ok {
+			return fmt.Errorf("%s[%d]: store profile %s is not defined", path, i, store.Extends)
+		}
+		if profile.Extends != "" {
+			return fmt.Errorf("store profile %s can not extend store profile %s", profile.Name, profile.Extends)
+		}
+		if store.StoreType == "" {
+			store.StoreType = profile.StoreType
+		}
+		if store.DsProfile == "" {
+			store.DsProfile = profile.DsProfile
+		}
+		store.Parameters = mergeAttributes(profile.Parameters, store.Parameters)
+		store.Extends = ""
+		stores[i] = store
+	}
+	return nil
+}
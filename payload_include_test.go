@@ -0,0 +1,156 @@
+package cc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPayloadIncludes(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(FsbRootPath, root)
+	t.Setenv("CC_TEST_SHARED", "shared")
+	files := map[string]string{
+		"shared/stores.yaml": `
+store_profiles:
+  - name: model-library
+    store_type: S3
+    profile: MODEL_LIBRARY
+    params:
+      root: /models
+      region: us-east-1
+stores:
+  - name: models
+    extends: model-library
+attributes:
+  scenario: base
+  model:
+    dt: 30
+    solver: implicit
+`,
+		"shared/kanawha.json": `{
+  "includes": ["shared/stores.yaml"],
+  "attributes": {"basin": "kanawha", "scenario": "kanawha-base"},
+  "inputs": [{"name": "terrain", "store_name": "models", "paths": {"default": "terrain.tif"}}]
+}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := NewFSBCcStore("manifest", "payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := UnmarshalPayload([]byte(`{
+  "includes": ["{ENV::CC_TEST_SHARED}/kanawha.json"],
+  "attributes": {"scenario": "2yr", "model": {"dt": 10}},
+  "stores": [{"name": "outputs", "extends": "model-library", "params": {"root": "/outputs"}}],
+  "actions": [{"name": "compute", "stores": [{"name": "scratch", "extends": "model-library"}]}]
+}`), PayloadJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = payload.Resolve(); err == nil {
+		t.Error("expected an error resolving a payload without loaded includes")
+	}
+	if err = payload.LoadIncludes(store); err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := payload.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resolved.Includes) != 0 || len(resolved.StoreProfiles) != 0 {
+		t.Errorf("expected includes and profiles to be resolved: %+v", resolved)
+	}
+	model := resolved.Attributes["model"].(map[string]any)
+	if resolved.Attributes["scenario"] != "2yr" || resolved.Attributes["basin"] != "kanawha" ||
+		model["dt"] != 10.0 || model["solver"] != "implicit" {
+		t.Errorf("unexpected attributes: %v", resolved.Attributes)
+	}
+	if len(resolved.Stores) != 2 || len(resolved.Inputs) != 1 {
+		t.Fatalf("unexpected stores and inputs: %+v %+v", resolved.Stores, resolved.Inputs)
+	}
+	models, outputs := resolved.Stores[0], resolved.Stores[1]
+	if models.StoreType != FSS3 || models.DsProfile != "MODEL_LIBRARY" || models.Parameters["root"] != "/models" {
+		t.Errorf("unexpected profile store: %+v", models)
+	}
+	if outputs.Parameters["root"] != "/outputs" || outputs.Parameters["region"] != "us-east-1" || outputs.Extends != "" {
+		t.Errorf("unexpected extended store: %+v", outputs)
+	}
+	if scratch := resolved.Actions[0].Stores[0]; scratch.StoreType != FSS3 {
+		t.Errorf("unexpected action store: %+v", scratch)
+	}
+	if payload.Actions[0].Stores[0].Extends != "model-library" {
+		t.Error("expected the unresolved payload to be unchanged")
+	}
+
+	//include cycles are errors
+	cycle := filepath.Join(root, "shared/cycle.yaml")
+	if err = os.WriteFile(cycle, []byte("includes: [shared/cycle.yaml]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	payload = Payload{Includes: []string{"shared/cycle.yaml"}}
+	if err = payload.LoadIncludes(store); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected an include cycle error, got %v", err)
+	}
+
+	//same name actions in one payload are kept.  an included action is replaced once
+	computes := []Action{
+		{Name: "compute", IOManager: IOManager{Attributes: PayloadAttributes{"plan": "p01"}}},
+		{Name: "compute", IOManager: IOManager{Attributes: PayloadAttributes{"plan": "p02"}}},
+	}
+	payload = Payload{Actions: computes}
+	if resolved, err = payload.Resolve(); err != nil || len(resolved.Actions) != 2 {
+		t.Errorf("expected both compute actions: %+v %v", resolved.Actions, err)
+	}
+	merged := mergePayloads(Payload{Actions: []Action{{Name: "compute"}, {Name: "extract"}}}, payload)
+	if len(merged.Actions) != 3 || merged.Actions[0].Attributes["plan"] != "p01" ||
+		merged.Actions[1].Name != "extract" || merged.Actions[2].Attributes["plan"] != "p02" {
+		t.Errorf("unexpected merged actions: %+v", merged.Actions)
+	}
+	changed := Payload{Actions: []Action{{Name: "compute", IOManager: IOManager{Attributes: PayloadAttributes{"plan": "p03"}}}, computes[1]}}
+	h1, err := payload.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := changed.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 == h2 {
+		t.Error("expected payloads with different duplicate actions to hash differently")
+	}
+
+	//resolving and hashing a payload without includes does not modify it
+	payload = Payload{
+		StoreProfiles: []DataStore{{Name: "library", StoreType: FSS3, Parameters: PayloadAttributes{"root": "/models"}}},
+		IOManager:     IOManager{Stores: []DataStore{{Name: "models", Extends: "library"}}},
+		Actions:       []Action{{Name: "compute", IOManager: IOManager{Stores: []DataStore{{Name: "scratch", Extends: "library"}}}}},
+	}
+	if _, err = payload.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = payload.Hash(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []DataStore{payload.Stores[0], payload.Actions[0].Stores[0]} {
+		if s.Extends != "library" || s.StoreType != "" || s.Parameters != nil {
+			t.Errorf("expected the payload store to be unchanged: %+v", s)
+		}
+	}
+
+	payload = Payload{IOManager: IOManager{Stores: []DataStore{{Name: "x", Extends: "missing"}}}}
+	if _, err = payload.Resolve(); err == nil {
+		t.Error("expected an error for an undefined store profile")
+	}
+}
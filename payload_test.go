@@ -0,0 +1,203 @@
+package cc
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	filestore "github.com/usace/filesapi"
+)
+
+func testFileIOManager(t *testing.T) (*IOManager, string) {
+	t.Helper()
+	root := t.TempDir()
+	ds := DataStore{Name: "local", StoreType: FSB, Parameters: PayloadAttributes{"root": root}}
+	session, err := (&FileDataStore[filestore.BlockFS]{}).Connect(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds.Session = session
+	im := &IOManager{
+		Stores: []DataStore{ds},
+		Inputs: []DataSource{{
+			Name:      "depths",
+			StoreName: "local",
+			Paths:     map[string]string{"default": "outputs/{VAR::realization}/depth.csv"},
+		}},
+		Outputs: []DataSource{{
+			Name:      "depths",
+			StoreName: "local",
+			Paths:     map[string]string{"default": "outputs/{VAR::realization}/depth.csv"},
+		}},
+	}
+	return im, root
+}
+
+func TestTemplateVarOperations(t *testing.T) {
+	im, root := testFileIOManager(t)
+	vars := map[string]string{"realization": "12"}
+	input := DataSourceOpInput{DataSourceName: "depths", PathKey: "default", TemplateVars: vars}
+
+	_, err := im.Put(PutOpInput{SrcReader: strings.NewReader("1.5,2.5"), DataSourceOpInput: input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "outputs/12/depth.csv")); err != nil {
+		t.Fatalf("expected the realization path to be written: %s", err)
+	}
+
+	data, err := im.Get(input)
+	if err != nil || string(data) != "1.5,2.5" {
+		t.Errorf("unexpected data: %q %v", data, err)
+	}
+
+	localPath := filepath.Join(t.TempDir(), "depth.csv")
+	err = im.CopyToLocal(input, localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = im.CopyFileToRemote(CopyFileToRemoteInput{
+		RemoteDsName: "depths",
+		DsPathKey:    "default",
+		LocalPath:    localPath,
+		TemplateVars: map[string]string{"realization": "13"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "outputs/13/depth.csv")); err != nil {
+		t.Errorf("expected the copied file to be written: %s", err)
+	}
+
+	//unresolved template variables are errors
+	input.TemplateVars = nil
+	_, err = im.Put(PutOpInput{SrcReader: strings.NewReader("x"), DataSourceOpInput: input})
+	if err == nil || !strings.Contains(err.Error(), "{VAR::realization}") {
+		t.Errorf("expected an unresolved template error, got %v", err)
+	}
+	if err = im.CopyFileToLocal("depths", "default", "", localPath); err == nil {
+		t.Error("expected an unresolved template error")
+	}
+}
+
+func TestExpandPaths(t *testing.T) {
+	im, _ := testFileIOManager(t)
+	for _, p := range []string{"events/7/hydrographs/elk.csv", "events/7/hydrographs/coal.csv", "events/7/hydrographs/upper/gauley.csv", "events/7/stage.csv"} {
+		_, err := im.Put(PutOpInput{
+			SrcReader:         strings.NewReader(p),
+			DataSourceOpInput: DataSourceOpInput{DataSource: &DataSource{Name: "events", StoreName: "local", Paths: map[string]string{"default": p}}, PathKey: "default"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	//sidecar metadata is not listed
+	if err := im.Stores[0].Session.(MetadataStore).PutMetadata("run", 1); err != nil {
+		t.Fatal(err)
+	}
+	listing, err := im.Stores[0].Session.(StoreLister).List("")
+	if err != nil || len(listing) != 4 || listing[3] != "events/7/stage.csv" {
+		t.Errorf("unexpected store listing: %v %v", listing, err)
+	}
+	im.Inputs = append(im.Inputs, DataSource{
+		Name:      "hydrographs",
+		StoreName: "local",
+		Paths: map[string]string{
+			"csv":    "/events/{VAR::event}/hydrographs/*.csv",
+			"all":    "events/{VAR::event}/",
+			"single": "events/{VAR::event}/stage.csv",
+		},
+	})
+	input := DataSourceOpInput{DataSourceName: "hydrographs", PathKey: "csv", TemplateVars: map[string]string{"event": "7"}}
+
+	paths, err := im.ExpandPaths(input)
+	if err != nil || len(paths) != 2 || paths[0] != "/events/7/hydrographs/coal.csv" {
+		t.Fatalf("unexpected paths: %v %v", paths, err)
+	}
+
+	contents := []string{}
+	err = im.ForEachReader(input, func(path string, reader io.Reader) error {
+		data, err := io.ReadAll(reader)
+		contents = append(contents, string(data))
+		return err
+	})
+	if err != nil || len(contents) != 2 || contents[1] != "events/7/hydrographs/elk.csv" {
+		t.Errorf("unexpected reader contents: %v %v", contents, err)
+	}
+
+	input.PathKey = "all"
+	localDir := t.TempDir()
+	localPaths, err := im.CopyDataSourceToLocal(input, localDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(localPaths) != 4 || localPaths[0] != filepath.Join(localDir, "hydrographs/coal.csv") {
+		t.Errorf("unexpected local paths: %v", localPaths)
+	}
+	if _, err := os.Stat(filepath.Join(localDir, "hydrographs/upper/gauley.csv")); err != nil {
+		t.Error(err)
+	}
+
+	input.PathKey = "single"
+	localPaths, err = im.CopyDataSourceToLocal(input, localDir)
+	if err != nil || len(localPaths) != 1 || localPaths[0] != filepath.Join(localDir, "stage.csv") {
+		t.Errorf("unexpected local paths: %v %v", localPaths, err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	im, root := testFileIOManager(t)
+	err := os.MkdirAll(filepath.Join(root, "inputs"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"geometry.g01": "geometry", "plan.p01": "plan"} {
+		if err = os.WriteFile(filepath.Join(root, "inputs", name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	im.Inputs = append(im.Inputs, DataSource{
+		Name:      "model",
+		StoreName: "local",
+		Paths:     map[string]string{"geometry": "inputs/geometry.g01", "plan": "inputs/plan.p01", "unmatched": "inputs/x"},
+	})
+	im.Outputs = append(im.Outputs, DataSource{
+		Name:      "archive",
+		StoreName: "local",
+		Paths:     map[string]string{"geometry": "archive/{VAR::run}/geometry.g01", "plan": "archive/{VAR::run}/plan.p01"},
+	})
+
+	//copy from an input to an output
+	result, err := im.Copy(
+		DataSourceOpInput{DataSourceName: "model", PathKey: "geometry"},
+		DataSourceOpInput{DataSourceName: "archive", PathKey: "geometry", TemplateVars: map[string]string{"run": "1"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//sha256 of "geometry"
+	if result.Bytes != 8 || result.Sha256 != "8148b7fefaf89ebf8e7951994d2601d731220c5460faecc64c69d12bb7ff3213" ||
+		result.DestinationPath != "archive/1/geometry.g01" {
+		t.Errorf("unexpected copy result: %+v", result)
+	}
+
+	results, err := im.CopyAll(
+		DataSourceOpInput{DataSourceName: "model"},
+		DataSourceOpInput{DataSourceName: "archive", TemplateVars: map[string]string{"run": "2"}},
+	)
+	if err != nil || len(results) != 2 || results[1].SourcePath != "inputs/plan.p01" {
+		t.Fatalf("unexpected copy results: %+v %v", results, err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "archive/2/plan.p01"))
+	if err != nil || string(data) != "plan" {
+		t.Errorf("unexpected copied data: %q %v", data, err)
+	}
+
+	_, err = im.CopyAll(DataSourceOpInput{DataSourceName: "model"}, DataSourceOpInput{DataSourceName: "archive"}, "unmatched")
+	if err == nil {
+		t.Error("expected an error copying an undefined destination path key")
+	}
+}
@@ -0,0 +1,207 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ValidationError describes a single problem in a payload.
+// Path is the JSON path of the invalid element, for example actions[2].inputs[0].store_name
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (ve ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", ve.Path, ve.Message)
+}
+
+// ValidationErrors is the list of all problems found while validating a payload
+type ValidationErrors []ValidationError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, len(ve))
+	for i, err := range ve {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid payload (%d errors):\n  %s", len(ve), strings.Join(msgs, "\n  "))
+}
+
+func (ve *ValidationErrors) add(path string, msg string, args ...any) {
+	*ve = append(*ve, ValidationError{path, fmt.Sprintf(msg, args...)})
+}
+
+// Validate checks the payload for invalid store references, duplicate store and
+// data source names, unregistered store types, unresolvable {ENV::} and {ATTR::}
+// templates, attributes that violate the registered AttributeConstraints, actions
+// without a registered action runner and undefined or cyclic action dependencies.
+// All problems are returned together as ValidationErrors.  A valid payload returns nil.
+//
+// Actions are only checked for a registered runner once a runner or factory has been
+// registered.  Plugins that dispatch pm.Actions themselves register nothing, and RunActions
+// checks every action for a runner before it runs any of them.
+func (p *Payload) Validate() error {
+	registerStoreTypes()
+	errs := ValidationErrors{}
+	p.IOManager.validate("", nil, nil, &errs)
+	checkRegisteredConstraints("attributes", p.Attributes, &errs)
+
+	for i, action := range p.Actions {
+		path := fmt.Sprintf("actions[%d]", i)
+		if action.Name == "" {
+			errs.add(path+".name", "action name is required")
+		}
+		action.IOManager.validate(path+".", &p.IOManager, p.Attributes, &errs)
+		checkRegisteredConstraints(path+".attributes", action.Attributes, &errs)
+		for j, dep := range action.DependsOn {
+			if !slices.ContainsFunc(p.Actions, func(a Action) bool { return a.Name == dep }) {
+				errs.add(fmt.Sprintf("%s.depends_on[%d]", path, j), "action %s is not defined", dep)
+			}
+		}
+	}
+	if cycle := actionCycle(p.Actions, actionDependencyIndices(p.Actions)); cycle != nil {
+		errs.add("actions", "dependency cycle %s", strings.Join(cycle, " -> "))
+	}
+	if hasRegisteredActions() {
+		validateActionRunners(p.Actions, &errs)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateActionRunners checks that every named action has a registered runner or factory
+func validateActionRunners(actions []Action, errs *ValidationErrors) {
+	for i, action := range actions {
+		if action.Name != "" && !isActionRegistered(action.Name) {
+			errs.add(fmt.Sprintf("actions[%d].name", i), "no action runner is registered for %s", action.Name)
+		}
+	}
+}
+
+// validate checks an IOManager.  Action IOManagers are validated with the payload IOManager
+// as the parent so that store references and attribute templates can resolve to payload values
+func (im *IOManager) validate(prefix string, parent *IOManager, parentAttrs PayloadAttributes, errs *ValidationErrors) {
+	stores := map[string]bool{}
+	if parent != nil {
+		for _, store := range parent.Stores {
+			stores[store.Name] = true
+		}
+	}
+
+	names := map[string]int{}
+	for i, store := range im.Stores {
+		path := fmt.Sprintf("%sstores[%d]", prefix, i)
+		if store.Name == "" {
+			errs.add(path+".name", "store name is required")
+		} else if first, ok := names[store.Name]; ok {
+			errs.add(path+".name", "duplicate store name %s (also used by %sstores[%d])", store.Name, prefix, first)
+		} else {
+			names[store.Name] = i
+		}
+		stores[store.Name] = true
+		if _, ok := DataStoreTypeRegistry[store.StoreType]; !ok {
+			errs.add(path+".store_type", "unknown store type %q", store.StoreType)
+		}
+	}
+
+	//templates in action attributes resolve against the payload attributes.
+	//templates in data sources resolve against the payload and action attributes
+	attrSub := parent != nil
+	validateParamTemplates(prefix+"attributes", im.Attributes, parentAttrs, attrSub, errs)
+	dsAttrs := PayloadAttributes{}
+	for k, v := range parentAttrs {
+		dsAttrs[k] = v
+	}
+	for k, v := range im.Attributes {
+		dsAttrs[k] = v
+	}
+
+	for _, io := range []struct {
+		name    string
+		sources []DataSource
+	}{{"inputs", im.Inputs}, {"outputs", im.Outputs}} {
+		names := map[string]int{}
+		for i, ds := range io.sources {
+			path := fmt.Sprintf("%s%s[%d]", prefix, io.name, i)
+			if ds.Name == "" {
+				errs.add(path+".name", "data source name is required")
+			} else if first, ok := names[ds.Name]; ok {
+				errs.add(path+".name", "duplicate data source name %s (also used by %s%s[%d])", ds.Name, prefix, io.name, first)
+			} else {
+				names[ds.Name] = i
+			}
+			validateTemplate(path+".name", ds.Name, dsAttrs, true, true, errs)
+
+			if ds.StoreName == "" {
+				errs.add(path+".store_name", "store name is required")
+			} else if !stores[ds.StoreName] {
+				errs.add(path+".store_name", "store %s is not defined", ds.StoreName)
+			}
+			for _, key := range sortedKeys(ds.Paths) {
+				if ds.Paths[key] == "" {
+					errs.add(fmt.Sprintf("%s.paths.%s", path, key), "path is empty")
+				}
+				validateTemplate(fmt.Sprintf("%s.paths.%s", path, key), ds.Paths[key], dsAttrs, true, true, errs)
+			}
+			for _, key := range sortedKeys(ds.DataPaths) {
+				validateTemplate(fmt.Sprintf("%s.data_paths.%s", path, key), ds.DataPaths[key], dsAttrs, true, true, errs)
+			}
+		}
+	}
+}
+
+func validateParamTemplates(path string, params map[string]any, attrs PayloadAttributes, attrSub bool, errs *ValidationErrors) {
+	for _, key := range sortedKeys(params) {
+		validateParamTemplate(path+"."+key, params[key], attrs, attrSub, errs)
+	}
+}
+
+func validateParamTemplate(path string, param any, attrs PayloadAttributes, attrSub bool, errs *ValidationErrors) {
+	switch val := param.(type) {
+	case string:
+		validateTemplate(path, val, attrs, attrSub, false, errs)
+	case map[string]any:
+		validateParamTemplates(path, val, attrs, attrSub, errs)
+	case []any:
+		for i, v := range val {
+			validateParamTemplate(fmt.Sprintf("%s[%d]", path, i), v, attrs, attrSub, errs)
+		}
+	}
+}
+
+// validateTemplate checks that the {ENV::}, {CC::} and {ATTR::} templates in a value can be resolved.
+// Strict templates (data source names and paths) must also be well formed.
+func validateTemplate(path string, template string, attrs PayloadAttributes, attrSub bool, strict bool, errs *ValidationErrors) {
+	_, err := substituteTemplate(template, func(kind string, name string) (string, bool, error) {
+		if kind == templateAttr {
+			if !attrSub {
+				return "", false, fmt.Errorf("unresolved template {ATTR::%s}: attribute templates are not supported here", name)
+			}
+			val, ok := lookupAttribute(attrs, name)
+			return templateValue(val), ok, nil
+		}
+		return envTemplateResolver(kind, name)
+	})
+	if err != nil {
+		var syntaxErr *TemplateSyntaxError
+		if errors.As(err, &syntaxErr) && !strict {
+			return
+		}
+		errs.add(path, "%s", err)
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,79 @@
+package cc
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const invalidTestPayload = `{
+	"attributes": {"scenario": "base", "model_dir": "{ENV::CC_TEST_UNSET_VAR}"},
+	"stores": [
+		{"name": "models", "store_type": "S3", "params": {"root": "/models"}},
+		{"name": "models", "store_type": "FTP"}
+	],
+	"inputs": [
+		{"name": "terrain", "store_name": "models", "paths": {"default": "{ATTR::scenario}/dem.tif"}},
+		{"name": "terrain", "store_name": "model", "paths": {"default": "{ATTR::missing}/dem.tif"}}
+	],
+	"outputs": [],
+	"actions": [
+		{
+			"name": "compute",
+			"attributes": {"run": "{ATTR::scenario}", "files": ["{ATTR::scenario}.csv", "{ATTR::unknown}.csv"]},
+			"inputs": [{"name": "grid", "store_name": "models", "paths": {"default": "{ATTR::run}/grid.tif"}}],
+			"outputs": [{"name": "results", "store_name": "outputs", "paths": {"default": ""}}]
+		}
+	]
+}`
+
+func TestPayloadValidate(t *testing.T) {
+	payload := Payload{}
+	err := json.Unmarshal([]byte(invalidTestPayload), &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = payload.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	expected := []string{
+		"stores[1].name",
+		"stores[1].store_type",
+		"attributes.model_dir",
+		"inputs[1].name",
+		"inputs[1].store_name",
+		"inputs[1].paths.default",
+		"actions[0].attributes.files[1]",
+		"actions[0].outputs[0].store_name",
+		"actions[0].outputs[0].paths.default",
+	}
+	paths := map[string]bool{}
+	for _, verr := range verrs {
+		paths[verr.Path] = true
+	}
+	for _, path := range expected {
+		if !paths[path] {
+			t.Errorf("missing validation error for %s", path)
+		}
+	}
+	if paths["inputs[0].paths.default"] || paths["actions[0].inputs[0].paths.default"] || paths["actions[0].attributes.files[0]"] {
+		t.Errorf("unexpected validation errors:\n%s", err)
+	}
+}
+
+func TestPayloadValidateValid(t *testing.T) {
+	payload := Payload{
+		IOManager: IOManager{
+			Stores: []DataStore{{Name: "local", StoreType: FSB}},
+			Inputs: []DataSource{{Name: "dem", StoreName: "local", Paths: map[string]string{"default": "dem.tif"}}},
+		},
+		Actions: []Action{{Name: "compute"}},
+	}
+	if err := payload.Validate(); err != nil {
+		t.Error(err)
+	}
+}
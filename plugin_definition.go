@@ -0,0 +1,408 @@
+package cc
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	"github.com/spf13/cast"
+)
+
+type ATTRIBUTE_TYPE string
+
+const (
+	ATTRIBUTE_STRING ATTRIBUTE_TYPE = "string"
+	ATTRIBUTE_INT    ATTRIBUTE_TYPE = "int"
+	ATTRIBUTE_FLOAT  ATTRIBUTE_TYPE = "float"
+	ATTRIBUTE_BOOL   ATTRIBUTE_TYPE = "bool"
+	ATTRIBUTE_ARRAY  ATTRIBUTE_TYPE = "array"
+	ATTRIBUTE_OBJECT ATTRIBUTE_TYPE = "object"
+)
+
+var attributeJsonSchemaTypes = map[ATTRIBUTE_TYPE]string{
+	ATTRIBUTE_STRING: "string",
+	ATTRIBUTE_INT:    "integer",
+	ATTRIBUTE_FLOAT:  "number",
+	ATTRIBUTE_BOOL:   "boolean",
+	ATTRIBUTE_ARRAY:  "array",
+	ATTRIBUTE_OBJECT: "object",
+}
+
+// matches values that will be resolved by a substitution template at runtime
+const templateValuePattern = `\{[A-Za-z_]+::[^{}]+\}`
+
+// PluginDefinition declares what a plugin expects from a payload.
+// Payload level attributes and data sources apply to every action.  When Actions is
+// not empty, payload actions must be one of the declared actions.
+type PluginDefinition struct {
+	Name        string                 `json:"name"`
+	Version     string                 `json:"version"`
+	Description string                 `json:"description,omitempty"`
+	Attributes  []AttributeDefinition  `json:"attributes,omitempty"`
+	Inputs      []DataSourceDefinition `json:"inputs,omitempty"`
+	Outputs     []DataSourceDefinition `json:"outputs,omitempty"`
+	Actions     []ActionDefinition     `json:"actions,omitempty"`
+}
+
+// ActionDefinition declares the attributes and data sources used by a single action.
+// Action data sources can be supplied by the action or by the payload.
+type ActionDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Required    bool                   `json:"required,omitempty"`
+	Attributes  []AttributeDefinition  `json:"attributes,omitempty"`
+	Inputs      []DataSourceDefinition `json:"inputs,omitempty"`
+	Outputs     []DataSourceDefinition `json:"outputs,omitempty"`
+}
+
+// AttributeDefinition declares an attribute.  Optional attributes with a default are
+// added to the payload when they are missing.  Attribute values are checked against
+// the definition constraints.
+type AttributeDefinition struct {
+	Name        string         `json:"name"`
+	Type        ATTRIBUTE_TYPE `json:"type"`
+	Required    bool           `json:"required,omitempty"`
+	Default     any            `json:"default,omitempty"`
+	Description string         `json:"description,omitempty"`
+	AttributeConstraint
+}
+
+// DataSourceDefinition declares a data source and the keys that must be present
+// in its paths and data paths.
+type DataSourceDefinition struct {
+	Name         string   `json:"name"`
+	Required     bool     `json:"required,omitempty"`
+	PathKeys     []string `json:"path_keys,omitempty"`
+	DataPathKeys []string `json:"data_path_keys,omitempty"`
+	Description  string   `json:"description,omitempty"`
+}
+
+// LoadPluginDefinition reads a plugin definition from CC_PLUGIN_DEFINITION.
+// The variable can hold a path to a JSON file or the JSON definition itself.
+// If the variable is not set, a nil definition is returned.
+func LoadPluginDefinition() (*PluginDefinition, error) {
+	val := strings.TrimSpace(os.Getenv(CcPluginDefinition))
+	if val == "" {
+		return nil, nil
+	}
+	data := []byte(val)
+	if !strings.HasPrefix(val, "{") {
+		var err error
+		data, err = os.ReadFile(val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read plugin definition: %w", err)
+		}
+	}
+	def := PluginDefinition{}
+	err := json.Unmarshal(data, &def)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin definition: %w", err)
+	}
+	return &def, nil
+}
+
+// CheckPayload checks a payload against the plugin definition and adds default values
+// for missing optional attributes.  All problems are returned together as ValidationErrors.
+func (pd *PluginDefinition) CheckPayload(payload *Payload) error {
+	errs := ValidationErrors{}
+	if payload.Attributes == nil {
+		payload.Attributes = PayloadAttributes{}
+	}
+	checkAttributes("attributes", pd.Attributes, payload.Attributes, nil, &errs)
+	checkDataSources("inputs", pd.Inputs, payload.Inputs, "", nil, &errs)
+	checkDataSources("outputs", pd.Outputs, payload.Outputs, "", nil, &errs)
+
+	if len(pd.Actions) > 0 {
+		found := map[string]bool{}
+		for i := range payload.Actions {
+			action := &payload.Actions[i]
+			path := fmt.Sprintf("actions[%d]", i)
+			def := pd.action(action.Name)
+			if def == nil {
+				errs.add(path+".name", "action %s is not defined by plugin %s", action.Name, pd.Name)
+				continue
+			}
+			found[action.Name] = true
+			if action.Attributes == nil {
+				action.Attributes = PayloadAttributes{}
+			}
+			checkAttributes(path+".attributes", def.Attributes, action.Attributes, payload.Attributes, &errs)
+			checkDataSources(path+".inputs", def.Inputs, action.Inputs, "inputs", payload.Inputs, &errs)
+			checkDataSources(path+".outputs", def.Outputs, action.Outputs, "outputs", payload.Outputs, &errs)
+		}
+		for _, def := range pd.Actions {
+			if def.Required && !found[def.Name] {
+				errs.add("actions", "required action %s is missing", def.Name)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (pd *PluginDefinition) action(name string) *ActionDefinition {
+	for i := range pd.Actions {
+		if pd.Actions[i].Name == name {
+			return &pd.Actions[i]
+		}
+	}
+	return nil
+}
+
+// checkAttributes checks attributes against their definitions and adds defaults for missing
+// optional attributes.  Action attributes are checked with the payload attributes as the parent
+// so that an attribute defined by the payload satisfies the action definition.
+func checkAttributes(path string, defs []AttributeDefinition, attrs PayloadAttributes, parent PayloadAttributes, errs *ValidationErrors) {
+	merged := attrs
+	if parent != nil {
+		merged = PayloadAttributes{}
+		maps.Copy(merged, parent)
+		maps.Copy(merged, attrs)
+	}
+	for _, def := range defs {
+		attrPath := path + "." + def.Name
+		val, ok := merged[def.Name]
+		if !ok {
+			if def.Required {
+				errs.add(attrPath, "required attribute is missing")
+				continue
+			} else if def.Default != nil {
+				attrs[def.Name] = def.Default
+				merged[def.Name] = def.Default
+			}
+		} else if err := checkAttributeType(def.Type, attributeNumber(def.Unit, val)); err != nil {
+			errs.add(attrPath, "%s", err)
+			continue
+		}
+		def.AttributeConstraint.check(attrPath, def.Name, merged, errs)
+	}
+}
+
+// attributeNumber returns the number of a unit annotated value so that
+// the number can be type checked.  Other values are returned unchanged.
+func attributeNumber(unit string, val any) any {
+	if unit != "" {
+		if q, err := ParseQuantity(val); err == nil && q.Unit != "" {
+			return q.Value
+		}
+	}
+	return val
+}
+
+// checkAttributeType checks that a value can be read as the attribute type.
+// Strings containing substitution templates are not checked since they are resolved later.
+func checkAttributeType(attrType ATTRIBUTE_TYPE, val any) error {
+	if s, ok := val.(string); ok && containsTemplate(s) {
+		return nil
+	}
+	var err error
+	switch attrType {
+	case ATTRIBUTE_STRING, "":
+		_, err = cast.ToStringE(val)
+	case ATTRIBUTE_INT:
+		_, err = cast.ToInt64E(val)
+		if f, ok := val.(float64); ok && f != float64(int64(f)) {
+			err = fmt.Errorf("%v is not an integer", f)
+		}
+	case ATTRIBUTE_FLOAT:
+		_, err = cast.ToFloat64E(val)
+	case ATTRIBUTE_BOOL:
+		_, err = cast.ToBoolE(val)
+	case ATTRIBUTE_ARRAY:
+		_, err = cast.ToSliceE(val)
+	case ATTRIBUTE_OBJECT:
+		_, err = cast.ToStringMapE(val)
+	default:
+		return fmt.Errorf("unknown attribute type %q", attrType)
+	}
+	if err != nil {
+		return fmt.Errorf("value %v is not a valid %s", val, attrType)
+	}
+	return nil
+}
+
+// checkDataSources checks the data sources against their definitions.  Data sources that are
+// not in sources are looked up in parentSources (the payload data sources for an action).
+func checkDataSources(path string, defs []DataSourceDefinition, sources []DataSource, parentPath string, parentSources []DataSource, errs *ValidationErrors) {
+	for _, def := range defs {
+		dsPath := path
+		ds, i := findDataSource(def.Name, sources)
+		if ds == nil {
+			dsPath = parentPath
+			ds, i = findDataSource(def.Name, parentSources)
+		}
+		if ds == nil {
+			if def.Required {
+				errs.add(path, "required data source %s is missing", def.Name)
+			}
+			continue
+		}
+		dsPath = fmt.Sprintf("%s[%d]", dsPath, i)
+		for _, key := range def.PathKeys {
+			if _, ok := ds.Paths[key]; !ok {
+				errs.add(dsPath+".paths", "required path key %s is missing", key)
+			}
+		}
+		for _, key := range def.DataPathKeys {
+			if _, ok := ds.DataPaths[key]; !ok {
+				errs.add(dsPath+".data_paths", "required data path key %s is missing", key)
+			}
+		}
+	}
+}
+
+func findDataSource(name string, sources []DataSource) (*DataSource, int) {
+	for i := range sources {
+		if sources[i].Name == name {
+			return &sources[i], i
+		}
+	}
+	return nil, -1
+}
+
+// JSONSchema returns a JSON Schema describing payloads accepted by the plugin
+func (pd *PluginDefinition) JSONSchema() *jsonschema.Schema {
+	schema := &jsonschema.Schema{
+		Version:     jsonschema.Version,
+		Title:       pd.Name,
+		Description: strings.TrimSpace(fmt.Sprintf("%s %s", pd.Description, versionComment(pd.Version))),
+		Type:        "object",
+		Properties:  jsonschema.NewProperties(),
+		Required:    []string{"actions"},
+	}
+	schema.Properties.Set("attributes", attributesSchema(pd.Attributes))
+	schema.Properties.Set("stores", &jsonschema.Schema{Type: "array", Items: storeSchema()})
+	schema.Properties.Set("inputs", dataSourcesSchema(pd.Inputs))
+	schema.Properties.Set("outputs", dataSourcesSchema(pd.Outputs))
+
+	actions := &jsonschema.Schema{Type: "array", Items: &jsonschema.Schema{Type: "object", Required: []string{"name"}}}
+	for _, def := range pd.Actions {
+		actionSchema := &jsonschema.Schema{
+			Type:        "object",
+			Description: def.Description,
+			Properties:  jsonschema.NewProperties(),
+			Required:    []string{"name"},
+		}
+		actionSchema.Properties.Set("name", &jsonschema.Schema{Const: def.Name})
+		actionSchema.Properties.Set("attributes", attributesSchema(def.Attributes))
+		//action data sources can be supplied by the payload so they are not required here
+		actionSchema.Properties.Set("inputs", dataSourcesSchema(optionalDataSources(def.Inputs)))
+		actionSchema.Properties.Set("outputs", dataSourcesSchema(optionalDataSources(def.Outputs)))
+		actions.Items.AnyOf = append(actions.Items.AnyOf, actionSchema)
+		if def.Required {
+			actions.AllOf = append(actions.AllOf, &jsonschema.Schema{Contains: namedSchema(def.Name)})
+		}
+	}
+	schema.Properties.Set("actions", actions)
+	return schema
+}
+
+func versionComment(version string) string {
+	if version == "" {
+		return ""
+	}
+	return "(version " + version + ")"
+}
+
+func attributesSchema(defs []AttributeDefinition) *jsonschema.Schema {
+	schema := &jsonschema.Schema{Type: "object", Properties: jsonschema.NewProperties()}
+	for _, def := range defs {
+		jsType, ok := attributeJsonSchemaTypes[def.Type]
+		if !ok {
+			jsType = "string"
+		}
+		attrSchema := &jsonschema.Schema{Description: def.Description, Default: def.Default}
+		valueSchema := &jsonschema.Schema{Type: jsType, Enum: def.Enum, Pattern: def.Pattern}
+		if def.Minimum != nil {
+			valueSchema.Minimum = json.Number(strconv.FormatFloat(*def.Minimum, 'f', -1, 64))
+		}
+		if def.Maximum != nil {
+			valueSchema.Maximum = json.Number(strconv.FormatFloat(*def.Maximum, 'f', -1, 64))
+		}
+		if jsType == "string" {
+			attrSchema = valueSchema
+			attrSchema.Description = def.Description
+			attrSchema.Default = def.Default
+		} else {
+			attrSchema.AnyOf = []*jsonschema.Schema{
+				valueSchema,
+				{Type: "string", Pattern: templateValuePattern},
+			}
+		}
+		schema.Properties.Set(def.Name, attrSchema)
+		if def.Required {
+			schema.Required = append(schema.Required, def.Name)
+		}
+	}
+	return schema
+}
+
+func storeSchema() *jsonschema.Schema {
+	schema := &jsonschema.Schema{
+		Type:       "object",
+		Properties: jsonschema.NewProperties(),
+		Required:   []string{"name", "store_type"},
+	}
+	schema.Properties.Set("name", &jsonschema.Schema{Type: "string"})
+	schema.Properties.Set("store_type", &jsonschema.Schema{Type: "string"})
+	schema.Properties.Set("profile", &jsonschema.Schema{Type: "string"})
+	schema.Properties.Set("params", &jsonschema.Schema{Type: "object"})
+	return schema
+}
+
+func dataSourcesSchema(defs []DataSourceDefinition) *jsonschema.Schema {
+	item := &jsonschema.Schema{
+		Type:       "object",
+		Properties: jsonschema.NewProperties(),
+		Required:   []string{"name", "store_name"},
+	}
+	item.Properties.Set("name", &jsonschema.Schema{Type: "string"})
+	item.Properties.Set("store_name", &jsonschema.Schema{Type: "string"})
+	item.Properties.Set("paths", &jsonschema.Schema{Type: "object", AdditionalProperties: &jsonschema.Schema{Type: "string"}})
+	item.Properties.Set("data_paths", &jsonschema.Schema{Type: "object", AdditionalProperties: &jsonschema.Schema{Type: "string"}})
+
+	schema := &jsonschema.Schema{Type: "array", Items: item}
+	for _, def := range defs {
+		//data sources matching the definition name must provide the declared keys
+		dsSchema := &jsonschema.Schema{
+			If:   namedSchema(def.Name),
+			Then: &jsonschema.Schema{Properties: jsonschema.NewProperties(), Description: def.Description},
+		}
+		if len(def.PathKeys) > 0 {
+			dsSchema.Then.Properties.Set("paths", &jsonschema.Schema{Required: def.PathKeys})
+			dsSchema.Then.Required = append(dsSchema.Then.Required, "paths")
+		}
+		if len(def.DataPathKeys) > 0 {
+			dsSchema.Then.Properties.Set("data_paths", &jsonschema.Schema{Required: def.DataPathKeys})
+			dsSchema.Then.Required = append(dsSchema.Then.Required, "data_paths")
+		}
+		item.AllOf = append(item.AllOf, dsSchema)
+		if def.Required {
+			schema.AllOf = append(schema.AllOf, &jsonschema.Schema{Contains: namedSchema(def.Name)})
+		}
+	}
+	return schema
+}
+
+func optionalDataSources(defs []DataSourceDefinition) []DataSourceDefinition {
+	optional := make([]DataSourceDefinition, len(defs))
+	for i, def := range defs {
+		def.Required = false
+		optional[i] = def
+	}
+	return optional
+}
+
+// namedSchema matches an object with the name property
+func namedSchema(name string) *jsonschema.Schema {
+	schema := &jsonschema.Schema{Properties: jsonschema.NewProperties(), Required: []string{"name"}}
+	schema.Properties.Set("name", &jsonschema.Schema{Const: name})
+	return schema
+}
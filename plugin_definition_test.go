@@ -0,0 +1,133 @@
+package cc
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testPluginDefinition = `{
+	"name": "hydraulics",
+	"version": "1.2.0",
+	"attributes": [
+		{"name": "scenario", "type": "string", "required": true},
+		{"name": "timestep", "type": "int", "default": 60}
+	],
+	"inputs": [
+		{"name": "terrain", "required": true, "path_keys": ["default"]}
+	],
+	"actions": [
+		{
+			"name": "compute",
+			"required": true,
+			"attributes": [
+				{"name": "tolerance", "type": "float", "required": true},
+				{"name": "verbose", "type": "bool", "default": false}
+			],
+			"inputs": [{"name": "grid", "required": true, "path_keys": ["geometry", "boundary"]}],
+			"outputs": [{"name": "results", "required": true}]
+		}
+	]
+}`
+
+func testDefinition(t *testing.T) *PluginDefinition {
+	t.Helper()
+	t.Setenv(CcPluginDefinition, testPluginDefinition)
+	def, err := LoadPluginDefinition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return def
+}
+
+func TestPluginDefinitionCheckPayload(t *testing.T) {
+	def := testDefinition(t)
+	payload := Payload{
+		IOManager: IOManager{
+			Attributes: PayloadAttributes{"scenario": "base"},
+			Inputs: []DataSource{
+				{Name: "terrain", StoreName: "local", Paths: map[string]string{"default": "dem.tif"}},
+				{Name: "grid", StoreName: "local", Paths: map[string]string{"geometry": "grid.g01"}},
+			},
+		},
+		Actions: []Action{
+			{Name: "compute", IOManager: IOManager{Attributes: PayloadAttributes{"tolerance": "{ENV::TOLERANCE}"}}},
+			{Name: "postprocess", IOManager: IOManager{Attributes: PayloadAttributes{"tolerance": "high"}}},
+		},
+	}
+
+	err := def.CheckPayload(&payload)
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	expected := map[string]string{
+		"inputs[1].paths":    "boundary",
+		"actions[0].outputs": "results",
+		"actions[1].name":    "postprocess",
+	}
+	if len(verrs) != len(expected) {
+		t.Errorf("unexpected validation errors:\n%s", err)
+	}
+	for _, verr := range verrs {
+		if !strings.Contains(verr.Message, expected[verr.Path]) {
+			t.Errorf("unexpected validation error %s", verr)
+		}
+	}
+
+	//defaults are added to missing optional attributes
+	if payload.Attributes["timestep"] != 60.0 || payload.Actions[0].Attributes["verbose"] != false {
+		t.Errorf("defaults were not applied: %v %v", payload.Attributes, payload.Actions[0].Attributes)
+	}
+
+	payload.Actions = []Action{{Name: "compute", IOManager: IOManager{
+		Attributes: PayloadAttributes{"tolerance": 0.01, "verbose": "yes"},
+		Outputs:    []DataSource{{Name: "results", StoreName: "local"}},
+	}}}
+	payload.Attributes["timestep"] = 2.5
+	payload.Inputs[1].Paths["boundary"] = "grid.b01"
+	err = def.CheckPayload(&payload)
+	if !errors.As(err, &verrs) || len(verrs) != 2 ||
+		verrs[0].Path != "attributes.timestep" || verrs[1].Path != "actions[0].attributes.verbose" {
+		t.Errorf("expected attribute type errors, got %v", err)
+	}
+
+	//payload attributes satisfy action attributes and are not shadowed by action defaults
+	payload.Attributes = PayloadAttributes{"scenario": "base", "tolerance": 0.05, "verbose": true}
+	payload.Actions[0].Attributes = PayloadAttributes{}
+	if err = def.CheckPayload(&payload); err != nil {
+		t.Error(err)
+	}
+	if _, ok := payload.Actions[0].Attributes["verbose"]; ok {
+		t.Errorf("action default shadows the payload attribute: %v", payload.Actions[0].Attributes)
+	}
+}
+
+func TestPluginDefinitionJSONSchema(t *testing.T) {
+	def := testDefinition(t)
+	data, err := json.Marshal(def.JSONSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := map[string]any{}
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema["title"] != "hydraulics" || schema["$schema"] == nil {
+		t.Errorf("unexpected schema header: %s", data)
+	}
+	attrs := schema["properties"].(map[string]any)["attributes"].(map[string]any)
+	if required := attrs["required"].([]any); len(required) != 1 || required[0] != "scenario" {
+		t.Errorf("unexpected required attributes: %v", required)
+	}
+	timestep := attrs["properties"].(map[string]any)["timestep"].(map[string]any)
+	if timestep["default"] != 60.0 || timestep["anyOf"].([]any)[0].(map[string]any)["type"] != "integer" {
+		t.Errorf("unexpected attribute schema: %v", timestep)
+	}
+	actions := schema["properties"].(map[string]any)["actions"].(map[string]any)
+	if len(actions["allOf"].([]any)) != 1 || len(actions["items"].(map[string]any)["anyOf"].([]any)) != 1 {
+		t.Errorf("unexpected actions schema: %v", actions)
+	}
+}
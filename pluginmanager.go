@@ -6,9 +6,8 @@ import (
 	"io"
 	"maps"
 	"os"
-	"reflect"
-	"regexp"
-	"strings"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -19,6 +18,7 @@ const (
 	CcPluginDefinition  = "CC_PLUGIN_DEFINITION"
 	CcProfile           = "CC"
 	CcPayloadFormatted  = "CC_PAYLOAD_FORMATTED"
+	CcPayloadFormat     = "CC_PAYLOAD_FORMAT"
 	CcRootPath          = "CC_ROOT"
 	CcLogIdentifier     = "CC_LOG"
 	AwsAccessKeyId      = "AWS_ACCESS_KEY_ID"
@@ -30,13 +30,25 @@ const (
 	AwsS3DisableSSL     = "S3_DISABLE_SSL"
 	AwsS3Endpoint       = "AWS_ENDPOINT"
 	FsbRootPath         = "FSB_ROOT_PATH"
-)
 
-var substitutionRegexPattern string = `{([^{}]*)}`
-var substitutionRegex *regexp.Regexp
+	CcProvenanceDataSource = "CC_PROVENANCE_DATASOURCE"
+	CcProvenancePathKey    = "CC_PROVENANCE_PATHKEY"
+	CcDryRun               = "CC_DRY_RUN"
+	CcSecretsPath          = "CC_SECRETS_PATH"
+	CcActionCacheStore     = "CC_ACTION_CACHE_STORE"
+	CcMaxConcurrentActions = "CC_MAX_CONCURRENT_ACTIONS"
+)
 
 var maxretry int = 100
 
+var pluginDefinition *PluginDefinition
+
+var dryRun bool
+
+var actionCacheStore string
+
+var maxConcurrentActions int
+
 type NamedAction interface {
 	GetName() string
 }
@@ -89,20 +101,58 @@ type PluginManager struct {
 	EventIdentifier string
 	ccStore         CcStore
 	Logger          *CcLogger
+	definition      *PluginDefinition
 	Payload
+
+	//name of the payload store used to cache action outputs (see ActionCacheEnabled)
+	actionCacheStore string
+
+	//maximum number of actions with declared dependencies that run at the same time
+	maxConcurrentActions int
+
+	middleware []ActionMiddleware
+	hooks      actionHooks
 }
 
 type PluginManagerConfig struct {
 	MaxRetry int
+
+	//plugin definition used to check the payload.
+	//overrides a definition in CC_PLUGIN_DEFINITION
+	PluginDefinition *PluginDefinition
+
+	//check the payload and report the action reads and writes without transferring data.
+	//dry runs can also be enabled with CC_DRY_RUN
+	DryRun bool
+
+	//name of a payload store used to cache action outputs.
+	//overrides a store name in CC_ACTION_CACHE_STORE
+	ActionCacheStore string
+
+	//maximum number of actions run at the same time when actions declare dependencies.
+	//overrides CC_MAX_CONCURRENT_ACTIONS.  Defaults to the number of CPUs
+	MaxConcurrentActions int
 }
 
 func InitPluginManagerWithConfig(config PluginManagerConfig) (*PluginManager, error) {
 	maxretry = config.MaxRetry
+	pluginDefinition = config.PluginDefinition
+	dryRun = config.DryRun
+	actionCacheStore = config.ActionCacheStore
+	maxConcurrentActions = config.MaxConcurrentActions
 	return InitPluginManager()
 }
 
-func connectStores(stores *[]DataStore) error {
+// connectStores connects the data store sessions.  Attribute errors raised by
+// store parameter accessors (see GetOrFail) are returned as errors.
+// Store parameters can use {ENV::}, {CC::} and {SECRET::} templates.
+func connectStores(stores *[]DataStore) (err error) {
+	defer recoverAttributeError(&err)
 	for i, ds := range *stores {
+		err = substituteStoreParameters(ds.Parameters)
+		if err != nil {
+			return fmt.Errorf("store %s: %w", ds.Name, err)
+		}
 		newInstance, err := DataStoreTypeRegistry.New(ds.StoreType)
 		if err != nil {
 			return err
@@ -122,7 +172,6 @@ func InitPluginManager() (*PluginManager, error) {
 	manifestId := os.Getenv(CcManifestId)
 	payloadId := os.Getenv(CcPayloadId)
 	registerStoreTypes()
-	substitutionRegex, _ = regexp.Compile(substitutionRegexPattern)
 	var manager PluginManager
 	manager.EventIdentifier = os.Getenv(CcEventIdentifier)
 	manager.Logger = NewCcLogger(CcLoggerInput{manifestId, payloadId, nil})
@@ -139,8 +188,47 @@ func InitPluginManager() (*PluginManager, error) {
 		return nil, fmt.Errorf("failed to get payload: %w", err)
 	}
 
+	//merge the payload includes and apply store profiles
+	err = payload.LoadIncludes(store)
+	if err != nil {
+		return nil, err
+	}
+	payload, err = payload.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	//check the payload against the plugin definition before validation
+	//so that default attributes are available to attribute templates
+	manager.definition = pluginDefinition
+	if manager.definition == nil {
+		manager.definition, err = LoadPluginDefinition()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if manager.definition != nil {
+		err = manager.definition.CheckPayload(&payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = payload.Validate()
+	if err != nil {
+		return nil, err
+	}
+
 	manager.IOManager = payload.IOManager //@TODO do I absolutely need these two lines?
 	manager.Actions = payload.Actions
+	manager.IOManager.recorder = newProvenanceRecorder()
+	manager.IOManager.dryRun = dryRun
+	if !dryRun {
+		manager.IOManager.dryRun, err = dryRunFromEnv()
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	//make connections to the plugin manager stores
 	err = connectStores(&manager.Stores)
@@ -148,11 +236,30 @@ func InitPluginManager() (*PluginManager, error) {
 		return nil, err
 	}
 
+	manager.maxConcurrentActions = maxConcurrentActions
+	if manager.maxConcurrentActions == 0 {
+		manager.maxConcurrentActions, err = maxConcurrentActionsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manager.actionCacheStore = actionCacheStore
+	if manager.actionCacheStore == "" {
+		manager.actionCacheStore = os.Getenv(CcActionCacheStore)
+	}
+	if manager.actionCacheStore != "" {
+		if _, err = manager.IOManager.GetStore(manager.actionCacheStore); err != nil {
+			return nil, fmt.Errorf("invalid action cache store: %w", err)
+		}
+	}
+
 	for i := range manager.Actions {
 		//add the pm manager IOManager as a parent to the action IOManager
 		//so that the action IOManager can recursively search through parent
 		//IOManager elements
 		manager.Actions[i].IOManager.SetParent(&manager.IOManager)
+		manager.Actions[i].IOManager.actionName = manager.Actions[i].Name
 
 		//make connection to the action stores
 		err = connectStores(&manager.Actions[i].Stores)
@@ -165,49 +272,150 @@ func InitPluginManager() (*PluginManager, error) {
 	return &manager, err
 }
 
+// GetPluginDefinition returns the plugin definition used to check the payload.
+// Returns nil if the plugin does not have a definition.
+func (pm *PluginManager) GetPluginDefinition() *PluginDefinition {
+	return pm.definition
+}
+
 // RunActions iterates through the registered actions and executes them.
 //
-// It iterates over the `Actions` slice in the `PluginManager`, and for each action,
-// it searches the `ActionRegistry` for a matching runner implementation. If a match is found
-// (identified by the action name), the runner is instantiated using reflection,
-// its `PluginManager`, `Action`, and `ActionName` fields are set, and its `Run` method is called.
+// For each action in the `Actions` slice, a new runner is created by the factory registered in
+// `ActionFactories` (see Register and RegisterActionFunc) or from the runner registered in the
+// `ActionRegistry`, and its `Run` method is called.  ActionRegistry runners are instantiated using
+// reflection and are initialized with InitAction, or for runners that do not embed ActionRunnerBase,
+// by setting their `PluginManager`, `Action`, and `ActionName` fields.
+// Actions without a registered runner are errors.
+//
+// When CC_PROVENANCE_DATASOURCE is set, a provenance document listing every data source read and
+// write is written to that output data source after the actions run (see WriteProvenance).
+//
+// Actions run one at a time in payload order unless an action declares depends_on.  Then each action
+// runs when the actions it depends on have completed, and independent actions run concurrently
+// (see PluginManagerConfig.MaxConcurrentActions).  Actions without depends_on run after the actions
+// before them in the payload.  A failed action skips its dependents unless its runner continues on
+// errors, and the errors of all failed and skipped actions are returned.
 //
-// This method relies heavily on reflection to dynamically instantiate and invoke the action runners,
-// making it flexible but also potentially less performant than statically typed calls.
-// It assumes that all action runner structs have fields named "PluginManager", "Action", and "ActionName".
+// When the cc store supports checkpoints (see CheckpointStore), the completed actions are recorded
+// and a restarted run of the same event and payload skips the actions completed by the previous run.
+//
+// When the action cache is enabled, actions with cached outputs are restored from the
+// cache instead of being run (see ActionCacheEnabled).
+//
+// Action middleware and the plugin and action hooks are called around the run (see Use).
+//
+// In dry run mode the actions are not run.  The data each action would read and write is
+// logged (see PlanActions) and an error is returned if any read path does not exist.
 //
 // @TODO review error handling here.....
 func (pm *PluginManager) RunActions() error {
-	for _, action := range pm.Actions {
-		for runnerName, runner := range ActionRegistry {
-			if action.Name == runnerName {
-				pm.Logger.Info("Running " + action.Name)
-				t := reflect.TypeOf(runner).Elem() //runner is a pointer, so take the value of it
-				pointerVal := reflect.New(t)       //create a new struct instance from type t
-				structType := pointerVal.Elem()
-				structType.FieldByName("PluginManager").Set(reflect.ValueOf(pm))
-				structType.FieldByName("Action").Set(reflect.ValueOf(action))
-				structType.FieldByName("ActionName").Set(reflect.ValueOf(runnerName))
-				runMethod := pointerVal.MethodByName("Run") //must call method on the pointer receiver
-				if runMethod.IsValid() {
-					results := runMethod.Call(nil)
-					//only a single error should be returned as results
-					if len(results) > 0 {
-						if err, ok := results[0].Interface().(error); ok {
-							if !(structType.FieldByName("ContinueOnError").Bool()) {
-								return fmt.Errorf("error running %s: %s", runnerName, err)
-							}
-						}
-					}
-				}
-				pm.Logger.Info("Completed " + action.Name)
+	if pm.DryRun() {
+		return pm.runDryRun()
+	}
+	event := PluginEvent{PluginManager: pm, Started: time.Now()}
+	err := pm.pluginStart(event)
+	if err == nil {
+		err = pm.runActions()
+		if perr := pm.writeConfiguredProvenance(); perr != nil {
+			if err != nil {
+				pm.Logger.Error(perr.Error())
+			} else {
+				err = perr
 			}
-			//}
+		}
+	}
+	event.Err = err
+	pm.pluginEnd(event)
+	return err
+}
+
+func (pm *PluginManager) runActions() error {
+	//fail before running anything if any action can not be run
+	errs := ValidationErrors{}
+	validateActionRunners(pm.Actions, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	progress := pm.loadActionProgress()
+	var err error
+	if hasActionDependencies(pm.Actions) {
+		err = pm.runActionGraph(progress)
+	} else {
+		err = pm.runActionSequence(progress)
+	}
+	if err == nil && progress != nil {
+		progress.Finished = true
+		pm.saveActionProgress(progress)
+	}
+	return err
+}
+
+// runActionSequence runs the actions one at a time in payload order.
+// The run stops at the first failed action unless the runner continues on errors.
+func (pm *PluginManager) runActionSequence(progress *actionProgress) error {
+	for i, action := range pm.Actions {
+		if progress != nil && progress.isCompleted(i, action.Name) {
+			pm.Logger.Info("Skipping " + action.Name + ", completed by a previous run")
+			continue
+		}
+		continueOnError, err := pm.runAction(i, action, progress)
+		if err != nil && !continueOnError {
+			return err
 		}
 	}
 	return nil
 }
 
+// runAction runs an action with its registered runner, its middleware and the action hooks.
+// Actions without a registered runner are errors.  Returns whether the runner continues on
+// errors along with the error of the run.
+func (pm *PluginManager) runAction(index int, action Action, progress *actionProgress) (bool, error) {
+	if pm.ActionCacheEnabled() {
+		action.IOManager.parentSources = &atomic.Bool{}
+	}
+	ctx := ActionContext{pm, action, action.Name}
+	runner, err := newActionRunner(ctx)
+	if err != nil {
+		return false, err
+	}
+	event := ActionEvent{Action: action, IOManager: &action.IOManager, Started: time.Now()}
+	err = pm.beforeAction(event)
+	if err == nil {
+		cacheKey := pm.actionCacheKey(&action)
+		if pm.restoreCachedAction(&action, cacheKey) {
+			pm.Logger.Info("Restored "+action.Name+" from the action cache", "key", cacheKey)
+			event.Restored = true
+			pm.completeAction(progress, index, action.Name)
+		} else {
+			pm.Logger.Info("Running " + action.Name)
+			err = callActionRunner(pm.wrapActionRunner(runner, ctx))
+			if err == nil {
+				pm.storeCachedAction(&action, cacheKey)
+				pm.completeAction(progress, index, action.Name)
+			}
+		}
+	}
+	event.Err = err
+	pm.afterAction(event)
+	if err != nil {
+		err = fmt.Errorf("error running %s: %s", action.Name, err)
+		if !continuesOnError(runner) {
+			return false, err
+		}
+		pm.Logger.Error("Failed "+action.Name+", continuing", "error", err.Error())
+		return true, err
+	}
+	pm.Logger.Info("Completed " + action.Name)
+	return false, nil
+}
+
+// callActionRunner calls an action runner.  Attribute errors raised by
+// GetOrFail accessors in the runner are returned as errors.
+func callActionRunner(runner ActionRunner) (err error) {
+	defer recoverAttributeError(&err)
+	return runner.Run()
+}
+
 // -----------------------------------------------
 // Wrapped IOManager functions
 // -----------------------------------------------
@@ -240,18 +448,50 @@ func (pm PluginManager) Put(input PutOpInput) (int, error) {
 	return pm.IOManager.Put(input)
 }
 
-func (pm PluginManager) Copy(src DataSourceOpInput, dest DataSourceOpInput) error {
+func (pm PluginManager) Copy(src DataSourceOpInput, dest DataSourceOpInput) (CopyResult, error) {
 	return pm.IOManager.Copy(src, dest)
 }
 
+func (pm PluginManager) CopyAll(src DataSourceOpInput, dest DataSourceOpInput, pathKeys ...string) ([]CopyResult, error) {
+	return pm.IOManager.CopyAll(src, dest, pathKeys...)
+}
+
 func (pm PluginManager) CopyFileToLocal(dsName string, pathkey string, dataPathKey string, localPath string) error {
 	return pm.IOManager.CopyFileToLocal(dsName, pathkey, dataPathKey, localPath)
 }
 
+func (pm PluginManager) CopyToLocal(input DataSourceOpInput, localPath string) error {
+	return pm.IOManager.CopyToLocal(input, localPath)
+}
+
+func (pm PluginManager) ExpandPaths(input DataSourceOpInput) ([]string, error) {
+	return pm.IOManager.ExpandPaths(input)
+}
+
+func (pm PluginManager) ForEachReader(input DataSourceOpInput, readerFunction func(path string, reader io.Reader) error) error {
+	return pm.IOManager.ForEachReader(input, readerFunction)
+}
+
+func (pm PluginManager) CopyDataSourceToLocal(input DataSourceOpInput, localDir string) ([]string, error) {
+	return pm.IOManager.CopyDataSourceToLocal(input, localDir)
+}
+
 func (pm PluginManager) CopyFileToRemote(input CopyFileToRemoteInput) error {
 	return pm.IOManager.CopyFileToRemote(input)
 }
 
+func (pm PluginManager) GetMetadataStore(storeName string, namespace ...string) (*NamespacedMetadataStore, error) {
+	return pm.IOManager.GetMetadataStore(storeName, namespace...)
+}
+
+func (pm PluginManager) GetRaster(input DataSourceOpInput, window GetRasterInput) (*ArrayResult, error) {
+	return pm.IOManager.GetRaster(input, window)
+}
+
+func (pm PluginManager) PutRaster(input DataSourceOpInput, raster GeoTiffInput) (int, error) {
+	return pm.IOManager.PutRaster(input, raster)
+}
+
 // -----------------------------------------------
 // Private utility functions
 // -----------------------------------------------
@@ -309,16 +549,58 @@ func (pm *PluginManager) substitutePathVariables() error {
 
 func (pm *PluginManager) substituteMapVariables(params map[string]any, attrSub bool) {
 	for param, val := range params {
-		switch val.(type) {
-		case string:
-			newval, err := parameterSubstitute(val, pm.Attributes, attrSub)
-			if err == nil {
-				params[param] = newval
+		params[param] = pm.substituteValue(val, attrSub)
+	}
+}
+
+// substituteValue substitutes strings in attribute values, including strings
+// nested in maps and lists.  Values with invalid templates are left unchanged.
+func (pm *PluginManager) substituteValue(val any, attrSub bool) any {
+	switch v := val.(type) {
+	case string:
+		newval, err := parameterSubstitute(v, pm.Attributes, attrSub)
+		if err == nil {
+			return newval
+		}
+	case map[string]any:
+		pm.substituteMapVariables(v, attrSub)
+	case []any:
+		for i := range v {
+			v[i] = pm.substituteValue(v[i], attrSub)
+		}
+	}
+	return val
+}
+
+// substituteStoreParameters resolves the templates in string store parameters,
+// including strings nested in maps and lists
+func substituteStoreParameters(params map[string]any) error {
+	for key, val := range params {
+		newval, err := substituteStoreValue(val)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", key, err)
+		}
+		params[key] = newval
+	}
+	return nil
+}
+
+func substituteStoreValue(val any) (any, error) {
+	switch v := val.(type) {
+	case string:
+		return substituteTemplate(v, envTemplateResolver)
+	case map[string]any:
+		return v, substituteStoreParameters(v)
+	case []any:
+		for i := range v {
+			newval, err := substituteStoreValue(v[i])
+			if err != nil {
+				return nil, err
 			}
-		case map[string]any:
-			pm.substituteMapVariables(val.(map[string]any), attrSub)
+			v[i] = newval
 		}
 	}
+	return val, nil
 }
 
 // @TODO add substitution for datapaths
@@ -330,7 +612,7 @@ func pathsSubstitute(ds *DataSource, payloadAttr map[string]any) error {
 	ds.Name = name
 
 	for i, p := range ds.Paths {
-		path, err := parameterSubstitute(p, payloadAttr, true)
+		path, err := pathSubstitute(p, payloadAttr)
 		if err != nil {
 			return err
 		}
@@ -338,7 +620,7 @@ func pathsSubstitute(ds *DataSource, payloadAttr map[string]any) error {
 	}
 
 	for i, p := range ds.DataPaths {
-		path, err := parameterSubstitute(p, payloadAttr, true)
+		path, err := pathSubstitute(p, payloadAttr)
 		if err != nil {
 			return err
 		}
@@ -348,51 +630,40 @@ func pathsSubstitute(ds *DataSource, payloadAttr map[string]any) error {
 	return nil
 }
 
+// parameterSubstitute resolves {ENV::}, {CC::} and, if attrSub is true, {ATTR::} templates.
+// Other templates such as {VAR::} are left for substitution when the data source is used.
 func parameterSubstitute(param interface{}, payloadAttr map[string]any, attrSub bool) (string, error) {
 	switch template := param.(type) {
 	case string:
-		result := substitutionRegex.FindAllStringSubmatch(template, -1)
-		for _, match := range result {
-			sub := strings.Split(match[1], "::")
-			if len(sub) != 2 {
-				return "", fmt.Errorf("invalid data source substitution: %s", match[0])
-			}
-			val := ""
-			switch {
-			case sub[0] == "ENV":
-				val = os.Getenv(sub[1])
-				if val == "" {
-					return "", fmt.Errorf("invalid data source substitution.  missing environment parameter: %s", match[0])
-				}
-			case sub[0] == "ATTR" && attrSub:
-				val2, ok := payloadAttr[sub[1]]
-				if !ok {
-					return "", fmt.Errorf("invalid data source substitution.  missing payload parameter: %s", match[0])
-				}
-				val = fmt.Sprintf("%v", val2) //need to coerce non-string values into strings.  for example ints might be perfectly valid for parameter substitution in a url
-			default:
-				continue //if its not ENV or ATTR, skip the substitution
-			}
-			template = strings.Replace(template, match[0], val, 1)
+		if !attrSub {
+			payloadAttr = nil
 		}
-		return template, nil
+		return substituteTemplate(template, attrTemplateResolver(payloadAttr))
 	default:
 		return "", errors.New("invalid parameter type")
 	}
 }
 
-func templateVarSubstitution(template string, templateVars map[string]string) string {
-	result := substitutionRegex.FindAllStringSubmatch(template, -1)
-	for _, match := range result {
-		sub := strings.Split(match[1], "::")
-		if len(sub) != 2 {
-			continue
-		}
-		if sub[0] == "VAR" {
-			if val, ok := templateVars[sub[1]]; ok {
-				template = strings.Replace(template, match[0], val, 1)
+// pathSubstitute resolves {ENV::}, {CC::} and {ATTR::} templates in a data source path.
+// Escaped braces are kept for templateVarSubstitution, which unescapes them when the path is used.
+func pathSubstitute(path string, payloadAttr map[string]any) (string, error) {
+	return substituteTemplatePass(path, attrTemplateResolver(payloadAttr))
+}
+
+// templateVarSubstitution resolves {VAR::} templates along with any remaining
+// {ENV::} and {CC::} templates.  Undefined template variables without a default are errors.
+// Braces that are not templates are treated as literal text.
+func templateVarSubstitution(template string, templateVars map[string]string) (string, error) {
+	ts := templateSubstitution{
+		resolve: func(kind string, name string) (string, bool, error) {
+			if kind == templateVar {
+				val, ok := templateVars[name]
+				return val, ok, nil
 			}
-		}
+			return envTemplateResolver(kind, name)
+		},
+		lenient: true,
 	}
-	return template
+	result, _, err := ts.render(template)
+	return result, err
 }
@@ -0,0 +1,225 @@
+package cc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type ProvenanceOperation string
+
+const (
+	ProvenanceRead  ProvenanceOperation = "read"
+	ProvenanceWrite ProvenanceOperation = "write"
+
+	defaultProvenancePathKey = "default"
+)
+
+// ProvenanceRecord describes a single read or write of a data source.
+// Bytes and Sha256 are only recorded for reads that are consumed through an IOManager reader
+// and are empty for partial (range) reads.
+type ProvenanceRecord struct {
+	Operation  ProvenanceOperation `json:"operation"`
+	Action     string              `json:"action,omitempty"`
+	DataSource string              `json:"data_source"`
+	Store      string              `json:"store"`
+	Path       string              `json:"path"`
+	DataPath   string              `json:"data_path,omitempty"`
+	Bytes      int64               `json:"bytes"`
+	Sha256     string              `json:"sha256,omitempty"`
+	Time       time.Time           `json:"time"`
+}
+
+// ProvenanceDocument is the provenance manifest for a plugin run
+type ProvenanceDocument struct {
+	ManifestId      string             `json:"manifest_id,omitempty"`
+	PayloadId       string             `json:"payload_id,omitempty"`
+	EventIdentifier string             `json:"event_identifier,omitempty"`
+	Plugin          string             `json:"plugin,omitempty"`
+	PluginVersion   string             `json:"plugin_version,omitempty"`
+	Started         time.Time          `json:"started"`
+	Completed       time.Time          `json:"completed"`
+	Records         []ProvenanceRecord `json:"records"`
+}
+
+// provenanceRecorder collects the provenance records of an IOManager and its action IOManagers
+type provenanceRecorder struct {
+	started time.Time
+	records []ProvenanceRecord
+	mutex   sync.Mutex
+}
+
+func newProvenanceRecorder() *provenanceRecorder {
+	return &provenanceRecorder{started: time.Now().UTC()}
+}
+
+func (pr *provenanceRecorder) record(rec ProvenanceRecord) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.records = append(pr.records, rec)
+}
+
+func (pr *provenanceRecorder) snapshot() []ProvenanceRecord {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	records := make([]ProvenanceRecord, len(pr.records))
+	copy(records, pr.records)
+	return records
+}
+
+// checksumReader counts and hashes the bytes read through it
+type checksumReader struct {
+	reader io.Reader
+	hash   hash.Hash
+	n      int64
+}
+
+func newChecksumReader(reader io.Reader) *checksumReader {
+	return &checksumReader{reader: reader, hash: sha256.New()}
+}
+
+func (cr *checksumReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	cr.n += int64(n)
+	cr.hash.Write(p[:n])
+	return n, err
+}
+
+func (cr *checksumReader) Sum() string {
+	return hex.EncodeToString(cr.hash.Sum(nil))
+}
+
+// provenanceReadCloser records a read when the reader is closed
+type provenanceReadCloser struct {
+	*checksumReader
+	closer   io.Closer
+	recorder *provenanceRecorder
+	rec      ProvenanceRecord
+	once     sync.Once
+}
+
+func (prc *provenanceReadCloser) Close() error {
+	prc.once.Do(func() {
+		prc.rec.Bytes = prc.n
+		prc.rec.Sha256 = prc.Sum()
+		prc.recorder.record(prc.rec)
+	})
+	return prc.closer.Close()
+}
+
+// provenance returns the recorder of the IOManager or its nearest parent
+func (im *IOManager) provenance() *provenanceRecorder {
+	for m := im; m != nil; m = m.parent {
+		if m.recorder != nil {
+			return m.recorder
+		}
+	}
+	return nil
+}
+
+func (im *IOManager) provenanceRecord(op ProvenanceOperation, ds DataSource, path string, datapath string) ProvenanceRecord {
+	return ProvenanceRecord{
+		Operation:  op,
+		Action:     im.actionName,
+		DataSource: ds.Name,
+		Store:      ds.StoreName,
+		Path:       path,
+		DataPath:   datapath,
+		Time:       time.Now().UTC(),
+	}
+}
+
+// recordReader wraps a reader so that the read is recorded, with its size and checksum, on close
+func (im *IOManager) recordReader(reader io.ReadCloser, ds DataSource, path string, datapath string) io.ReadCloser {
+	recorder := im.provenance()
+	if recorder == nil {
+		return reader
+	}
+	return &provenanceReadCloser{
+		checksumReader: newChecksumReader(reader),
+		closer:         reader,
+		recorder:       recorder,
+		rec:            im.provenanceRecord(ProvenanceRead, ds, path, datapath),
+	}
+}
+
+func (im *IOManager) recordOperation(op ProvenanceOperation, ds DataSource, path string, datapath string, n int64, sha string) {
+	if recorder := im.provenance(); recorder != nil {
+		rec := im.provenanceRecord(op, ds, path, datapath)
+		rec.Bytes = n
+		rec.Sha256 = sha
+		recorder.record(rec)
+	}
+}
+
+// Provenance returns the provenance of the data read and written by the plugin so far
+func (pm *PluginManager) Provenance() ProvenanceDocument {
+	doc := ProvenanceDocument{
+		ManifestId:      os.Getenv(CcManifestId),
+		PayloadId:       os.Getenv(CcPayloadId),
+		EventIdentifier: pm.EventIdentifier,
+		Completed:       time.Now().UTC(),
+		Records:         []ProvenanceRecord{},
+	}
+	if pm.definition != nil {
+		doc.Plugin = pm.definition.Name
+		doc.PluginVersion = pm.definition.Version
+	}
+	if recorder := pm.IOManager.provenance(); recorder != nil {
+		doc.Started = recorder.started
+		doc.Records = recorder.snapshot()
+	}
+	return doc
+}
+
+// WriteProvenance writes the provenance document as json to an output data source path.
+// The write is not included in the provenance records.
+func (pm *PluginManager) WriteProvenance(input DataSourceOpInput) error {
+	ds, err := pm.IOManager.opDataSource(input, DataSourceOutput)
+	if err != nil {
+		return err
+	}
+	store, err := pm.IOManager.GetStore(ds.StoreName)
+	if err != nil {
+		return err
+	}
+	writer, ok := pm.IOManager.storeWriter(store)
+	if !ok {
+		return fmt.Errorf("data store %s session does not implement a storewriter", ds.StoreName)
+	}
+	path, datapath, err := opPaths(ds, input)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(pm.Provenance(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = writer.Put(bytes.NewReader(data), path, datapath)
+	return err
+}
+
+// writeConfiguredProvenance writes the provenance document to the output data source
+// in CC_PROVENANCE_DATASOURCE if it is set
+func (pm *PluginManager) writeConfiguredProvenance() error {
+	dsName := os.Getenv(CcProvenanceDataSource)
+	if dsName == "" {
+		return nil
+	}
+	pathKey := os.Getenv(CcProvenancePathKey)
+	if pathKey == "" {
+		pathKey = defaultProvenancePathKey
+	}
+	err := pm.WriteProvenance(DataSourceOpInput{DataSourceName: dsName, PathKey: pathKey})
+	if err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+	return nil
+}
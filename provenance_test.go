@@ -0,0 +1,67 @@
+package cc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	im, root := testFileIOManager(t)
+	pm := PluginManager{EventIdentifier: "event-3"}
+	pm.IOManager = *im
+	pm.IOManager.recorder = newProvenanceRecorder()
+	pm.Outputs = append(pm.Outputs, DataSource{
+		Name:      "provenance",
+		StoreName: "local",
+		Paths:     map[string]string{"default": "provenance/event-3.json"},
+	})
+	action := Action{Name: "compute"}
+	action.IOManager.SetParent(&pm.IOManager)
+	action.IOManager.actionName = action.Name
+
+	vars := map[string]string{"realization": "4"}
+	_, err := action.Put(PutOpInput{
+		SrcReader:         strings.NewReader("depth"),
+		DataSourceOpInput: DataSourceOpInput{DataSourceName: "depths", PathKey: "default", TemplateVars: vars},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pm.Get(DataSourceOpInput{DataSourceName: "depths", PathKey: "default", TemplateVars: vars})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := pm.Provenance()
+	if len(doc.Records) != 2 || doc.EventIdentifier != "event-3" {
+		t.Fatalf("unexpected provenance: %+v", doc)
+	}
+	write, read := doc.Records[0], doc.Records[1]
+	if write.Operation != ProvenanceWrite || write.Action != "compute" || write.Path != "outputs/4/depth.csv" ||
+		write.Store != "local" || write.Bytes != 5 || write.Sha256 == "" {
+		t.Errorf("unexpected write record: %+v", write)
+	}
+	if read.Operation != ProvenanceRead || read.Action != "" || read.Sha256 != write.Sha256 || read.Bytes != 5 {
+		t.Errorf("unexpected read record: %+v", read)
+	}
+
+	//the provenance document is written to the configured data source when the actions complete
+	t.Setenv(CcProvenanceDataSource, "provenance")
+	if err = pm.RunActions(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "provenance/event-3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := ProvenanceDocument{}
+	if err = json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if len(written.Records) != 2 || written.Started.IsZero() {
+		t.Errorf("unexpected provenance document: %s", data)
+	}
+}
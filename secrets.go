@@ -0,0 +1,170 @@
+package cc
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SecretProvider supplies the values of {SECRET::name} templates.
+// Providers return found=false for secrets they do not have.
+type SecretProvider interface {
+	GetSecret(name string) (val string, found bool, err error)
+}
+
+// EnvSecretProvider reads secrets from environment variables named Prefix+name
+type EnvSecretProvider struct {
+	Prefix string
+}
+
+func (p EnvSecretProvider) GetSecret(name string) (string, bool, error) {
+	val := os.Getenv(p.Prefix + name)
+	return val, val != "", nil
+}
+
+// FileSecretProvider reads secrets from files in a directory, such as docker and kubernetes
+// secret mounts.  The file name is the secret name and a trailing newline is removed.
+// If Dir is empty, the directory in CC_SECRETS_PATH or /run/secrets is used.
+type FileSecretProvider struct {
+	Dir string
+}
+
+const defaultSecretsPath = "/run/secrets"
+
+func (p FileSecretProvider) GetSecret(name string) (string, bool, error) {
+	dir := p.Dir
+	if dir == "" {
+		dir = os.Getenv(CcSecretsPath)
+	}
+	if dir == "" {
+		dir = defaultSecretsPath
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return "", false, fmt.Errorf("invalid secret name %s", name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+type SecretProviderRegistry []SecretProvider
+
+// SecretProviders are asked for {SECRET::} values in order and the first provider with
+// the secret is used.  Secrets are read from environment variables and then from
+// secret files.  Plugins can add providers for other secret stores with RegisterSecretProvider.
+var SecretProviders = SecretProviderRegistry{EnvSecretProvider{}, FileSecretProvider{}}
+
+// RegisterSecretProvider adds a provider that is asked for secrets before the existing providers
+func (spr *SecretProviderRegistry) RegisterSecretProvider(provider SecretProvider) {
+	*spr = append(SecretProviderRegistry{provider}, *spr...)
+}
+
+// GetSecret reads a secret from the secret providers.  The value is recorded
+// so that it is redacted from logs and saved payloads (see RedactSecrets).
+func (spr SecretProviderRegistry) GetSecret(name string) (string, bool, error) {
+	for _, provider := range spr {
+		val, found, err := provider.GetSecret(name)
+		if err != nil {
+			return "", false, err
+		}
+		if found {
+			resolvedSecrets.add(name, val)
+			return val, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// secretRegistry holds the resolved secret values and the names of the secrets they came from
+type secretRegistry struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+var resolvedSecrets = secretRegistry{values: map[string]string{}}
+
+func (sr *secretRegistry) add(name string, val string) {
+	if val == "" {
+		return
+	}
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.values[val] = name
+}
+
+// redact replaces secret values with their {SECRET::name} template.  Longer values are
+// replaced first so that a secret containing another secret is redacted as a whole.
+func (sr *secretRegistry) redact(s string) string {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	if len(sr.values) == 0 || s == "" {
+		return s
+	}
+	vals := make([]string, 0, len(sr.values))
+	for val := range sr.values {
+		if strings.Contains(s, val) {
+			vals = append(vals, val)
+		}
+	}
+	if len(vals) == 0 {
+		return s
+	}
+	sort.Slice(vals, func(i, j int) bool { return len(vals[i]) > len(vals[j]) })
+	oldnew := make([]string, 0, 2*len(vals))
+	for _, val := range vals {
+		oldnew = append(oldnew, val, fmt.Sprintf("{%s%s%s}", templateSecret, templateTypeSeparator, sr.values[val]))
+	}
+	return strings.NewReplacer(oldnew...).Replace(s)
+}
+
+func (sr *secretRegistry) empty() bool {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	return len(sr.values) == 0
+}
+
+// RedactSecrets replaces the resolved secret values in a string with their {SECRET::name} template.
+// Every resolved value is redacted, so short secrets can also redact matching unrelated text.
+func RedactSecrets(s string) string {
+	return resolvedSecrets.redact(s)
+}
+
+// redactValue redacts the strings in a decoded json value
+func redactValue(val any) any {
+	switch v := val.(type) {
+	case string:
+		return RedactSecrets(v)
+	case map[string]any:
+		for key, item := range v {
+			v[key] = redactValue(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return val
+}
+
+// redactLogAttr redacts secret values from log messages and attributes
+func redactLogAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(RedactSecrets(a.Value.String()))
+	case slog.KindAny:
+		if s := fmt.Sprint(a.Value.Any()); RedactSecrets(s) != s {
+			a.Value = slog.StringValue(RedactSecrets(s))
+		}
+	}
+	return a
+}
@@ -0,0 +1,88 @@
+package cc
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type mapSecretProvider map[string]string
+
+func (p mapSecretProvider) GetSecret(name string) (string, bool, error) {
+	val, ok := p[name]
+	return val, ok, nil
+}
+
+func TestSecrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "api_token"), []byte("tok-8c1f27\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(CcSecretsPath, dir)
+	t.Setenv("CC_TEST_DB_PASSWORD", "pw-41d9e2")
+
+	providers := SecretProviders
+	t.Cleanup(func() { SecretProviders = providers })
+	SecretProviders.RegisterSecretProvider(mapSecretProvider{"vault_key": "vk-77a0b3"})
+
+	val, err := substituteTemplate("postgres://cc:{SECRET::CC_TEST_DB_PASSWORD}@db/{SECRET::api_token}", envTemplateResolver)
+	if err != nil || val != "postgres://cc:pw-41d9e2@db/tok-8c1f27" {
+		t.Fatalf("unexpected substitution: %q %v", val, err)
+	}
+	if val, err = substituteTemplate("{SECRET::vault_key}", envTemplateResolver); err != nil || val != "vk-77a0b3" {
+		t.Errorf("unexpected registered provider secret: %q %v", val, err)
+	}
+	if _, err = substituteTemplate("{SECRET::missing}", envTemplateResolver); err == nil || !strings.Contains(err.Error(), "secret missing is not defined") {
+		t.Errorf("expected a missing secret error, got %v", err)
+	}
+	if _, _, err = (FileSecretProvider{Dir: dir}).GetSecret("../api_token"); err == nil {
+		t.Error("expected an error for a secret name outside the secrets directory")
+	}
+
+	if redacted := RedactSecrets("token tok-8c1f27 used"); redacted != "token {SECRET::api_token} used" {
+		t.Errorf("unexpected redaction: %s", redacted)
+	}
+	//short secrets are redacted too
+	SecretProviders.RegisterSecretProvider(mapSecretProvider{"pin": "q7"})
+	t.Cleanup(func() {
+		resolvedSecrets.mu.Lock()
+		defer resolvedSecrets.mu.Unlock()
+		delete(resolvedSecrets.values, "q7")
+	})
+	if short, err := substituteTemplate("{SECRET::pin}", envTemplateResolver); err != nil || short != "q7" {
+		t.Errorf("unexpected short secret: %q %v", short, err)
+	}
+	if redacted := RedactSecrets("pin q7"); redacted != "pin {SECRET::pin}" {
+		t.Errorf("expected a short secret to be redacted: %s", redacted)
+	}
+
+	//secrets are redacted from the log
+	buf := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&buf, ccLoggerOpts(slog.LevelDebug)))
+	logger.Info("connecting with pw-41d9e2", "token", "tok-8c1f27", "url", []string{"vk-77a0b3"})
+	if s := buf.String(); strings.Contains(s, "pw-41d9e2") || strings.Contains(s, "tok-8c1f27") || strings.Contains(s, "vk-77a0b3") {
+		t.Errorf("expected secrets to be redacted from the log: %s", s)
+	}
+
+	//and from saved payloads
+	payload := Payload{IOManager: IOManager{
+		Attributes: PayloadAttributes{"connection": map[string]any{"url": val}},
+		Stores:     []DataStore{{Name: "db", StoreType: "POSTGRES", Parameters: PayloadAttributes{"password": "{SECRET::CC_TEST_DB_PASSWORD}"}}},
+	}}
+	if err = substituteStoreParameters(payload.Stores[0].Parameters); err != nil || payload.Stores[0].Parameters["password"] != "pw-41d9e2" {
+		t.Fatalf("unexpected store parameters: %v %v", payload.Stores[0].Parameters, err)
+	}
+	data, err := writePayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); strings.Contains(s, "pw-41d9e2") || !strings.Contains(s, "{SECRET::vault_key}") {
+		t.Errorf("expected secrets to be redacted from the payload: %s", s)
+	}
+	if payload.Stores[0].Parameters["password"] != "pw-41d9e2" {
+		t.Error("expected the payload to be unchanged")
+	}
+}
@@ -0,0 +1,394 @@
+package cc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Payload templates have the form {TYPE::NAME:-default|function:arg|function}
+//
+//   - TYPE is ENV (environment variable), ATTR (payload or action attribute),
+//     CC (built-in variable), VAR (template variable supplied to a data source operation)
+//     or SECRET (value from the SecretProviders, redacted from logs and saved payloads)
+//   - NAME for ATTR templates can be a dotted path into nested attributes (model.params.dt)
+//     or a list index (gages.0)
+//   - the optional default is used when the value is missing and can contain templates
+//   - functions are applied to the value in order (see templateFunctions)
+//
+// Literal braces are written as {{ and }}.
+const (
+	templateEnv  = "ENV"
+	templateAttr = "ATTR"
+	templateCc   = "CC"
+	templateVar  = "VAR"
+
+	templateSecret = "SECRET"
+
+	templateTypeSeparator = "::"
+	templateDefault       = ":-"
+)
+
+// built-in {CC::} variables and the environment variables they are read from
+var templateBuiltins = map[string]string{
+	"EVENT_NUMBER":     CcEventNumber,
+	"EVENT_IDENTIFIER": CcEventIdentifier,
+	"MANIFEST_ID":      CcManifestId,
+	"PAYLOAD_ID":       CcPayloadId,
+}
+
+var templateMissingMessages = map[string]string{
+	templateEnv:  "environment variable %s is not set",
+	templateAttr: "attribute %s is not defined",
+	templateCc:   "built-in variable %s is not set",
+	templateVar:  "template variable %s is not defined",
+
+	templateSecret: "secret %s is not defined",
+}
+
+type templateFunc func(val string, arg string) (string, error)
+
+var templateFunctions = map[string]templateFunc{
+	//left pads a value with zeros to a minimum width: {ATTR::event|pad:5} -> 00042
+	"pad": func(val string, arg string) (string, error) {
+		width, err := strconv.Atoi(arg)
+		if err != nil {
+			return "", fmt.Errorf("invalid pad width %q", arg)
+		}
+		sign := ""
+		if strings.HasPrefix(val, "-") {
+			sign, val = "-", val[1:]
+		}
+		if n := width - len(sign) - len(val); n > 0 {
+			val = strings.Repeat("0", n) + val
+		}
+		return sign + val, nil
+	},
+	"upper": func(val string, arg string) (string, error) {
+		return strings.ToUpper(val), nil
+	},
+	"lower": func(val string, arg string) (string, error) {
+		return strings.ToLower(val), nil
+	},
+	"trim": func(val string, arg string) (string, error) {
+		return strings.TrimSpace(val), nil
+	},
+}
+
+// errTemplateDeferred is returned by a templateResolver for templates that
+// are resolved by a later substitution pass.  Deferred templates are left in place.
+var errTemplateDeferred = errors.New("template deferred")
+
+// TemplateSyntaxError is returned for malformed templates
+type TemplateSyntaxError struct {
+	Template string
+	Reason   string
+}
+
+func (e *TemplateSyntaxError) Error() string {
+	return fmt.Sprintf("invalid substitution %s: %s", e.Template, e.Reason)
+}
+
+// templateResolver returns the value for a template type and name.
+// Missing values return found=false so that the template default can be used.
+type templateResolver func(kind string, name string) (val string, found bool, err error)
+
+// substituteTemplate replaces all templates in a string.  If any template is deferred
+// by the resolver, escaped braces are kept so that the later pass reads the same template text.
+func substituteTemplate(template string, resolve templateResolver) (string, error) {
+	ts := templateSubstitution{resolve: resolve}
+	resolved, raw, err := ts.render(template)
+	if err != nil {
+		return "", err
+	}
+	if ts.deferred {
+		return raw, nil
+	}
+	return resolved, nil
+}
+
+// substituteTemplatePass replaces the templates in a string that is substituted again by a
+// later pass.  Escaped braces are always kept so that the final pass unescapes them once.
+func substituteTemplatePass(template string, resolve templateResolver) (string, error) {
+	ts := templateSubstitution{resolve: resolve}
+	_, raw, err := ts.render(template)
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// containsTemplate returns true if the string has at least one {TYPE::NAME} template
+func containsTemplate(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '{' {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '{' {
+			i++
+			continue
+		}
+		end := matchingBrace(s, i)
+		if end < 0 {
+			return false
+		}
+		if kind, _, ok := strings.Cut(s[i+1:end], templateTypeSeparator); ok && isTemplateType(kind) {
+			return true
+		}
+	}
+	return false
+}
+
+type templateSubstitution struct {
+	resolve  templateResolver
+	deferred bool
+
+	//treat braces that are not {TYPE::NAME} templates as literal text
+	lenient bool
+}
+
+// render returns the text with templates replaced and literal braces unescaped
+// along with the raw form of the text, which keeps escapes and deferred templates
+func (ts *templateSubstitution) render(text string) (string, string, error) {
+	resolved := strings.Builder{}
+	raw := strings.Builder{}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case (c == '{' || c == '}') && i+1 < len(text) && text[i+1] == c:
+			resolved.WriteByte(c)
+			raw.WriteString(text[i : i+2])
+			i++
+		case c == '{':
+			end := matchingBrace(text, i)
+			if end < 0 {
+				//unterminated braces are literal text
+				resolved.WriteByte(c)
+				raw.WriteByte(c)
+				continue
+			}
+			val, err := ts.expression(text[i : end+1])
+			switch {
+			case errors.Is(err, errTemplateDeferred):
+				ts.deferred = true
+				resolved.WriteString(text[i : end+1])
+				raw.WriteString(text[i : end+1])
+			case err != nil:
+				return "", "", err
+			default:
+				resolved.WriteString(val)
+				raw.WriteString(escapeTemplateBraces(val))
+			}
+			i = end
+		default:
+			resolved.WriteByte(c)
+			raw.WriteByte(c)
+		}
+	}
+	return resolved.String(), raw.String(), nil
+}
+
+// expression resolves a single {TYPE::NAME:-default|function} template
+func (ts *templateSubstitution) expression(template string) (string, error) {
+	body := template[1 : len(template)-1]
+	kind, rest, ok := strings.Cut(body, templateTypeSeparator)
+	if !ok || !isTemplateType(kind) {
+		if ts.lenient {
+			return template, nil
+		}
+		return "", &TemplateSyntaxError{template, "expected {TYPE::NAME}"}
+	}
+
+	name, defaultVal, functions := splitTemplateExpression(rest)
+	if name == "" {
+		return "", &TemplateSyntaxError{template, "missing name"}
+	}
+
+	val, found, err := ts.resolve(kind, name)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		if defaultVal == nil {
+			msg, ok := templateMissingMessages[kind]
+			if !ok {
+				msg = kind + " %s is not defined"
+			}
+			return "", fmt.Errorf("unresolved template %s: "+msg, template, name)
+		}
+		def := templateSubstitution{resolve: ts.resolve, lenient: ts.lenient}
+		val, _, err = def.render(*defaultVal)
+		if err != nil {
+			return "", err
+		}
+		if def.deferred {
+			//the default can't be resolved until a later pass
+			return "", errTemplateDeferred
+		}
+	}
+
+	for _, function := range functions {
+		fname, arg, _ := strings.Cut(function, ":")
+		fn, ok := templateFunctions[fname]
+		if !ok {
+			return "", &TemplateSyntaxError{template, fmt.Sprintf("unknown function %q", fname)}
+		}
+		val, err = fn(val, arg)
+		if err != nil {
+			return "", &TemplateSyntaxError{template, err.Error()}
+		}
+	}
+	return val, nil
+}
+
+// splitTemplateExpression splits NAME:-default|function|function.
+// Separators within nested templates in the default are ignored.
+func splitTemplateExpression(expr string) (string, *string, []string) {
+	parts := []string{}
+	start, depth := 0, 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '|':
+			if depth == 0 {
+				parts = append(parts, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, expr[start:])
+
+	name := parts[0]
+	var defaultVal *string
+	if n, d, ok := strings.Cut(parts[0], templateDefault); ok {
+		name, defaultVal = n, &d
+	}
+	return strings.TrimSpace(name), defaultVal, parts[1:]
+}
+
+// matchingBrace returns the index of the brace closing the template starting at
+// start, or -1 if the template is not terminated
+func matchingBrace(text string, start int) int {
+	depth := 0
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isTemplateType(kind string) bool {
+	if kind == "" {
+		return false
+	}
+	for _, c := range kind {
+		if (c < 'A' || c > 'Z') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func escapeTemplateBraces(val string) string {
+	return strings.NewReplacer("{", "{{", "}", "}}").Replace(val)
+}
+
+// unescapeTemplateBraces replaces escaped braces in text that has no templates
+func unescapeTemplateBraces(val string) string {
+	return strings.NewReplacer("{{", "{", "}}", "}").Replace(val)
+}
+
+// templateValue converts an attribute value into template text.
+// Structured values are written as json.
+func templateValue(val any) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case map[string]any, []any:
+		data, err := json.Marshal(v)
+		if err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%v", val) //need to coerce non-string values into strings.  for example ints might be perfectly valid for parameter substitution in a url
+}
+
+// lookupAttribute finds an attribute by name or by a dotted path into nested
+// maps and lists.  An exact match on the full name takes precedence.
+func lookupAttribute(attrs map[string]any, path string) (any, bool) {
+	if val, ok := attrs[path]; ok {
+		return val, true
+	}
+	var current any = attrs
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]any:
+			val, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = val
+		case PayloadAttributes:
+			val, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = val
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// envTemplateResolver resolves {ENV::}, {CC::} and {SECRET::} templates
+func envTemplateResolver(kind string, name string) (string, bool, error) {
+	switch kind {
+	case templateSecret:
+		return SecretProviders.GetSecret(name)
+	case templateEnv:
+		val := os.Getenv(name)
+		return val, val != "", nil
+	case templateCc:
+		env, ok := templateBuiltins[name]
+		if !ok {
+			return "", false, fmt.Errorf("unknown built-in variable {CC::%s}", name)
+		}
+		val := os.Getenv(env)
+		return val, val != "", nil
+	}
+	return "", false, errTemplateDeferred
+}
+
+// attrTemplateResolver resolves {ENV::}, {CC::}, {SECRET::} and, if attrs is not nil, {ATTR::} templates
+func attrTemplateResolver(attrs map[string]any) templateResolver {
+	return func(kind string, name string) (string, bool, error) {
+		if kind == templateAttr && attrs != nil {
+			val, ok := lookupAttribute(attrs, name)
+			if !ok {
+				return "", false, nil
+			}
+			return templateValue(val), true, nil
+		}
+		return envTemplateResolver(kind, name)
+	}
+}
@@ -0,0 +1,126 @@
+package cc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParameterSubstitute(t *testing.T) {
+	t.Setenv("CC_TEST_BASIN", "kanawha")
+	t.Setenv(CcEventNumber, "42")
+	t.Setenv(CcManifestId, "manifest-1")
+	attrs := map[string]any{
+		"scenario": "Base",
+		"event":    42.0,
+		"model":    map[string]any{"params": map[string]any{"dt": 30.0}},
+		"gages":    []any{"elk", "coal"},
+		"run.name": "dotted key",
+	}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{ENV::CC_TEST_BASIN}/{ATTR::scenario}", "kanawha/Base"},
+		{"{ENV::CC_TEST_UNSET:-default}", "default"},
+		{"{ENV::CC_TEST_UNSET:-{ATTR::scenario|lower}}/x", "base/x"},
+		{"{ENV::CC_TEST_UNSET:-}", ""},
+		{"{ATTR::model.params.dt}s", "30s"},
+		{"{ATTR::gages.1|upper}", "COAL"},
+		{"{ATTR::run.name}", "dotted key"},
+		{"event_{ATTR::event|pad:5}.csv", "event_00042.csv"},
+		{"{CC::MANIFEST_ID}/event_{CC::EVENT_NUMBER|pad:3}", "manifest-1/event_042"},
+		{"{ATTR::model.params}", `{"dt":30}`},
+		{"literal {{braces}} and {{ENV::CC_TEST_BASIN}}", "literal {braces} and {ENV::CC_TEST_BASIN}"},
+		{"{ATTR::scenario}/{VAR::realization}/{{x}}", "Base/{VAR::realization}/{{x}}"},
+		{"unterminated {ATTR::scenario", "unterminated {ATTR::scenario"},
+	}
+	for _, test := range tests {
+		result, err := parameterSubstitute(test.template, attrs, true)
+		if err != nil {
+			t.Errorf("%s: %s", test.template, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.template, test.expected, result)
+		}
+	}
+
+	var syntaxErr *TemplateSyntaxError
+	for _, template := range []string{"{ATTR::scenario|bogus}", "{scenario}", "{ATTR::event|pad:x}"} {
+		_, err := parameterSubstitute(template, attrs, true)
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%s: expected a syntax error, got %v", template, err)
+		}
+	}
+	if _, err := parameterSubstitute("{ATTR::model.params.missing}", attrs, true); err == nil {
+		t.Error("expected an error for a missing attribute")
+	}
+
+	//attribute templates are left in place when attribute substitution is disabled
+	result, err := parameterSubstitute("{ATTR::scenario}/{ENV::CC_TEST_BASIN}", attrs, false)
+	if err != nil || result != "{ATTR::scenario}/kanawha" {
+		t.Errorf("unexpected result without attribute substitution: %q %v", result, err)
+	}
+}
+
+func TestTemplateVarSubstitution(t *testing.T) {
+	vars := map[string]string{"realization": "7"}
+	tests := map[string]string{
+		"Base/{VAR::realization|pad:3}/{{x}}":  "Base/007/{x}",
+		"{VAR::block:-all}/{VAR::realization}": "all/7",
+		"{literal}/{VAR::realization}":         "{literal}/7",
+	}
+	for template, expected := range tests {
+		result, err := templateVarSubstitution(template, vars)
+		if err != nil || result != expected {
+			t.Errorf("%s: expected %q, got %q (%v)", template, expected, result, err)
+		}
+	}
+	if _, err := templateVarSubstitution("{VAR::missing}/{VAR::realization}", vars); err == nil {
+		t.Error("expected an error for an undefined template variable")
+	}
+}
+
+func TestPathSubstitutionPasses(t *testing.T) {
+	attrs := map[string]any{"scenario": "base", "braces": "{x}"}
+	tests := map[string]string{
+		"/data/{{VAR::x}}/{{lit}}":                    "/data/{VAR::x}/{lit}",
+		"/data/{ATTR::scenario}/{{VAR::x}}":           "/data/base/{VAR::x}",
+		"/data/{ATTR::braces}/{VAR::realization}":     "/data/{x}/7",
+		"/data/{{lit}}/{VAR::realization}/{{VAR::x}}": "/data/{lit}/7/{VAR::x}",
+	}
+	for template, expected := range tests {
+		ds := DataSource{Name: "ds", Paths: map[string]string{"default": template}}
+		if err := pathsSubstitute(&ds, attrs); err != nil {
+			t.Errorf("%s: %s", template, err)
+			continue
+		}
+		path, _, err := opPaths(ds, DataSourceOpInput{PathKey: "default", TemplateVars: map[string]string{"realization": "7"}})
+		if err != nil || path != expected {
+			t.Errorf("%s: expected %q, got %q (%v)", template, expected, path, err)
+		}
+	}
+
+	params := map[string]any{"bucket": "{{literal}}", "nested": []any{"a}}b"}}
+	if err := substituteStoreParameters(params); err != nil || params["bucket"] != "{literal}" || params["nested"].([]any)[0] != "a}b" {
+		t.Errorf("unexpected store parameter substitution: %v %v", params, err)
+	}
+}
+
+func TestSubstituteMapVariables(t *testing.T) {
+	t.Setenv("CC_TEST_BASIN", "kanawha")
+	pm := PluginManager{}
+	pm.Attributes = PayloadAttributes{"scenario": "base"}
+	params := map[string]any{
+		"basin":   "{ENV::CC_TEST_BASIN}",
+		"nested":  map[string]any{"path": "{ATTR::scenario}/{ENV::CC_TEST_BASIN}"},
+		"list":    []any{"{ATTR::scenario|upper}", 1.0},
+		"invalid": "{not a template}",
+	}
+	pm.substituteMapVariables(params, true)
+	if params["basin"] != "kanawha" || params["nested"].(map[string]any)["path"] != "base/kanawha" ||
+		params["list"].([]any)[0] != "BASE" || params["invalid"] != "{not a template}" {
+		t.Errorf("unexpected substitution: %v", params)
+	}
+}
@@ -1,13 +1,18 @@
 package cc
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	. "github.com/usace/cc-go-sdk"
 
@@ -22,16 +27,26 @@ const (
 	defaultAttrName           string = "a"
 	defaultMetadataPath       string = "/scalars"
 	defaultTileExtent         int64  = 256
+	internalMetadataPrefix    string = "__"
+	metadataTimestampPrefix   string = "__ts_"
+	metadataJsonPrefix        string = "__json_"
 	stringSliceMetadataPrefix string = "__strslc_"
 	stringSliceMetadataOffset string = "_offset_"
 	stringSliceMetadataData   string = "_data_"
+	geoReferenceMetadataKey   string = "__georef"
+
+	//data store parameter for the number of writes to an array between automatic consolidations
+	consolidateEveryParam string = "consolidate_every"
 )
 
 var webProtocolRegex *regexp.Regexp = regexp.MustCompile(`^(https?):\/\/(.*)$`)
 
 type TileDbEventStore struct {
-	context *tiledb.Context
-	uri     string
+	context          *tiledb.Context
+	uri              string
+	consolidateEvery int
+	writeCounts      map[string]int
+	writeMutex       sync.Mutex
 }
 
 var eventStoreType2TileDbType map[ARRAY_TYPE]tiledb.ArrayType = map[ARRAY_TYPE]tiledb.ArrayType{
@@ -54,6 +69,15 @@ var eventStoreOrder2TileDbOrder map[LAYOUT_ORDER]tiledb.Layout = map[LAYOUT_ORDE
 	UNORDERED: tiledb.TILEDB_UNORDERED,
 }
 
+func getLayoutOrder(layout tiledb.Layout) (LAYOUT_ORDER, error) {
+	for k, v := range eventStoreOrder2TileDbOrder {
+		if v == layout {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid layout order: %v", layout)
+}
+
 func NewTiledbEventStore(eventPath string, profile string) (*TileDbEventStore, error) {
 	store := TileDbEventStore{}
 	_, err := store.Connect(DataStore{
@@ -108,6 +132,8 @@ func (tdb *TileDbEventStore) Connect(ds DataStore) (any, error) {
 	}
 
 	tdb.context = context
+	tdb.consolidateEvery = ds.Parameters.GetIntOrDefault(consolidateEveryParam, 0)
+	tdb.writeCounts = make(map[string]int)
 	err = tdb.createAttributeArray()
 	return tdb, err
 }
@@ -147,25 +173,9 @@ func (tdb *TileDbEventStore) CreateArray(input CreateArrayInput) error {
 
 	tiledbAttrs := make([]*tiledb.Attribute, len(input.Attributes))
 	for i, attribute := range input.Attributes {
-		if tiledbAttr, ok := ccAttr2TiledbAttrMap[attribute.DataType]; ok {
-			tiledbAttrs[i], err = tiledb.NewAttribute(
-				tdb.context,
-				attribute.Name,
-				tiledbAttr,
-			)
-			if err != nil {
-				return err
-			}
-
-			if tiledbAttr == tiledb.TILEDB_STRING_ASCII {
-				err = tiledbAttrs[i].SetCellValNum(tiledb.TILEDB_VAR_NUM)
-				if err != nil {
-					return err
-				}
-			}
-
-		} else {
-			return errors.New("unsupported attribute type")
+		tiledbAttrs[i], err = tdb.newAttribute(attribute)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -196,7 +206,156 @@ func (tdb *TileDbEventStore) CreateArray(input CreateArrayInput) error {
 	return array.Create(arraySchema)
 }
 
+func (tdb *TileDbEventStore) newAttribute(attribute ArrayAttribute) (*tiledb.Attribute, error) {
+	tiledbAttrType, ok := ccAttr2TiledbAttrMap[attribute.DataType]
+	if !ok {
+		return nil, errors.New("unsupported attribute type")
+	}
+	tiledbAttr, err := tiledb.NewAttribute(tdb.context, attribute.Name, tiledbAttrType)
+	if err != nil {
+		return nil, err
+	}
+	if tiledbAttrType == tiledb.TILEDB_STRING_ASCII {
+		err = tiledbAttr.SetCellValNum(tiledb.TILEDB_VAR_NUM)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tiledbAttr, nil
+}
+
+// DescribeArray returns the schema of an array in the event store
+func (tdb *TileDbEventStore) DescribeArray(arrayPath string) (ArraySchema, error) {
+	array, err := tiledb.NewArray(tdb.context, tdb.uri+"/"+arrayPath)
+	if err != nil {
+		return ArraySchema{}, err
+	}
+	defer array.Free()
+
+	err = array.Open(tiledb.TILEDB_READ)
+	if err != nil {
+		return ArraySchema{}, err
+	}
+	defer array.Close()
+
+	return getArraySchema(*array)
+}
+
+// ArrayExists returns true if an array exists at the path in the event store
+func (tdb *TileDbEventStore) ArrayExists(arrayPath string) (bool, error) {
+	objType, err := tiledb.ObjectType(tdb.context, tdb.uri+"/"+arrayPath)
+	if err != nil {
+		return false, err
+	}
+	return objType == tiledb.TILEDB_ARRAY, nil
+}
+
+// ListArrays returns the paths of the arrays in the event store beginning with the prefix.
+// An empty prefix lists all arrays.  The internal metadata array is not listed.
+func (tdb *TileDbEventStore) ListArrays(prefix string) ([]string, error) {
+	config, err := tdb.context.Config()
+	if err != nil {
+		return nil, err
+	}
+	vfs, err := tiledb.NewVFS(tdb.context, config)
+	if err != nil {
+		return nil, err
+	}
+	defer vfs.Free()
+
+	//start the search at the deepest directory in the prefix
+	searchDir := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		searchDir = prefix[:i]
+	}
+	arrays := []string{}
+	err = tdb.listArrays(vfs, strings.TrimSuffix(tdb.uri+"/"+searchDir, "/"), prefix, &arrays)
+	return arrays, err
+}
+
+func (tdb *TileDbEventStore) listArrays(vfs *tiledb.VFS, uri string, prefix string, arrays *[]string) error {
+	folders, _, err := vfs.List(uri)
+	if err != nil {
+		return err
+	}
+	for _, folder := range folders {
+		folder = strings.TrimSuffix(folder, "/")
+		relpath := strings.TrimPrefix(strings.TrimPrefix(folder, tdb.uri), "/")
+		if "/"+relpath == defaultMetadataPath {
+			continue
+		}
+		objType, err := tiledb.ObjectType(tdb.context, folder)
+		if err != nil {
+			return err
+		}
+		switch {
+		case objType == tiledb.TILEDB_ARRAY:
+			if strings.HasPrefix(relpath, prefix) {
+				*arrays = append(*arrays, relpath)
+			}
+		case strings.HasPrefix(relpath, prefix) || strings.HasPrefix(prefix, relpath+"/"):
+			//directory or group that may contain matching arrays
+			err = tdb.listArrays(vfs, folder, prefix, arrays)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteArray removes an array and all of its data from the event store
+func (tdb *TileDbEventStore) DeleteArray(arrayPath string) error {
+	exists, err := tdb.ArrayExists(arrayPath)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("array %s does not exist", arrayPath)
+	}
+	return tiledb.ObjectRemove(tdb.context, tdb.uri+"/"+arrayPath)
+}
+
+// EvolveArray adds and drops attributes on an existing array
+func (tdb *TileDbEventStore) EvolveArray(input EvolveArrayInput) error {
+	if len(input.AddAttributes) == 0 && len(input.DropAttributes) == 0 {
+		return errors.New("array evolution requires at least one attribute to add or drop")
+	}
+	evolution, err := tiledb.NewArraySchemaEvolution(tdb.context)
+	if err != nil {
+		return err
+	}
+	defer evolution.Free()
+
+	for _, attribute := range input.AddAttributes {
+		tiledbAttr, err := tdb.newAttribute(attribute)
+		if err != nil {
+			return err
+		}
+		err = evolution.AddAttribute(tiledbAttr)
+		tiledbAttr.Free()
+		if err != nil {
+			return err
+		}
+	}
+	for _, attrName := range input.DropAttributes {
+		err = evolution.DropAttribute(attrName)
+		if err != nil {
+			return err
+		}
+	}
+	return evolution.Evolve(tdb.uri + "/" + input.ArrayPath)
+}
+
 func (tdb *TileDbEventStore) PutArray(input PutArrayInput) error {
+	err := tdb.putArray(input)
+	if err != nil {
+		return err
+	}
+	return tdb.recordWrite(input.DataPath)
+}
+
+func (tdb *TileDbEventStore) putArray(input PutArrayInput) error {
 	array, err := tiledb.NewArray(tdb.context, tdb.uri+"/"+input.DataPath)
 	if err != nil {
 		return err
@@ -206,6 +365,7 @@ func (tdb *TileDbEventStore) PutArray(input PutArrayInput) error {
 	if err != nil {
 		return err
 	}
+	defer array.Close()
 
 	query, err := tiledb.NewQuery(tdb.context, array)
 	if err != nil {
@@ -267,12 +427,17 @@ func (tdb *TileDbEventStore) PutArray(input PutArrayInput) error {
 }
 
 func (tdb *TileDbEventStore) GetArray(input GetArrayInput) (*ArrayResult, error) {
+	openOptions, err := tdb.timeTravelOptions(input)
+	if err != nil {
+		return nil, err
+	}
+
 	array, err := tiledb.NewArray(tdb.context, tdb.uri+"/"+input.DataPath)
 	if err != nil {
 		return nil, err
 	}
 
-	err = array.Open(tiledb.TILEDB_READ)
+	err = array.OpenWithOptions(tiledb.TILEDB_READ, openOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -375,6 +540,135 @@ func (tdb *TileDbEventStore) GetArray(input GetArrayInput) (*ArrayResult, error)
 	}, nil
 }
 
+func (tdb *TileDbEventStore) timeTravelOptions(input GetArrayInput) ([]tiledb.ArrayOpenOption, error) {
+	switch {
+	case input.Timestamp > 0 && input.Fragment > 0:
+		return nil, errors.New("time travel reads accept a timestamp or a fragment, not both")
+	case input.Timestamp > 0:
+		return []tiledb.ArrayOpenOption{tiledb.WithEndTimestamp(input.Timestamp)}, nil
+	case input.Fragment > 0:
+		fragments, err := tdb.ListFragments(input.DataPath)
+		if err != nil {
+			return nil, err
+		}
+		if input.Fragment > len(fragments) {
+			return nil, fmt.Errorf("invalid fragment %d. array %s has %d fragments", input.Fragment, input.DataPath, len(fragments))
+		}
+		return []tiledb.ArrayOpenOption{tiledb.WithEndTimestamp(fragments[input.Fragment-1].EndTime)}, nil
+	}
+	return nil, nil
+}
+
+// arrayUri returns the uri of an array in the event store.
+// An empty array path refers to the event metadata array.
+func (tdb *TileDbEventStore) arrayUri(arrayPath string) string {
+	if arrayPath == "" {
+		return tdb.uri + defaultMetadataPath
+	}
+	return tdb.uri + "/" + arrayPath
+}
+
+// Consolidate merges the fragments and the metadata of an array.
+// An empty array path consolidates the event metadata array.
+func (tdb *TileDbEventStore) Consolidate(arrayPath string) error {
+	return tdb.arrayMaintenance(arrayPath, "sm.consolidation.mode", func(array *tiledb.Array, config *tiledb.Config) error {
+		return array.Consolidate(config)
+	})
+}
+
+// Vacuum removes fragments and metadata that have been consolidated.
+// An empty array path vacuums the event metadata array.
+func (tdb *TileDbEventStore) Vacuum(arrayPath string) error {
+	return tdb.arrayMaintenance(arrayPath, "sm.vacuum.mode", func(array *tiledb.Array, config *tiledb.Config) error {
+		return array.Vacuum(config)
+	})
+}
+
+func (tdb *TileDbEventStore) arrayMaintenance(arrayPath string, modeParam string, op func(*tiledb.Array, *tiledb.Config) error) error {
+	array, err := tiledb.NewArray(tdb.context, tdb.arrayUri(arrayPath))
+	if err != nil {
+		return err
+	}
+	defer array.Free()
+
+	for _, mode := range []string{"fragments", "array_meta"} {
+		config, err := tiledb.NewConfig()
+		if err != nil {
+			return err
+		}
+		err = config.Set(modeParam, mode)
+		if err == nil {
+			err = op(array, config)
+		}
+		config.Free()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListFragments returns the fragments of an array ordered by write time
+func (tdb *TileDbEventStore) ListFragments(arrayPath string) ([]ArrayFragment, error) {
+	fragmentInfo, err := tiledb.NewFragmentInfo(tdb.context, tdb.arrayUri(arrayPath))
+	if err != nil {
+		return nil, err
+	}
+	defer fragmentInfo.Free()
+
+	err = fragmentInfo.Load()
+	if err != nil {
+		return nil, err
+	}
+	count, err := fragmentInfo.GetFragmentNum()
+	if err != nil {
+		return nil, err
+	}
+	fragments := make([]ArrayFragment, count)
+	for i := uint32(0); i < count; i++ {
+		uri, err := fragmentInfo.GetFragmentURI(i)
+		if err != nil {
+			return nil, err
+		}
+		start, end, err := fragmentInfo.GetTimestampRange(i)
+		if err != nil {
+			return nil, err
+		}
+		fragments[i] = ArrayFragment{uri, start, end}
+	}
+	return fragments, nil
+}
+
+// SetAutoConsolidation consolidates an array after every n writes to the array.
+// Zero disables automatic consolidation.
+func (tdb *TileDbEventStore) SetAutoConsolidation(writes int) {
+	tdb.writeMutex.Lock()
+	defer tdb.writeMutex.Unlock()
+	tdb.consolidateEvery = writes
+}
+
+func (tdb *TileDbEventStore) recordWrite(arrayPath string) error {
+	tdb.writeMutex.Lock()
+	if tdb.consolidateEvery <= 0 {
+		tdb.writeMutex.Unlock()
+		return nil
+	}
+	if tdb.writeCounts == nil {
+		tdb.writeCounts = make(map[string]int)
+	}
+	tdb.writeCounts[arrayPath]++
+	consolidate := tdb.writeCounts[arrayPath] >= tdb.consolidateEvery
+	if consolidate {
+		tdb.writeCounts[arrayPath] = 0
+	}
+	tdb.writeMutex.Unlock()
+
+	if consolidate {
+		return tdb.Consolidate(arrayPath)
+	}
+	return nil
+}
+
 func getOpBufferRange(br []int64, domain []int64) []int64 {
 	if len(br) == 0 {
 		return domain
@@ -428,6 +722,15 @@ func (tdb *TileDbEventStore) createSimpleArray(input CreateSimpleArrayInput) err
 }
 
 func (tdb *TileDbEventStore) PutSimpleArray(input PutSimpleArrayInput) error {
+	if input.GeoReference != nil {
+		if len(input.Dims) != 2 {
+			return errors.New("georeferenced simple arrays must be two dimensional")
+		}
+		if err := input.GeoReference.Validate(); err != nil {
+			return err
+		}
+	}
+
 	object, err := tiledb.ObjectType(tdb.context, tdb.uri+"/"+input.DataPath)
 	if err != nil {
 		return err
@@ -485,10 +788,28 @@ func (tdb *TileDbEventStore) PutSimpleArray(input PutSimpleArrayInput) error {
 		ArrayType:   ARRAY_DENSE,
 		PutLayout:   input.PutLayout,
 	}
-	return tdb.PutArray(pinput)
+	err = tdb.PutArray(pinput)
+	if err != nil {
+		return err
+	}
+
+	if input.GeoReference != nil {
+		return tdb.putGeoReference(input.DataPath, *input.GeoReference)
+	}
+	return nil
 }
 
 func (tdb *TileDbEventStore) GetSimpleArray(input GetSimpleArrayInput) (*ArrayResult, error) {
+	georef, dims, err := tdb.getGeoReference(input.DataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err = input.ResolveWindow(georef, dims)
+	if err != nil {
+		return nil, err
+	}
+
 	var bufferRange []int64
 	if len(input.XRange) == 2 || len(input.YRange) == 2 {
 		bufferRange = []int64{0, 0, 0, 0}
@@ -512,9 +833,70 @@ func (tdb *TileDbEventStore) GetSimpleArray(input GetSimpleArrayInput) (*ArrayRe
 	if err != nil {
 		return nil, err
 	}
+	if georef != nil && len(result.Range) == 4 {
+		windowRef := georef.Subset(result.Range[0], result.Range[2])
+		result.GeoReference = &windowRef
+	}
 	return result, nil
 }
 
+// putGeoReference stores the georeference as json metadata on the simple array
+func (tdb *TileDbEventStore) putGeoReference(datapath string, georef GeoReference) error {
+	data, err := json.Marshal(georef)
+	if err != nil {
+		return err
+	}
+	array, err := tiledb.NewArray(tdb.context, tdb.uri+"/"+datapath)
+	if err != nil {
+		return err
+	}
+	err = array.Open(tiledb.TILEDB_WRITE)
+	if err != nil {
+		return err
+	}
+	defer array.Close()
+	return array.PutMetadata(geoReferenceMetadataKey, string(data))
+}
+
+// getGeoReference returns the georeference for a simple array and the array dims
+// or a nil georeference if the array was not stored with one
+func (tdb *TileDbEventStore) getGeoReference(datapath string) (*GeoReference, []int64, error) {
+	array, err := tiledb.NewArray(tdb.context, tdb.uri+"/"+datapath)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = array.Open(tiledb.TILEDB_READ)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer array.Close()
+
+	schema, err := getArraySchema(*array)
+	if err != nil {
+		return nil, nil, err
+	}
+	dims := make([]int64, len(schema.Domain)/2)
+	for i := range dims {
+		dims[i] = schema.Domain[2*i+1] - schema.Domain[2*i] + 1
+	}
+
+	metadata, err := array.GetMetadataMap()
+	if err != nil {
+		return nil, nil, err
+	}
+	md, ok := metadata[geoReferenceMetadataKey]
+	if !ok {
+		return nil, dims, nil
+	}
+	georefJson, ok := md.Value.(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid georeference metadata for %s", datapath)
+	}
+	georef := GeoReference{}
+	err = json.Unmarshal([]byte(georefJson), &georef)
+	return &georef, dims, err
+}
+
 func handleVariableResults(data []uint8, query *tiledb.Query, attr string, offsets []uint64) [][]uint8 {
 	elements, _ := query.ResultBufferElements()
 	results := make([][]uint8, elements[attr][0])
@@ -548,6 +930,10 @@ func createBuffer(attrName string, attrType ATTR_TYPE, bufferElems map[string][3
 			data = make([]int8, attrElem[1])
 		case ATTR_INT16:
 			data = make([]int16, attrElem[1])
+		case ATTR_UINT16:
+			data = make([]uint16, attrElem[1])
+		case ATTR_UINT32:
+			data = make([]uint32, attrElem[1])
 		case ATTR_INT32:
 			data = make([]int32, attrElem[1])
 		case ATTR_INT64:
@@ -580,6 +966,8 @@ var ccAttr2TiledbAttrMap map[ATTR_TYPE]tiledb.Datatype = map[ATTR_TYPE]tiledb.Da
 	ATTR_UINT8:   tiledb.TILEDB_UINT8,
 	ATTR_INT8:    tiledb.TILEDB_INT8,
 	ATTR_INT16:   tiledb.TILEDB_INT16,
+	ATTR_UINT16:  tiledb.TILEDB_UINT16,
+	ATTR_UINT32:  tiledb.TILEDB_UINT32,
 	ATTR_INT32:   tiledb.TILEDB_INT32,
 	ATTR_INT64:   tiledb.TILEDB_INT64,
 	ATTR_FLOAT32: tiledb.TILEDB_FLOAT32,
@@ -645,6 +1033,7 @@ func getArraySchema(array tiledb.Array) (ArraySchema, error) {
 		if err == nil {
 			brange := make([]int64, ndim*2)
 			dnames := make([]string, ndim)
+			dims := make([]ArrayDimension, 0, ndim)
 			for i := 0; i < int(ndim); i++ {
 				dim, err := d.DimensionFromIndex(uint(i))
 				if err != nil {
@@ -657,6 +1046,10 @@ func getArraySchema(array tiledb.Array) (ArraySchema, error) {
 					break
 				}
 				dnames[i] = dname
+				ccdim := ArrayDimension{Name: dname, DimensionType: DIMENSION_INT}
+				if dtype, err := dim.Type(); err == nil && dtype == tiledb.TILEDB_STRING_ASCII {
+					ccdim.DimensionType = DIMENSION_STRING
+				}
 				domain, err := dim.Domain()
 				if err != nil {
 					log.Printf("Unable to extract array domain: %s\n", err)
@@ -665,15 +1058,30 @@ func getArraySchema(array tiledb.Array) (ArraySchema, error) {
 				if idomain, ok := domain.([]int64); ok {
 					brange[2*i] = idomain[0]
 					brange[2*i+1] = idomain[1]
+					ccdim.Domain = idomain
+				}
+				if extent, err := dim.Extent(); err == nil {
+					if iextent, ok := extent.(int64); ok {
+						ccdim.TileExtent = iextent
+					}
 				}
+				dims = append(dims, ccdim)
 			}
 			ccArraySchema.Domain = brange
 			ccArraySchema.DomainNames = dnames
+			ccArraySchema.Dimensions = dims
 		}
 	}
 	ccArraySchema.AttributeNames = names
 	ccArraySchema.AttributeTypes = types
 
+	if cellOrder, err := schema.CellOrder(); err == nil {
+		ccArraySchema.CellLayout, _ = getLayoutOrder(cellOrder)
+	}
+	if tileOrder, err := schema.TileOrder(); err == nil {
+		ccArraySchema.TileLayout, _ = getLayoutOrder(tileOrder)
+	}
+
 	return ccArraySchema, nil
 }
 
@@ -759,6 +1167,17 @@ func (tdb *TileDbEventStore) createAttributeArray() error {
 }
 
 func (tdb *TileDbEventStore) PutMetadata(key string, val any) error {
+	err := tdb.putMetadata(key, val)
+	if err != nil {
+		return err
+	}
+	return tdb.recordWrite("")
+}
+
+func (tdb *TileDbEventStore) putMetadata(key string, val any) error {
+	if strings.HasPrefix(key, internalMetadataPrefix) {
+		return fmt.Errorf("invalid metadata key %s. keys beginning with %s are reserved", key, internalMetadataPrefix)
+	}
 	uri := tdb.uri + defaultMetadataPath
 	array, err := tiledb.NewArray(tdb.context, uri)
 	if err != nil {
@@ -770,6 +1189,16 @@ func (tdb *TileDbEventStore) PutMetadata(key string, val any) error {
 	}
 	defer array.Close()
 
+	//remove any previous encoding of the key
+	for _, encodedKey := range encodedMetadataKeys(key) {
+		array.DeleteMetadata(encodedKey)
+	}
+
+	err = array.PutMetadata(metadataTimestampPrefix+key, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+
 	switch val := val.(type) {
 	case []string:
 		data, offsets := computeStringSliceMetadataComponents(val)
@@ -781,6 +1210,13 @@ func (tdb *TileDbEventStore) PutMetadata(key string, val any) error {
 		}
 		return array.PutMetadata(offsetkey, offsets)
 	default:
+		if IsStructuredMetadata(val) {
+			data, err := json.Marshal(val)
+			if err != nil {
+				return err
+			}
+			return array.PutMetadata(metadataJsonPrefix+key, string(data))
+		}
 		return array.PutMetadata(key, val)
 	}
 }
@@ -799,7 +1235,7 @@ func (tdb *TileDbEventStore) GetMetadata(key string, dest any) error {
 	defer array.Close()
 
 	destTypePtr := reflect.TypeOf(dest) //dest type must be a pointer
-	if destTypePtr.Kind() != reflect.Ptr {
+	if destTypePtr == nil || destTypePtr.Kind() != reflect.Ptr {
 		return errors.New("dest type must be a pointer")
 	}
 
@@ -836,14 +1272,19 @@ func (tdb *TileDbEventStore) GetMetadata(key string, dest any) error {
 	default:
 		_, _, val, err := array.GetMetadata(key)
 		if err != nil {
-			return err
+			//structured values are stored as json
+			_, _, jsonval, jsonerr := array.GetMetadata(metadataJsonPrefix + key)
+			if jsonerr != nil {
+				return err
+			}
+			jsonstr, ok := jsonval.(string)
+			if !ok {
+				return fmt.Errorf("invalid structured metadata value for %s", key)
+			}
+			return json.Unmarshal([]byte(jsonstr), dest)
 		}
 
 		valType := reflect.TypeOf(val)
-		destTypePtr := reflect.TypeOf(dest) //dest type must be a pointer
-		if destTypePtr.Kind() != reflect.Ptr {
-			return errors.New("dest type must be a pointer")
-		}
 		destType := destTypePtr.Elem()
 
 		if destType != valType {
@@ -856,39 +1297,63 @@ func (tdb *TileDbEventStore) GetMetadata(key string, dest any) error {
 	}
 }
 
-func (tdb *TileDbEventStore) GetMetadataOld(key string, dest any) error {
-
+// ListMetadata lists the event metadata keys beginning with the prefix.
+// Internal keys used to encode metadata values are not listed.
+func (tdb *TileDbEventStore) ListMetadata(prefix string) ([]MetadataEntry, error) {
 	uri := tdb.uri + defaultMetadataPath
 	array, err := tiledb.NewArray(tdb.context, uri)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = array.Open(tiledb.TILEDB_READ)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer array.Close()
 
-	_, _, val, err := array.GetMetadata(key)
+	metadata, err := array.GetMetadataMap()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	destTypePtr := reflect.TypeOf(dest) //dest type must be a pointer
-	if destTypePtr.Kind() != reflect.Ptr {
-		return errors.New("dest type must be a pointer")
+	stringSliceDataPrefix := stringSliceMetadataPrefix + stringSliceMetadataData
+	entries := []MetadataEntry{}
+	for rawKey := range metadata {
+		key := rawKey
+		switch {
+		case strings.HasPrefix(rawKey, metadataJsonPrefix):
+			key = strings.TrimPrefix(rawKey, metadataJsonPrefix)
+		case strings.HasPrefix(rawKey, stringSliceDataPrefix):
+			key = strings.TrimPrefix(rawKey, stringSliceDataPrefix)
+		case strings.HasPrefix(rawKey, internalMetadataPrefix):
+			continue
+		}
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		entry := MetadataEntry{Key: key}
+		if ts, ok := metadata[metadataTimestampPrefix+key]; ok {
+			if millis, ok := ts.Value.(int64); ok {
+				entry.Updated = time.UnixMilli(millis).UTC()
+			}
+		}
+		entries = append(entries, entry)
 	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
 
-	destType := destTypePtr.Elem()
-	valType := reflect.TypeOf(val)
-
-	if destType != valType {
-		return fmt.Errorf("dest type mismatch. expected %s got %s", destType.Name(), valType.Name())
+// encodedMetadataKeys returns the internal keys used to store a metadata key
+func encodedMetadataKeys(key string) []string {
+	return []string{
+		key,
+		metadataJsonPrefix + key,
+		metadataTimestampPrefix + key,
+		fmt.Sprintf("%s%s%s", stringSliceMetadataPrefix, stringSliceMetadataOffset, key),
+		fmt.Sprintf("%s%s%s", stringSliceMetadataPrefix, stringSliceMetadataData, key),
 	}
-
-	reflect.ValueOf(dest).Elem().Set(reflect.ValueOf(val))
-
-	return err
 }
 
 func computeStringSliceMetadataComponents(vals []string) ([]byte, []int64) {
@@ -905,6 +1370,14 @@ func computeStringSliceMetadataComponents(vals []string) ([]byte, []int64) {
 }
 
 func (tdb *TileDbEventStore) DeleteMetadata(key string) error {
+	err := tdb.deleteMetadata(key)
+	if err != nil {
+		return err
+	}
+	return tdb.recordWrite("")
+}
+
+func (tdb *TileDbEventStore) deleteMetadata(key string) error {
 	uri := tdb.uri + defaultMetadataPath
 	array, err := tiledb.NewArray(tdb.context, uri)
 	if err != nil {
@@ -916,7 +1389,13 @@ func (tdb *TileDbEventStore) DeleteMetadata(key string) error {
 	}
 	defer array.Close()
 
-	return array.DeleteMetadata(key)
+	for _, encodedKey := range encodedMetadataKeys(key) {
+		err = array.DeleteMetadata(encodedKey)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 ///////
@@ -503,6 +503,112 @@ func TestTileDbStoreGetSparseArray2(t *testing.T) {
 // //////METADATA TESTS//////////////////////
 // /////////////////////////////////////
 
+// ////////////////////////////
+// //Array Schema Testing///////
+// ////////////////////////////
+func TestTileDbStoreDescribeArray(t *testing.T) {
+	eventPath := "sims/1"
+	eventStore, err := NewTiledbEventStore(eventPath, testProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := eventStore.DescribeArray("dataset1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Dimensions) != 1 || schema.Dimensions[0].TileExtent != 5 {
+		t.Fatalf("unexpected array dimensions: %v", schema.Dimensions)
+	}
+	fmt.Println(schema)
+}
+
+func TestTileDbStoreListArrays(t *testing.T) {
+	eventPath := "sims/1"
+	eventStore, err := NewTiledbEventStore(eventPath, testProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arrays, err := eventStore.ListArrays("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(arrays)
+}
+
+func TestTileDbStoreEvolveArray(t *testing.T) {
+	eventPath := "sims/1"
+	eventStore, err := NewTiledbEventStore(eventPath, testProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eventStore.EvolveArray(EvolveArrayInput{
+		ArrayPath:     "dataset1",
+		AddAttributes: []ArrayAttribute{{"attr9", ATTR_FLOAT64}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := eventStore.DescribeArray("dataset1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = schema.GetType("attr9"); err != nil {
+		t.Fatal(err)
+	}
+	err = eventStore.EvolveArray(EvolveArrayInput{
+		ArrayPath:      "dataset1",
+		DropAttributes: []string{"attr9"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTileDbStoreTimeTravel(t *testing.T) {
+	eventPath := "sims/1"
+	eventStore, err := NewTiledbEventStore(eventPath, testProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fragments, err := eventStore.ListFragments("five-by-ten-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fragments) == 0 {
+		t.Fatal("expected at least one fragment")
+	}
+	result, err := eventStore.GetArray(GetArrayInput{
+		Attrs:    []string{"a"},
+		DataPath: "five-by-ten-test",
+		Fragment: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(result.Data)
+}
+
+func TestTileDbStoreConsolidate(t *testing.T) {
+	eventPath := "sims/1"
+	eventStore, err := NewTiledbEventStore(eventPath, testProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eventStore.Consolidate("five-by-ten-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eventStore.Vacuum("five-by-ten-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//consolidate the event metadata array
+	err = eventStore.Consolidate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestTileDbStorePutMetdataInt64Slice(t *testing.T) {
 	eventPath := "sims/1"
 	eventStore, err := NewTiledbEventStore(eventPath, testProfile)
@@ -604,3 +710,32 @@ func TestTileDbStoreDeleteMetdataFloat64(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestTileDbStoreStructuredMetadata(t *testing.T) {
+	eventPath := "sims/1"
+	eventStore, err := NewTiledbEventStore(eventPath, testProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewNamespacedMetadataStore(eventStore, "plugin", "action")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.PutMetadata("KEY_MAP", map[string]int{"a": 1, "b": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	val := map[string]int{}
+	err = store.GetMetadata("KEY_MAP", &val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val["b"] != 2 {
+		t.Errorf("unexpected structured metadata: %v", val)
+	}
+	entries, err := eventStore.ListMetadata("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(entries)
+}
@@ -0,0 +1,175 @@
+package cc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+type UnitDimension string
+
+const (
+	LENGTH   UnitDimension = "length"
+	AREA     UnitDimension = "area"
+	VOLUME   UnitDimension = "volume"
+	FLOW     UnitDimension = "flow"
+	VELOCITY UnitDimension = "velocity"
+	TIME     UnitDimension = "time"
+)
+
+// Unit is a unit of measure.  Factor converts a value in the unit to the SI unit
+// of the dimension (m, m2, m3, m3/s, m/s and s).
+type Unit struct {
+	Symbol    string
+	Dimension UnitDimension
+	Factor    float64
+}
+
+type UnitRegistry map[string]Unit
+
+// Units are the units used for unit annotated attributes.  Unit symbols are case insensitive.
+// Plugins can register additional units with RegisterUnit.
+var Units UnitRegistry = make(map[string]Unit)
+
+func (ur *UnitRegistry) RegisterUnit(dimension UnitDimension, factor float64, symbols ...string) {
+	for _, symbol := range symbols {
+		(*ur)[strings.ToLower(symbol)] = Unit{symbols[0], dimension, factor}
+	}
+}
+
+// Lookup finds a unit by symbol or alias
+func (ur UnitRegistry) Lookup(symbol string) (Unit, bool) {
+	unit, ok := ur[strings.ToLower(strings.TrimSpace(symbol))]
+	return unit, ok
+}
+
+const (
+	feet   = 0.3048
+	gallon = 0.003785411784
+	acre   = 4046.8564224
+)
+
+func init() {
+	Units.RegisterUnit(LENGTH, 1, "m", "meter", "meters", "metre", "metres")
+	Units.RegisterUnit(LENGTH, 0.001, "mm", "millimeter", "millimeters")
+	Units.RegisterUnit(LENGTH, 0.01, "cm", "centimeter", "centimeters")
+	Units.RegisterUnit(LENGTH, 1000, "km", "kilometer", "kilometers")
+	Units.RegisterUnit(LENGTH, 0.0254, "in", "inch", "inches")
+	Units.RegisterUnit(LENGTH, feet, "ft", "foot", "feet")
+	Units.RegisterUnit(LENGTH, 0.9144, "yd", "yard", "yards")
+	Units.RegisterUnit(LENGTH, 1609.344, "mi", "mile", "miles")
+
+	Units.RegisterUnit(AREA, 1, "m2", "m^2", "sqm")
+	Units.RegisterUnit(AREA, 1e6, "km2", "km^2", "sqkm")
+	Units.RegisterUnit(AREA, 10000, "ha", "hectare", "hectares")
+	Units.RegisterUnit(AREA, feet*feet, "ft2", "ft^2", "sqft")
+	Units.RegisterUnit(AREA, acre, "ac", "acre", "acres")
+	Units.RegisterUnit(AREA, 1609.344*1609.344, "mi2", "mi^2", "sqmi")
+
+	Units.RegisterUnit(VOLUME, 1, "m3", "m^3")
+	Units.RegisterUnit(VOLUME, 0.001, "L", "liter", "liters", "litre", "litres")
+	Units.RegisterUnit(VOLUME, feet*feet*feet, "ft3", "ft^3", "cf")
+	Units.RegisterUnit(VOLUME, gallon, "gal", "gallon", "gallons")
+	Units.RegisterUnit(VOLUME, acre*feet, "af", "acre-ft", "acre-feet")
+
+	Units.RegisterUnit(FLOW, 1, "cms", "m3/s", "m^3/s")
+	Units.RegisterUnit(FLOW, 0.001, "L/s", "lps")
+	Units.RegisterUnit(FLOW, feet*feet*feet, "cfs", "ft3/s", "ft^3/s")
+	Units.RegisterUnit(FLOW, 1000*feet*feet*feet, "kcfs")
+	Units.RegisterUnit(FLOW, gallon/60, "gpm")
+	Units.RegisterUnit(FLOW, gallon*1e6/86400, "mgd")
+
+	Units.RegisterUnit(VELOCITY, 1, "m/s", "mps")
+	Units.RegisterUnit(VELOCITY, feet, "ft/s", "fps")
+	Units.RegisterUnit(VELOCITY, 1/3.6, "km/h", "kph")
+	Units.RegisterUnit(VELOCITY, 0.44704, "mph")
+
+	Units.RegisterUnit(TIME, 1, "s", "sec", "second", "seconds")
+	Units.RegisterUnit(TIME, 60, "min", "minute", "minutes")
+	Units.RegisterUnit(TIME, 3600, "h", "hr", "hour", "hours")
+	Units.RegisterUnit(TIME, 86400, "d", "day", "days")
+	Units.RegisterUnit(TIME, 604800, "wk", "week", "weeks")
+}
+
+// ConvertUnits converts a value between two units of the same dimension
+func ConvertUnits(value float64, from string, to string) (float64, error) {
+	fromUnit, ok := Units.Lookup(from)
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %s", from)
+	}
+	toUnit, ok := Units.Lookup(to)
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %s", to)
+	}
+	if fromUnit.Dimension != toUnit.Dimension {
+		return 0, fmt.Errorf("can not convert %s (%s) to %s (%s)", from, fromUnit.Dimension, to, toUnit.Dimension)
+	}
+	if fromUnit.Factor == toUnit.Factor {
+		return value, nil
+	}
+	return value * fromUnit.Factor / toUnit.Factor, nil
+}
+
+// Quantity is a unit annotated number.  Unit is empty for plain numbers.
+type Quantity struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// As converts the quantity to a unit.  Quantities without a unit are assumed to be in the unit.
+func (q Quantity) As(unit string) (float64, error) {
+	if q.Unit == "" {
+		return q.Value, nil
+	}
+	return ConvertUnits(q.Value, q.Unit, unit)
+}
+
+func (q Quantity) String() string {
+	return strings.TrimSpace(fmt.Sprintf("%v %s", q.Value, q.Unit))
+}
+
+var quantityRegex = regexp.MustCompile(`^\s*([-+]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][-+]?[0-9]+)?)\s*([A-Za-z][A-Za-z0-9^/-]*)?\s*$`)
+
+// ParseQuantity reads a number, a string with a number and unit ("12.5 ft" or "12.5ft")
+// or an object with a value and unit ({"value": 12.5, "unit": "ft"}).
+// Units must be registered in Units.
+func ParseQuantity(val any) (Quantity, error) {
+	q := Quantity{}
+	switch v := val.(type) {
+	case string:
+		match := quantityRegex.FindStringSubmatch(v)
+		if match == nil {
+			return q, fmt.Errorf("value %v is not a number", val)
+		}
+		q.Value = cast.ToFloat64(match[1])
+		q.Unit = match[2]
+	case map[string]any, PayloadAttributes:
+		m := cast.ToStringMap(v)
+		if _, ok := m["value"]; !ok {
+			return q, fmt.Errorf("quantity %v does not have a value", val)
+		}
+		num, err := cast.ToFloat64E(m["value"])
+		if err != nil {
+			return q, fmt.Errorf("quantity value %v is not a number", m["value"])
+		}
+		q.Value = num
+		q.Unit, err = cast.ToStringE(m["unit"])
+		if err != nil {
+			return q, fmt.Errorf("quantity unit %v is not a string", m["unit"])
+		}
+	default:
+		num, err := cast.ToFloat64E(val)
+		if err != nil {
+			return q, fmt.Errorf("value %v is not a number", val)
+		}
+		q.Value = num
+	}
+	if q.Unit != "" {
+		if _, ok := Units.Lookup(q.Unit); !ok {
+			return q, fmt.Errorf("unknown unit %s", q.Unit)
+		}
+	}
+	return q, nil
+}
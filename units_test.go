@@ -0,0 +1,82 @@
+package cc
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestUnits(t *testing.T) {
+	conversions := []struct {
+		value    float64
+		from, to string
+		expected float64
+	}{
+		{10, "ft", "m", 3.048},
+		{1, "mi", "km", 1.609344},
+		{1000, "cfs", "cms", 28.316846592},
+		{1, "acre-feet", "m3", 1233.48183754752},
+		{2, "Hours", "min", 120},
+		{1, "ac", "ft2", 43560},
+	}
+	for _, c := range conversions {
+		v, err := ConvertUnits(c.value, c.from, c.to)
+		if err != nil || math.Abs(v-c.expected) > 1e-9*c.expected {
+			t.Errorf("%v %s to %s: expected %v, got %v %v", c.value, c.from, c.to, c.expected, v, err)
+		}
+	}
+	if _, err := ConvertUnits(1, "cfs", "ft"); err == nil {
+		t.Error("expected an error converting flow to length")
+	}
+	if _, err := ConvertUnits(1, "furlong", "m"); err == nil {
+		t.Error("expected an error for an unknown unit")
+	}
+
+	quantities := map[string]any{
+		"12.5 ft": "12.5 ft",
+		"12.5ft":  "12.5ft",
+		"map":     map[string]any{"value": 12.5, "unit": "ft"},
+	}
+	for name, val := range quantities {
+		q, err := ParseQuantity(val)
+		if err != nil || q.Value != 12.5 || q.Unit != "ft" {
+			t.Errorf("%s: unexpected quantity %v %v", name, q, err)
+		}
+	}
+	for _, val := range []any{"12.5 furlongs", "ft", map[string]any{"unit": "ft"}} {
+		if _, err := ParseQuantity(val); err == nil {
+			t.Errorf("expected an error parsing %v", val)
+		}
+	}
+
+	attrs := PayloadAttributes{
+		"depth":    "12.5 ft",
+		"flow":     map[string]any{"value": 2, "unit": "kcfs"},
+		"width":    30,
+		"duration": "6 h",
+	}
+	if v, err := attrs.GetFloatAs("depth", "m"); err != nil || math.Abs(v-3.81) > 1e-9 {
+		t.Errorf("unexpected depth: %v %v", v, err)
+	}
+	if v := attrs.GetFloatAsOrFail("flow", "cfs"); math.Abs(v-2000) > 1e-9 {
+		t.Errorf("unexpected flow: %v", v)
+	}
+	if v, err := attrs.GetFloatAs("width", "m"); err != nil || v != 30 {
+		t.Errorf("expected a plain number in the requested unit, got %v %v", v, err)
+	}
+	_, err := attrs.GetFloatAs("duration", "ft")
+	var attrErr *AttributeError
+	if !errors.As(err, &attrErr) || attrErr.Path != "duration" {
+		t.Errorf("expected an incompatible unit error, got %v", err)
+	}
+	if v := attrs.GetFloatAsOrDefault("missing", "m", 1); v != 1 {
+		t.Errorf("expected the default, got %v", v)
+	}
+
+	//plain numbers take the unit of a registered constraint
+	AttributeConstraints.RegisterConstraint("width", AttributeConstraint{Unit: "ft"})
+	t.Cleanup(func() { delete(AttributeConstraints, "width") })
+	if v, err := attrs.GetFloatAs("width", "m"); err != nil || math.Abs(v-9.144) > 1e-9 {
+		t.Errorf("expected the constraint unit to be used, got %v %v", v, err)
+	}
+}